@@ -12,9 +12,14 @@ import (
 
 	"zoomment-server/internal/config"
 	"zoomment-server/internal/database"
+	"zoomment-server/internal/handlers"
 	"zoomment-server/internal/logger"
 	"zoomment-server/internal/middleware"
 	"zoomment-server/internal/routes"
+	"zoomment-server/internal/services/changefeed"
+	"zoomment-server/internal/services/outbox"
+	"zoomment-server/internal/services/retention"
+	"zoomment-server/internal/services/webhooks"
 
 	_ "zoomment-server/docs" // Import swagger docs
 )
@@ -35,6 +40,27 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Create indexes (unique outbox keys, TTLs)
+	if err := database.EnsureIndexes(); err != nil {
+		logger.Error(err, "Failed to create MongoDB indexes")
+		os.Exit(1)
+	}
+
+	// Purge soft-deleted comments in the background
+	retention.Start(cfg)
+
+	// Deliver queued emails in the background
+	outbox.Start(cfg)
+
+	// Send site webhooks for live events in the background
+	webhooks.Start(cfg)
+
+	// Publish live events from MongoDB changes (EVENTS_SOURCE=changestream)
+	if err := changefeed.Start(cfg, handlers.PublishChange); err != nil {
+		logger.Error(err, "Failed to start change feed")
+		os.Exit(1)
+	}
+
 	// Set Gin mode
 	if !isDev {
 		gin.SetMode(gin.ReleaseMode)
@@ -44,13 +70,20 @@ func main() {
 	router := gin.New()
 	router.RedirectTrailingSlash = false
 	router.RedirectFixedPath = false
+
+	// Only believe X-Forwarded-For from known proxies (TRUSTED_PROXIES), so
+	// clients can't pick the IP that rate limits and blocklists see
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Error(err, "Invalid TRUSTED_PROXIES")
+		os.Exit(1)
+	}
 	
 	// Configure CORS to allow fingerprint header
 	corsConfig := cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "fingerprint", "token"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "fingerprint", "token", "Last-Event-ID"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After"},
 		AllowCredentials: false,
 		MaxAge:           12 * 3600, // 12 hours
 	}
@@ -59,6 +92,7 @@ func main() {
 	router.Use(logger.GinLogger())
 	router.Use(gin.Recovery())
 	router.Use(middleware.Auth(cfg))
+	router.Use(middleware.Locale())
 
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -0,0 +1,213 @@
+// Package challenge implements the proof-of-work gate for anonymous writes
+// A client asks for a signed challenge and must find a nonce such that the
+// SHA-256 of "<challenge>:<nonce>" starts with a number of zero bits (the
+// difficulty). Checking takes one hash; finding one takes 2^difficulty on
+// average, which is nothing for a reader and expensive for a spam bot.
+package challenge
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"zoomment-server/internal/tokens"
+	"zoomment-server/internal/utils"
+)
+
+// Algorithm is the hash clients have to use
+const Algorithm = "sha256"
+
+const (
+	// DefaultDifficulty is used when a site doesn't set one (~65k hashes)
+	DefaultDifficulty = 16
+
+	// MinDifficulty and MaxDifficulty bound what sites may set
+	MinDifficulty = 8
+	MaxDifficulty = 24
+
+	// maxExtraDifficulty is how much busy clients get on top (each bit doubles the work)
+	maxExtraDifficulty = 6
+
+	// TTL is how long a challenge may be solved and used
+	TTL = 10 * time.Minute
+
+	// busyThreshold is how many challenges a client may ask for within
+	// activityWindow before the difficulty rises
+	busyThreshold  = 10
+	activityWindow = 10 * time.Minute
+)
+
+// Errors of Verify
+var (
+	ErrInvalid  = errors.New("invalid challenge")
+	ErrUnsolved = errors.New("challenge not solved")
+)
+
+// Challenge is an issued challenge
+type Challenge struct {
+	Token      string
+	Difficulty int
+	ExpiresAt  time.Time
+}
+
+// Issue creates a challenge for a domain
+func Issue(secret, domain string, difficulty int) (Challenge, error) {
+	token, err := tokens.Sign(secret, tokens.PurposeChallenge, jwt.MapClaims{
+		"jti":    utils.GenerateSecret(),
+		"domain": domain,
+		"d":      difficulty,
+	}, TTL)
+	if err != nil {
+		return Challenge{}, err
+	}
+	return Challenge{Token: token, Difficulty: difficulty, ExpiresAt: time.Now().Add(TTL)}, nil
+}
+
+// Verify checks the solution of a challenge issued for domain
+// Returns the challenge ID and expiry, so callers can make sure it's only used once.
+func Verify(secret, domain, token, nonce string) (string, time.Time, error) {
+	claims, err := tokens.Parse(secret, tokens.PurposeChallenge, token)
+	if err != nil || tokens.String(claims, "domain") != domain {
+		return "", time.Time{}, ErrInvalid
+	}
+	difficulty, ok := claims["d"].(float64)
+	jti := tokens.String(claims, "jti")
+	expiresAt, expErr := claims.GetExpirationTime()
+	if !ok || jti == "" || expErr != nil || expiresAt == nil {
+		return "", time.Time{}, ErrInvalid
+	}
+
+	if !Solved(token, nonce, int(difficulty)) {
+		return "", time.Time{}, ErrUnsolved
+	}
+	return jti, expiresAt.Time, nil
+}
+
+// Solved reports whether nonce solves a challenge
+func Solved(token, nonce string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(token + ":" + nonce))
+	return leadingZeroBits(sum[:]) >= difficulty
+}
+
+// Solve finds a nonce for a challenge, the way a client would
+func Solve(token string, difficulty int) string {
+	for i := 0; ; i++ {
+		nonce := strconv.Itoa(i)
+		if Solved(token, nonce, difficulty) {
+			return nonce
+		}
+	}
+}
+
+// Difficulty raises the base difficulty for clients that asked for many
+// challenges recently: one bit (twice the work) per doubling above the threshold
+func Difficulty(base, recent int) int {
+	if recent <= busyThreshold {
+		return base
+	}
+	extra := int(math.Log2(float64(recent)/busyThreshold)) + 1
+	if extra > maxExtraDifficulty {
+		extra = maxExtraDifficulty
+	}
+	return base + extra
+}
+
+func leadingZeroBits(sum []byte) int {
+	count := 0
+	for _, b := range sum {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+	return count
+}
+
+// ========================================
+// Activity
+// ========================================
+
+// window counts a key's challenges in the current and the previous window
+type window struct {
+	start    time.Time
+	current  int
+	previous int
+}
+
+// Activity counts how many challenges clients asked for recently
+// Counts are per instance; they only steer the difficulty.
+type Activity struct {
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewActivity creates an empty activity counter
+func NewActivity() *Activity {
+	return &Activity{windows: make(map[string]*window), now: time.Now}
+}
+
+// Add counts a challenge for keys (an IP, a fingerprint...) and returns the
+// highest count of the last activityWindow among them
+// Empty keys are skipped.
+func (a *Activity) Add(keys ...string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	a.sweep(now)
+
+	highest := 0
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		w, ok := a.windows[key]
+		if !ok {
+			w = &window{start: now}
+			a.windows[key] = w
+		}
+		w.advance(now)
+		w.current++
+
+		// Sliding window estimate: the part of the previous window still in range
+		elapsed := now.Sub(w.start).Seconds() / activityWindow.Seconds()
+		count := w.current + int(float64(w.previous)*(1-elapsed))
+		if count > highest {
+			highest = count
+		}
+	}
+	return highest
+}
+
+// advance moves the window forward to now
+func (w *window) advance(now time.Time) {
+	switch passed := now.Sub(w.start); {
+	case passed >= 2*activityWindow:
+		w.start, w.current, w.previous = now, 0, 0
+	case passed >= activityWindow:
+		w.start, w.current, w.previous = w.start.Add(activityWindow), 0, w.current
+	}
+}
+
+// sweep forgets keys that have been quiet for two windows
+func (a *Activity) sweep(now time.Time) {
+	if now.Sub(a.lastSweep) < activityWindow {
+		return
+	}
+	a.lastSweep = now
+	for key, w := range a.windows {
+		if now.Sub(w.start) >= 2*activityWindow {
+			delete(a.windows, key)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package challenge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"zoomment-server/internal/tokens"
+)
+
+func TestIssueAndVerify(t *testing.T) {
+	issued, err := Issue("secret", "example.com", 8)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	nonce := Solve(issued.Token, issued.Difficulty)
+
+	id, expiresAt, err := Verify("secret", "example.com", issued.Token, nonce)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if id == "" || time.Until(expiresAt) <= 0 || time.Until(expiresAt) > TTL {
+		t.Errorf("Verify() = %q, %v", id, expiresAt)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	issued, _ := Issue("secret", "example.com", 8)
+	nonce := Solve(issued.Token, issued.Difficulty)
+	expired, _ := tokens.Sign("secret", tokens.PurposeChallenge, jwt.MapClaims{"jti": "x", "domain": "example.com", "d": 0}, -time.Minute)
+	moderation, _ := tokens.Sign("secret", tokens.PurposeModerate, jwt.MapClaims{"jti": "x", "domain": "example.com", "d": 0}, time.Minute)
+
+	// A nonce that doesn't solve the challenge
+	wrong := "x"
+	for Solved(issued.Token, wrong, issued.Difficulty) {
+		wrong += "x"
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		domain   string
+		token    string
+		nonce    string
+		expected error
+	}{
+		{name: "wrong nonce", secret: "secret", domain: "example.com", token: issued.Token, nonce: wrong, expected: ErrUnsolved},
+		{name: "other site", secret: "secret", domain: "other.com", token: issued.Token, nonce: nonce, expected: ErrInvalid},
+		{name: "wrong secret", secret: "other", domain: "example.com", token: issued.Token, nonce: nonce, expected: ErrInvalid},
+		{name: "expired", secret: "secret", domain: "example.com", token: expired, nonce: "0", expected: ErrInvalid},
+		{name: "other purpose", secret: "secret", domain: "example.com", token: moderation, nonce: "0", expected: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Verify(tt.secret, tt.domain, tt.token, tt.nonce); err != tt.expected {
+				t.Errorf("Verify() error = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		sum      []byte
+		expected int
+	}{
+		{sum: []byte{0x80, 0x00}, expected: 0},
+		{sum: []byte{0x01, 0xff}, expected: 7},
+		{sum: []byte{0x00, 0x10}, expected: 11},
+		{sum: []byte{0x00, 0x00}, expected: 16},
+	}
+
+	for _, tt := range tests {
+		if result := leadingZeroBits(tt.sum); result != tt.expected {
+			t.Errorf("leadingZeroBits(%x) = %d, want %d", tt.sum, result, tt.expected)
+		}
+	}
+}
+
+func TestDifficulty(t *testing.T) {
+	tests := []struct {
+		recent   int
+		expected int
+	}{
+		{recent: 0, expected: 16},
+		{recent: busyThreshold, expected: 16},
+		{recent: busyThreshold + 1, expected: 17},
+		{recent: 2 * busyThreshold, expected: 18},
+		{recent: 4 * busyThreshold, expected: 19},
+		{recent: 1000 * busyThreshold, expected: 16 + maxExtraDifficulty},
+	}
+
+	for _, tt := range tests {
+		if result := Difficulty(16, tt.recent); result != tt.expected {
+			t.Errorf("Difficulty(16, %d) = %d, want %d", tt.recent, result, tt.expected)
+		}
+	}
+}
+
+func TestActivity(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	activity := NewActivity()
+	activity.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		activity.Add("ip:1")
+	}
+	if count := activity.Add("ip:1", "fingerprint:a", ""); count != 5 {
+		t.Errorf("count = %d, want 5 (the busiest key)", count)
+	}
+
+	// Half of the previous window still counts
+	now = now.Add(activityWindow + activityWindow/2)
+	if count := activity.Add("ip:1"); count != 3 {
+		t.Errorf("count after a window and a half = %d, want 3", count)
+	}
+
+	// Quiet keys start over
+	now = now.Add(3 * activityWindow)
+	if count := activity.Add("ip:1"); count != 1 {
+		t.Errorf("count after a quiet period = %d, want 1", count)
+	}
+}
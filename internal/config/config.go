@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,13 +14,66 @@ import (
 // In Go, struct fields that start with uppercase are "exported" (public)
 // Fields that start with lowercase are private to the package
 type Config struct {
-	Port        string
-	MongoDBURI  string
-	JWTSecret   string
+	Port         string
+	MongoDBURI   string
+	JWTSecret    string
 	DashboardURL string
-	BrandName   string
-	AdminEmail  string
-	BotEmail    EmailConfig
+	APIURL       string
+	BrandName    string
+	AdminEmail   string
+	BotEmail     EmailConfig
+
+	// MailTransport selects how emails are delivered: smtp, sendmail, file, log or memory
+	MailTransport string
+
+	// MailSendmailPath is the binary used by the sendmail transport
+	MailSendmailPath string
+
+	// MailFileDir is where the file transport writes .eml files
+	MailFileDir string
+
+	// MailTemplateDir holds email templates that replace the built-in ones
+	MailTemplateDir string
+
+	// CommentEditWindow is how long after posting a comment can be edited (0 = no limit)
+	CommentEditWindow time.Duration
+
+	// DeletedCommentRetention is how long soft-deleted comments are kept before purging
+	DeletedCommentRetention time.Duration
+
+	// MailWorkers is how many outbox workers send queued emails
+	MailWorkers int
+
+	// MailMaxAttempts is how many times an email is tried before it is dead-lettered
+	MailMaxAttempts int
+
+	// EventsSource selects where live events come from: local (this instance's
+	// writes) or changestream (MongoDB changes, for several instances)
+	EventsSource string
+
+	// InstanceName tells instances apart, e.g. in their saved change feed
+	// positions (defaults to the hostname)
+	InstanceName string
+
+	// WebhookAllowPrivateNetworks lets webhooks call loopback and private addresses
+	WebhookAllowPrivateNetworks bool
+
+	// TelegramAPIURL is the Bot API server used by Telegram notification channels
+	TelegramAPIURL string
+
+	// AkismetBaseURL is the Akismet API used by sites with an Akismet key
+	AkismetBaseURL string
+
+	// RateLimitStore is where rate limit buckets are kept: memory or mongo
+	// (shared by every instance)
+	RateLimitStore string
+
+	// RateLimits overrides the default rate limits, e.g. "comments:ip=20/1m,auth:email=off"
+	RateLimits string
+
+	// TrustedProxies are the proxy addresses or CIDRs whose X-Forwarded-For
+	// header is believed (none by default, so clients can't pick their IP)
+	TrustedProxies []string
 }
 
 // EmailConfig holds SMTP configuration
@@ -56,23 +111,52 @@ func Load() (*Config, error) {
 	// &Config{} creates a pointer to a new Config struct
 	// In Go, we often return pointers to avoid copying large structs
 	config := &Config{
-		Port:        port,
-		MongoDBURI:  mongoURI,
-		JWTSecret:   jwtSecret,
+		Port:         port,
+		MongoDBURI:   mongoURI,
+		JWTSecret:    jwtSecret,
 		DashboardURL: dashboardURL,
-		BrandName:   brandName,
-		AdminEmail:  adminEmail,
+		APIURL:       getEnv("API_URL", "http://localhost:"+port),
+		BrandName:    brandName,
+		AdminEmail:   adminEmail,
 		BotEmail: EmailConfig{
 			Address:  getEnv("BOT_EMAIL_ADDR", ""),
 			Password: getEnv("BOT_EMAIL_PASS", ""),
 			Host:     getEnv("BOT_EMAIL_HOST", "smtp.gmail.com"),
 			Port:     emailPort,
 		},
+		MailTransport:               getEnv("MAIL_TRANSPORT", "smtp"),
+		MailSendmailPath:            getEnv("MAIL_SENDMAIL_PATH", "/usr/sbin/sendmail"),
+		MailFileDir:                 getEnv("MAIL_FILE_DIR", "tmp/mail"),
+		MailTemplateDir:             getEnv("MAIL_TEMPLATE_DIR", ""),
+		CommentEditWindow:           time.Duration(getEnvInt("COMMENT_EDIT_WINDOW_MINUTES", 15)) * time.Minute,
+		DeletedCommentRetention:     time.Duration(getEnvInt("DELETED_COMMENT_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		MailWorkers:                 getEnvInt("MAIL_WORKERS", 2),
+		MailMaxAttempts:             getEnvInt("MAIL_MAX_ATTEMPTS", 8),
+		EventsSource:                getEnv("EVENTS_SOURCE", "local"),
+		InstanceName:                instanceName(),
+		WebhookAllowPrivateNetworks: getEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false") == "true",
+		TelegramAPIURL:              getEnv("TELEGRAM_API_URL", "https://api.telegram.org"),
+		AkismetBaseURL:              getEnv("AKISMET_BASE_URL", "https://rest.akismet.com/1.1"),
+		RateLimitStore:              getEnv("RATE_LIMIT_STORE", "memory"),
+		RateLimits:                  getEnv("RATE_LIMITS", ""),
+		TrustedProxies:              getEnvList("TRUSTED_PROXIES"),
 	}
 
 	return config, nil
 }
 
+// instanceName returns INSTANCE_NAME, or the hostname if it isn't set
+func instanceName() string {
+	if name := getEnv("INSTANCE_NAME", ""); name != "" {
+		return name
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "default"
+	}
+	return hostname
+}
+
 // getEnv gets an environment variable or returns a default value
 // This is a private function (lowercase first letter)
 func getEnv(key, defaultValue string) string {
@@ -84,6 +168,27 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// getEnvInt gets an integer environment variable or returns a default value
+// Invalid numbers fall back to the default, same as BOT_EMAIL_PORT
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList gets a comma-separated environment variable, nil if it's empty
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // MustLoad loads config and panics if it fails
 // "Must" prefix is a Go convention for functions that panic on error
 func MustLoad() *Config {
@@ -93,4 +198,3 @@ func MustLoad() *Config {
 	}
 	return config
 }
-
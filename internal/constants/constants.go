@@ -14,4 +14,7 @@ const (
 
 	// Date format for email notifications
 	DateFormat = "02 Jan 2006 - 15:04"
+
+	// Body shown in place of a deleted comment that still has replies
+	DeletedCommentBody = "[deleted]"
 )
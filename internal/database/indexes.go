@@ -0,0 +1,193 @@
+package database
+
+import (
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+const (
+	// sentMailRetentionSeconds is how long delivered emails stay in the outbox
+	sentMailRetentionSeconds = 7 * 24 * 60 * 60
+
+	// webhookDeliveryRetentionSeconds is how long the webhook delivery log is kept
+	webhookDeliveryRetentionSeconds = 30 * 24 * 60 * 60
+)
+
+// EnsureIndexes creates the indexes the application relies on
+// Creating an index that already exists is a no-op, so this runs on every start.
+func EnsureIndexes() error {
+	_, err := mgm.Coll(&models.OutboxMessage{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		// Enqueuing the same email twice must not send it twice
+		{
+			Keys:    bson.D{{Key: "idempotencyKey", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Workers look for due messages
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}},
+		},
+		// Delivered emails are removed after a while; only they have sentAt
+		{
+			Keys:    bson.D{{Key: "sentAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(sentMailRetentionSeconds),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// One cursor per change feed
+	_, err = mgm.Coll(&models.EventCursor{}).Indexes().CreateOne(mgm.Ctx(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = mgm.Coll(&models.Webhook{}).Indexes().CreateOne(mgm.Ctx(), mongo.IndexModel{
+		Keys: bson.D{{Key: "siteId", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Spent single-use tokens are forgotten once they expire
+	_, err = mgm.Coll(&models.UsedToken{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tokenId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// One bucket per rate limit key, forgotten once it has refilled
+	_, err = mgm.Coll(&models.RateLimitBucket{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = mgm.Coll(&models.BlocklistEntry{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "siteId", Value: 1}, {Key: "type", Value: 1}, {Key: "value", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Entries with an expiry are removed once it has passed
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// One reputation per author and site
+	_, err = mgm.Coll(&models.Reputation{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "siteId", Value: 1}, {Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "siteId", Value: 1}, {Key: "updatedAt", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Each reader reports a comment once
+	_, err = mgm.Coll(&models.Report{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "commentId", Value: 1}, {Key: "reporter", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "siteId", Value: 1}, {Key: "resolvedAt", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Spam heuristics look at an author's recent comments and repeated bodies
+	_, err = mgm.Coll(&models.Comment{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "domain", Value: 1}, {Key: "email", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "domain", Value: 1}, {Key: "ip", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "domain", Value: 1}, {Key: "bodyHash", Value: 1}, {Key: "createdAt", Value: -1}}},
+		// Threads load replies by parent, and search looks for them
+		{Keys: bson.D{{Key: "parentId", Value: 1}}},
+		// Site owners search the comments of their site. Words aren't stemmed,
+		// as sites write in any language.
+		{
+			Keys: bson.D{{Key: "domain", Value: 1}, {Key: "body", Value: "text"}, {Key: "author", Value: "text"}},
+			Options: options.Index().
+				SetName("comments_search").
+				SetDefaultLanguage("none").
+				SetWeights(bson.D{{Key: "body", Value: 1}, {Key: "author", Value: 3}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// One spam classifier per site
+	_, err = mgm.Coll(&models.SpamCorpus{}).Indexes().CreateOne(mgm.Ctx(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "siteId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = mgm.Coll(&models.SpamToken{}).Indexes().CreateOne(mgm.Ctx(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "siteId", Value: 1}, {Key: "token", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = mgm.Coll(&models.NotificationChannel{}).Indexes().CreateOne(mgm.Ctx(), mongo.IndexModel{
+		Keys: bson.D{{Key: "siteId", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = mgm.Coll(&models.WebhookDelivery{}).Indexes().CreateMany(mgm.Ctx(), []mongo.IndexModel{
+		// An event seen by several instances is delivered once
+		{
+			Keys:    bson.D{{Key: "idempotencyKey", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		// Workers look for due deliveries
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}},
+		},
+		// Delivery log of a webhook
+		{
+			Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		// The log is trimmed after a while
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(webhookDeliveryRetentionSeconds),
+		},
+	})
+	return err
+}
@@ -2,8 +2,11 @@ package errors
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+
+	"zoomment-server/internal/i18n"
 )
 
 // AppError represents a structured application error
@@ -12,6 +15,7 @@ type AppError struct {
 	Message    string `json:"message"`          // Human-readable message
 	StatusCode int    `json:"-"`                // HTTP status code
 	Details    any    `json:"details,omitempty"` // Additional details (validation errors, etc.)
+	RetryAfter int    `json:"-"`                // Seconds until the request may be retried (Retry-After header)
 }
 
 // Error implements the error interface
@@ -29,6 +33,8 @@ const (
 	ErrCodeInternal     = "internal_error"
 	ErrCodeConflict     = "conflict"
 	ErrCodeBadRequest   = "bad_request"
+	ErrCodeRateLimited  = "rate_limited"
+	ErrCodeChallenge    = "challenge_failed"
 )
 
 // Pre-defined common errors
@@ -110,11 +116,24 @@ func BadRequest(message string) *AppError {
 	}
 }
 
+// TooManyRequests creates a rate limit error
+// retryAfter (seconds) is sent in the Retry-After header and in the details.
+func TooManyRequests(retryAfter int) *AppError {
+	return &AppError{
+		Code:       ErrCodeRateLimited,
+		Message:    "Too many requests, please try again later",
+		StatusCode: http.StatusTooManyRequests,
+		Details:    gin.H{"retryAfter": retryAfter},
+		RetryAfter: retryAfter,
+	}
+}
+
 // Response sends an error response to the client
 // Format: {"message": "...", "code": "...", "details": ...}
 // "message" is always present (Node.js compatible), others are optional
+// "message" is translated to the request locale; "code" never changes
 func (e *AppError) Response(c *gin.Context) {
-	response := gin.H{"message": e.Message}
+	response := gin.H{"message": i18n.T(i18n.FromContext(c), e.Message)}
 
 	// Add optional fields only if they have values
 	if e.Code != "" {
@@ -123,13 +142,16 @@ func (e *AppError) Response(c *gin.Context) {
 	if e.Details != nil {
 		response["details"] = e.Details
 	}
+	if e.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
 
 	c.JSON(e.StatusCode, response)
 }
 
 // Abort sends an error response and aborts the request
 func (e *AppError) Abort(c *gin.Context) {
-	response := gin.H{"message": e.Message}
+	response := gin.H{"message": i18n.T(i18n.FromContext(c), e.Message)}
 
 	if e.Code != "" {
 		response["code"] = e.Code
@@ -137,6 +159,9 @@ func (e *AppError) Abort(c *gin.Context) {
 	if e.Details != nil {
 		response["details"] = e.Details
 	}
+	if e.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
 
 	c.AbortWithStatusJSON(e.StatusCode, response)
 }
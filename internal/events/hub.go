@@ -0,0 +1,306 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types
+const (
+	CommentCreated  = "comment.created"
+	CommentEdited   = "comment.edited"
+	CommentDeleted  = "comment.deleted"
+	VoteChanged     = "vote.changed"
+	ReactionChanged = "reaction.changed"
+	PresenceChanged = "presence"
+)
+
+// Event is something that happened on a page
+// Data is sent to clients as-is, so it must only hold public fields.
+// Key identifies the change that caused the event, the same on every instance
+// when events come from the database; consumers that must act once per change
+// (like webhooks) deduplicate on it.
+type Event struct {
+	ID     uint64 `json:"id,omitempty"` // 0 for transient events
+	Type   string `json:"type"`
+	PageID string `json:"pageId"`
+	Data   any    `json:"data"`
+	Key    string `json:"-"`
+	Domain string `json:"-"` // Site of the page, for listeners
+}
+
+// Listener is called for every event published on a hub
+// Listeners run on the publishing goroutine and must not block.
+type Listener func(Event)
+
+const (
+	// historySize is how many recent events are kept for replay
+	historySize = 1000
+
+	// subscriptionBuffer is how many events a subscriber may fall behind
+	// before it is disconnected (it can resume with its last event ID)
+	subscriptionBuffer = 64
+)
+
+// Hub is an in-process publish/subscribe hub for page events
+type Hub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	historySize int
+	pages       map[string]map[*Subscription]struct{}
+	listeners   []Listener
+	keyPrefix   string
+
+	// floor is the last ID before the history; clients that saw it missed nothing
+	floor uint64
+}
+
+// NewHub creates a hub that keeps the last historySize events for replay
+func NewHub(historySize int) *Hub {
+	// IDs start from the current time so IDs from before a restart
+	// are recognised as too old to resume from
+	start := uint64(time.Now().UnixMicro())
+
+	// Keys of local events must not collide with another instance's
+	prefix := make([]byte, 4)
+	rand.Read(prefix)
+
+	return &Hub{
+		keyPrefix:   hex.EncodeToString(prefix) + ":",
+		lastID:      start,
+		floor:       start,
+		historySize: historySize,
+		pages:       make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the events of the pages it follows
+// Events is closed when the subscription is closed or falls too far behind.
+type Subscription struct {
+	Events <-chan Event
+
+	hub    *Hub
+	events chan Event
+	pages  map[string]struct{}
+	closed bool
+}
+
+// Subscribe creates a subscription for some pages
+func (h *Hub) Subscribe(pageIDs ...string) *Subscription {
+	events := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{
+		Events: events,
+		hub:    h,
+		events: events,
+		pages:  make(map[string]struct{}),
+	}
+
+	for _, pageID := range pageIDs {
+		sub.Follow(pageID)
+	}
+	return sub
+}
+
+// Follow starts receiving events for a page
+func (s *Subscription) Follow(pageID string) {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	if h.pages[pageID] == nil {
+		h.pages[pageID] = make(map[*Subscription]struct{})
+	}
+	h.pages[pageID][s] = struct{}{}
+	s.pages[pageID] = struct{}{}
+}
+
+// Unfollow stops receiving events for a page
+func (s *Subscription) Unfollow(pageID string) {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.unfollow(s, pageID)
+}
+
+// Close ends the subscription and closes Events
+func (s *Subscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.close(s)
+}
+
+// Publish sends an event to the subscribers of its page
+// Returns the event with its ID set.
+func (h *Hub) Publish(pageID, eventType string, data any) Event {
+	return h.PublishEvent(Event{Type: eventType, PageID: pageID, Data: data})
+}
+
+// PublishEvent sends an event that may already have an ID and key
+// IDs must grow; an event without one (or with an older one) gets the next ID.
+func (h *Hub) PublishEvent(event Event) Event {
+	h.mu.Lock()
+
+	if event.ID <= h.lastID {
+		event.ID = h.lastID + 1
+	}
+	h.lastID = event.ID
+	if event.Key == "" {
+		event.Key = h.keyPrefix + strconv.FormatUint(event.ID, 10)
+	}
+
+	h.history = append(h.history, event)
+	if len(h.history) > h.historySize {
+		evicted := len(h.history) - h.historySize
+		h.floor = h.history[evicted-1].ID
+		h.history = h.history[evicted:]
+	}
+
+	for sub := range h.pages[event.PageID] {
+		select {
+		case sub.events <- event:
+		default:
+			// Too slow - drop it, the client reconnects and resumes
+			h.close(sub)
+		}
+	}
+
+	listeners := h.listeners
+	h.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+	return event
+}
+
+// Listen registers a listener for all events of the hub
+// Transient events are not passed to listeners.
+func (h *Hub) Listen(listener Listener) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.listeners = append(h.listeners, listener)
+}
+
+// Broadcast sends a transient event to the subscribers of a page
+// Transient events have no ID and are not kept for replay.
+func (h *Hub) Broadcast(pageID, eventType string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	event := Event{Type: eventType, PageID: pageID, Data: data}
+	for sub := range h.pages[pageID] {
+		select {
+		case sub.events <- event:
+		default:
+			h.close(sub)
+		}
+	}
+}
+
+// Replay returns the events of a page published after lastID
+// ok is false when events may be missing (lastID is older than the history
+// or from before a restart); the client should then reload the page data.
+func (h *Hub) Replay(pageID string, lastID uint64) (events []Event, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastID > h.lastID {
+		return nil, false
+	}
+	if lastID == h.lastID {
+		return nil, true
+	}
+	if lastID < h.floor {
+		return nil, false
+	}
+
+	for _, event := range h.history {
+		if event.ID > lastID && event.PageID == pageID {
+			events = append(events, event)
+		}
+	}
+	return events, true
+}
+
+// unfollow removes a subscription from a page (caller holds the lock)
+func (h *Hub) unfollow(s *Subscription, pageID string) {
+	delete(s.pages, pageID)
+	if subs := h.pages[pageID]; subs != nil {
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(h.pages, pageID)
+		}
+	}
+}
+
+// close removes a subscription from all pages and closes its channel (caller holds the lock)
+func (h *Hub) close(s *Subscription) {
+	if s.closed {
+		return
+	}
+	for pageID := range s.pages {
+		h.unfollow(s, pageID)
+	}
+	s.closed = true
+	close(s.events)
+}
+
+// Default is the hub used by the package-level functions
+var Default = NewHub(historySize)
+
+// localPublishing is turned off when events come from the database instead
+var localPublishing atomic.Bool
+
+func init() {
+	localPublishing.Store(true)
+}
+
+// SetLocalPublishing chooses whether Publish sends events
+// When another source (like the change feed) publishes every change to
+// Default, handlers keep calling Publish and their events are dropped.
+func SetLocalPublishing(enabled bool) {
+	localPublishing.Store(enabled)
+}
+
+// Publish sends an event about a local change through the default hub
+func Publish(pageID, eventType string, data any) Event {
+	if !localPublishing.Load() {
+		return Event{}
+	}
+	return Default.Publish(pageID, eventType, data)
+}
+
+// PublishEvent sends an event about a local change through the default hub
+func PublishEvent(event Event) Event {
+	if !localPublishing.Load() {
+		return Event{}
+	}
+	return Default.PublishEvent(event)
+}
+
+// Listen registers a listener on the default hub
+func Listen(listener Listener) {
+	Default.Listen(listener)
+}
+
+// Subscribe creates a subscription on the default hub
+func Subscribe(pageIDs ...string) *Subscription {
+	return Default.Subscribe(pageIDs...)
+}
+
+// Replay returns missed events from the default hub
+func Replay(pageID string, lastID uint64) ([]Event, bool) {
+	return Default.Replay(pageID, lastID)
+}
@@ -0,0 +1,135 @@
+package events
+
+import "testing"
+
+func TestPublishReachesFollowers(t *testing.T) {
+	hub := NewHub(10)
+	sub := hub.Subscribe("page-a")
+	defer sub.Close()
+
+	hub.Publish("page-b", CommentCreated, nil)
+	sent := hub.Publish("page-a", CommentCreated, "hello")
+
+	select {
+	case event := <-sub.Events:
+		if event.ID != sent.ID || event.Data != "hello" {
+			t.Errorf("got %+v, want %+v", event, sent)
+		}
+	default:
+		t.Fatal("no event received")
+	}
+
+	select {
+	case event := <-sub.Events:
+		t.Errorf("received event of another page: %+v", event)
+	default:
+	}
+}
+
+func TestFollowAndUnfollow(t *testing.T) {
+	hub := NewHub(10)
+	sub := hub.Subscribe()
+	defer sub.Close()
+
+	sub.Follow("page-a")
+	sub.Follow("page-b")
+	sub.Unfollow("page-a")
+
+	hub.Publish("page-a", VoteChanged, nil)
+	hub.Publish("page-b", VoteChanged, nil)
+
+	if got := len(sub.Events); got != 1 {
+		t.Errorf("received %d events, want 1", got)
+	}
+}
+
+func TestSlowSubscriberIsClosed(t *testing.T) {
+	hub := NewHub(1000)
+	sub := hub.Subscribe("page")
+
+	for i := 0; i < subscriptionBuffer+1; i++ {
+		hub.Publish("page", VoteChanged, i)
+	}
+
+	received := 0
+	for range sub.Events {
+		received++
+	}
+	if received != subscriptionBuffer {
+		t.Errorf("received %d events before close, want %d", received, subscriptionBuffer)
+	}
+
+	// Closing again is safe
+	sub.Close()
+}
+
+func TestReplay(t *testing.T) {
+	hub := NewHub(3)
+	first := hub.Publish("page", CommentCreated, 1)
+	second := hub.Publish("other", CommentCreated, 2)
+	third := hub.Publish("page", CommentEdited, 3)
+
+	events, ok := hub.Replay("page", first.ID)
+	if !ok || len(events) != 1 || events[0].ID != third.ID {
+		t.Errorf("Replay(after first) = %v, %v", events, ok)
+	}
+
+	events, ok = hub.Replay("page", third.ID)
+	if !ok || len(events) != 0 {
+		t.Errorf("Replay(up to date) = %v, %v", events, ok)
+	}
+
+	// History holds 3 events: after one more, the first is gone
+	hub.Publish("page", CommentDeleted, 4)
+	if _, ok := hub.Replay("page", first.ID-1); ok {
+		t.Error("Replay() from before the history should not be ok")
+	}
+	if _, ok := hub.Replay("page", second.ID-1); !ok {
+		t.Error("Replay() from the start of the history should be ok")
+	}
+
+	// IDs from the future (e.g. another process) can't be resumed
+	if _, ok := hub.Replay("page", third.ID+100); ok {
+		t.Error("Replay() with an unknown ID should not be ok")
+	}
+}
+
+func TestPublishEventKeepsGrowingIDs(t *testing.T) {
+	hub := NewHub(10)
+	external := hub.lastID + 1000
+
+	first := hub.PublishEvent(Event{ID: external, Type: VoteChanged, PageID: "page", Key: "change-1"})
+	if first.ID != external || first.Key != "change-1" {
+		t.Errorf("got %+v, want ID %d and key change-1", first, external)
+	}
+
+	// An older ID (e.g. from polling) gets the next one
+	second := hub.PublishEvent(Event{ID: 5, Type: VoteChanged, PageID: "page"})
+	if second.ID != external+1 {
+		t.Errorf("got ID %d, want %d", second.ID, external+1)
+	}
+	if second.Key == "" {
+		t.Error("events without a key should get one")
+	}
+
+	// IDs with gaps can still be resumed
+	if events, ok := hub.Replay("page", first.ID); !ok || len(events) != 1 {
+		t.Errorf("Replay(after first) = %v, %v", events, ok)
+	}
+}
+
+func TestListenersReceiveEvents(t *testing.T) {
+	hub := NewHub(10)
+	var received []string
+	hub.Listen(func(event Event) {
+		received = append(received, event.Type)
+	})
+
+	hub.Publish("page", CommentCreated, nil)
+	hub.Broadcast("page", PresenceChanged, nil)
+	hub.Publish("other", VoteChanged, nil)
+
+	if len(received) != 2 || received[0] != CommentCreated || received[1] != VoteChanged {
+		t.Errorf("listener received %v", received)
+	}
+}
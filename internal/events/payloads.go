@@ -0,0 +1,31 @@
+package events
+
+import "zoomment-server/internal/models"
+
+// Event payloads
+// Fields tagged json:"-" are for listeners on the server (like webhooks)
+// and never reach readers.
+
+// CommentDeletedEvent is the data of a comment.deleted event
+type CommentDeletedEvent struct {
+	ID        string  `json:"_id"`
+	ParentID  *string `json:"parentId"`
+	Tombstone bool    `json:"tombstone"` // Still shown as "[deleted]" because it has replies
+}
+
+// VoteChangedEvent is the data of a vote.changed event
+// Unlike VoteResponse it doesn't say how anyone voted: events go to every reader.
+type VoteChangedEvent struct {
+	CommentID string `json:"commentId"`
+	Upvotes   int    `json:"upvotes"`
+	Downvotes int    `json:"downvotes"`
+	Score     int    `json:"score"`
+}
+
+// ReactionChangedEvent is the data of a reaction.changed event
+type ReactionChangedEvent struct {
+	Aggregation []models.ReactionAggregation `json:"aggregation"`
+
+	// Added is the reaction that was added (empty when one was removed)
+	Added string `json:"-"`
+}
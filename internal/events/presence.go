@@ -0,0 +1,79 @@
+package events
+
+import "sync"
+
+// PresenceEvent is the data of a presence event
+type PresenceEvent struct {
+	Readers int `json:"readers"`
+}
+
+// Presence counts the readers connected to each page
+// A reader is identified by its fingerprint, so several tabs of the same
+// browser count once.
+type Presence struct {
+	mu      sync.Mutex
+	hub     *Hub
+	readers map[string]map[string]int // pageID -> reader -> connections
+}
+
+// NewPresence creates a presence tracker that announces changes through hub
+func NewPresence(hub *Hub) *Presence {
+	return &Presence{
+		hub:     hub,
+		readers: make(map[string]map[string]int),
+	}
+}
+
+// Join adds a connection of a reader to a page
+// The current count is always announced so the new connection learns it.
+func (p *Presence) Join(pageID, reader string) int {
+	p.mu.Lock()
+	if p.readers[pageID] == nil {
+		p.readers[pageID] = make(map[string]int)
+	}
+	p.readers[pageID][reader]++
+	count := len(p.readers[pageID])
+	p.mu.Unlock()
+
+	p.hub.Broadcast(pageID, PresenceChanged, PresenceEvent{Readers: count})
+	return count
+}
+
+// Leave removes a connection of a reader from a page
+// Changes are announced only when the reader's last connection is gone.
+func (p *Presence) Leave(pageID, reader string) int {
+	p.mu.Lock()
+	readers := p.readers[pageID]
+	if readers[reader] == 0 {
+		count := len(readers)
+		p.mu.Unlock()
+		return count
+	}
+
+	readers[reader]--
+	left := readers[reader] == 0
+	if left {
+		delete(readers, reader)
+	}
+	count := len(readers)
+	if count == 0 {
+		delete(p.readers, pageID)
+	}
+	p.mu.Unlock()
+
+	if left {
+		p.hub.Broadcast(pageID, PresenceChanged, PresenceEvent{Readers: count})
+	}
+	return count
+}
+
+// Count returns the number of readers of a page
+func (p *Presence) Count(pageID string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.readers[pageID])
+}
+
+// DefaultPresence tracks readers on the default hub
+var DefaultPresence = NewPresence(Default)
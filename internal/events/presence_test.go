@@ -0,0 +1,58 @@
+package events
+
+import "testing"
+
+func TestPresenceCountsDistinctReaders(t *testing.T) {
+	hub := NewHub(10)
+	presence := NewPresence(hub)
+
+	steps := []struct {
+		join   bool
+		reader string
+		want   int
+	}{
+		{true, "alice", 1},
+		{true, "alice", 1}, // second tab
+		{true, "bob", 2},
+		{false, "alice", 2}, // alice still has a tab open
+		{false, "alice", 1},
+		{false, "alice", 1}, // already gone
+		{false, "bob", 0},
+	}
+
+	for i, step := range steps {
+		var got int
+		if step.join {
+			got = presence.Join("page", step.reader)
+		} else {
+			got = presence.Leave("page", step.reader)
+		}
+		if got != step.want {
+			t.Errorf("step %d: got %d readers, want %d", i, got, step.want)
+		}
+	}
+
+	if len(presence.readers) != 0 {
+		t.Errorf("empty pages should be removed, got %v", presence.readers)
+	}
+}
+
+func TestPresenceBroadcastsTransientEvents(t *testing.T) {
+	hub := NewHub(10)
+	presence := NewPresence(hub)
+	sub := hub.Subscribe("page")
+	defer sub.Close()
+
+	presence.Join("page", "alice")
+
+	event := <-sub.Events
+	if event.ID != 0 || event.Type != PresenceChanged {
+		t.Errorf("got %+v, want a transient presence event", event)
+	}
+	if data, ok := event.Data.(PresenceEvent); !ok || data.Readers != 1 {
+		t.Errorf("got data %+v, want 1 reader", event.Data)
+	}
+	if missed, _ := hub.Replay("page", 0); len(missed) != 0 {
+		t.Errorf("presence events should not be replayed, got %v", missed)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/repository"
+)
+
+// outboxFailuresLimit is how many failed emails the outbox overview lists
+const outboxFailuresLimit = 50
+
+// GetOutbox returns the outbox queue depth and recent delivery failures
+// GET /api/admin/outbox
+func GetOutbox(c *gin.Context) {
+	stats, err := repository.GetOutboxStats()
+	if err != nil {
+		logger.Error(err, "Failed to count outbox messages")
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	failures, err := repository.GetFailedMail(outboxFailuresLimit)
+	if err != nil {
+		logger.Error(err, "Failed to get failed outbox messages")
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, OutboxResponse{
+		Pending:  stats.Pending,
+		Sending:  stats.Sending,
+		Sent:     stats.Sent,
+		Dead:     stats.Dead,
+		Failures: OutboxMessagesToResponse(failures),
+	})
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/services/blocklist"
+	"zoomment-server/internal/validators"
+)
+
+const (
+	// maxBlocklistEntries limits how many entries a site (or the global list) can have
+	maxBlocklistEntries = 1000
+
+	// blocklistReason is added to the spam reasons of held and shadow-banned comments
+	blocklistReason = "blocklist"
+)
+
+// ListBlocklist returns the blocklist of a site, newest first
+// GET /api/sites/:id/blocklist
+// GET /api/admin/blocklist (global entries)
+func ListBlocklist(c *gin.Context) {
+	siteID, ok := blocklistScope(c)
+	if !ok {
+		return
+	}
+
+	entries, err := repository.GetBlocklist(siteID)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	response := make([]BlocklistEntryResponse, 0, len(entries))
+	for i := range entries {
+		response = append(response, BlocklistEntryToResponse(&entries[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AddBlocklistEntry adds an email, email domain, IP range, fingerprint, word or pattern
+// POST /api/sites/:id/blocklist
+// POST /api/admin/blocklist (global entries)
+func AddBlocklistEntry(c *gin.Context) {
+	siteID, ok := blocklistScope(c)
+	if !ok {
+		return
+	}
+
+	var req validators.AddBlocklistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.BadRequest("Invalid blocklist entry").Response(c)
+		return
+	}
+
+	value, err := blocklist.Normalize(req.Type, req.Value)
+	if err != nil || (req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now())) {
+		errors.BadRequest("Invalid blocklist entry").Response(c)
+		return
+	}
+
+	count, err := repository.CountBlocklist(siteID)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+	if count >= maxBlocklistEntries {
+		errors.BadRequest("Too many blocklist entries").Response(c)
+		return
+	}
+
+	entry := &models.BlocklistEntry{
+		SiteID:    siteID,
+		Type:      req.Type,
+		Value:     value,
+		Mode:      req.Mode,
+		Note:      strings.TrimSpace(req.Note),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if entry.Mode == "" {
+		entry.Mode = models.BlockModeReject
+	}
+	if err := mgm.Coll(entry).Create(entry); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			errors.Conflict("Already on the blocklist").Response(c)
+			return
+		}
+		logger.Error(err, "Failed to create blocklist entry")
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, BlocklistEntryToResponse(entry))
+}
+
+// UpdateBlocklistEntry changes the mode, note or expiry of an entry
+// PATCH /api/sites/:id/blocklist/:entryId
+// PATCH /api/admin/blocklist/:entryId (global entries)
+// To change what an entry matches, delete it and add a new one.
+func UpdateBlocklistEntry(c *gin.Context) {
+	entry := findBlocklistEntry(c)
+	if entry == nil {
+		return
+	}
+
+	var req validators.UpdateBlocklistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.BadRequest("Invalid blocklist entry").Response(c)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		errors.BadRequest("Invalid blocklist entry").Response(c)
+		return
+	}
+
+	// Only overwrite the settings present in the request
+	if req.Mode != nil {
+		entry.Mode = *req.Mode
+	}
+	if req.Note != nil {
+		entry.Note = strings.TrimSpace(*req.Note)
+	}
+	if req.ExpiresAt != nil {
+		entry.ExpiresAt = req.ExpiresAt
+	}
+	if req.Permanent {
+		entry.ExpiresAt = nil
+	}
+
+	if err := mgm.Coll(entry).Update(entry); err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, BlocklistEntryToResponse(entry))
+}
+
+// DeleteBlocklistEntry removes an entry
+// DELETE /api/sites/:id/blocklist/:entryId
+// DELETE /api/admin/blocklist/:entryId (global entries)
+func DeleteBlocklistEntry(c *gin.Context) {
+	entry := findBlocklistEntry(c)
+	if entry == nil {
+		return
+	}
+
+	if err := mgm.Coll(entry).Delete(entry); err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDeletedResponse(entry.ID.Hex()))
+}
+
+// ========================================
+// Helper Functions
+// ========================================
+
+// blocklistScope returns the site whose blocklist the URL is about
+// The admin routes have no site and manage the global entries (nil).
+// Responds with 404 and returns false if the site can't be used.
+func blocklistScope(c *gin.Context) (*primitive.ObjectID, bool) {
+	if c.Param("id") == "" {
+		return nil, true
+	}
+
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return nil, false
+	}
+	return &site.ID, true
+}
+
+// findBlocklistEntry loads the blocklist entry in the URL
+// Responds with 404 and returns nil if it can't be used
+func findBlocklistEntry(c *gin.Context) *models.BlocklistEntry {
+	siteID, ok := blocklistScope(c)
+	if !ok {
+		return nil
+	}
+
+	entryID, err := primitive.ObjectIDFromHex(c.Param("entryId"))
+	if err != nil {
+		errors.NotFound("Blocklist entry").Response(c)
+		return nil
+	}
+
+	entry, err := repository.GetBlocklistEntry(siteID, entryID)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return nil
+	}
+	if entry == nil {
+		errors.NotFound("Blocklist entry").Response(c)
+		return nil
+	}
+
+	return entry
+}
+
+// checkBlocklist looks up who is writing in the blocklist of a site and the
+// global one. site is nil for unregistered domains.
+// Rejecting entries are answered with 403; otherwise the mode of the matching
+// entry is returned ("" = none) for the caller to apply.
+func checkBlocklist(site *models.Site, subject blocklist.Subject) (string, *errors.AppError) {
+	var siteID *primitive.ObjectID
+	if site != nil {
+		siteID = &site.ID
+	}
+
+	entries, err := repository.ActiveBlocklist(siteID)
+	if err != nil {
+		logger.Error(err, "Failed to check blocklist")
+		return "", errors.ErrDatabaseError
+	}
+
+	entry := blocklist.Match(entries, subject, time.Now())
+	if entry == nil {
+		return "", nil
+	}
+	if entry.CurrentMode() == models.BlockModeReject {
+		return "", errors.New(errors.ErrCodeForbidden, "You can't comment on this site", http.StatusForbidden)
+	}
+	return entry.CurrentMode(), nil
+}
+
+// blocklistSubject describes who sends a vote or reaction
+func blocklistSubject(origin commentOrigin) blocklist.Subject {
+	subject := blocklist.Subject{IP: origin.IP, Fingerprint: origin.Fingerprint}
+	if origin.User != nil {
+		subject.Email = origin.User.Email
+	}
+	return subject
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"zoomment-server/internal/challenge"
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/middleware"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/validators"
+)
+
+// GetChallenge issues a proof-of-work challenge for a page
+// GET /api/challenge?pageId=xxx
+// Returns {"required": false} when the page's site doesn't ask for one.
+// Clients that ask for many challenges get harder ones.
+func GetChallenge(cfg *config.Config) gin.HandlerFunc {
+	activity := challenge.NewActivity()
+
+	return func(c *gin.Context) {
+		domain, err := ExtractDomainFromPageID(c.Query("pageId"))
+		if err != nil || domain == "" {
+			errors.BadRequest("Invalid pageId").Response(c)
+			return
+		}
+
+		site := siteForDomain(domain)
+		if !challengeRequired(site, middleware.GetUser(c)) {
+			c.JSON(http.StatusOK, ChallengeResponse{Required: false})
+			return
+		}
+
+		keys := []string{"ip:" + c.ClientIP()}
+		if fingerprint := c.GetHeader("fingerprint"); fingerprint != "" {
+			keys = append(keys, "fingerprint:"+fingerprint)
+		}
+		difficulty := challenge.Difficulty(siteDifficulty(site), activity.Add(keys...))
+
+		issued, err := challenge.Issue(cfg.JWTSecret, site.Domain, difficulty)
+		if err != nil {
+			logger.Error(err, "Failed to sign challenge")
+			errors.ErrInternalError.Response(c)
+			return
+		}
+
+		c.JSON(http.StatusOK, ChallengeResponse{
+			Required:   true,
+			Challenge:  issued.Token,
+			Algorithm:  challenge.Algorithm,
+			Difficulty: issued.Difficulty,
+			ExpiresAt:  &issued.ExpiresAt,
+		})
+	}
+}
+
+// ========================================
+// Helper Functions
+// ========================================
+
+// challengeRequired reports whether a request must come with a solved challenge
+// Signed-in users never need one.
+func challengeRequired(site *models.Site, user *models.User) bool {
+	return site != nil && site.Challenge != nil && site.Challenge.Enabled && user == nil
+}
+
+// siteDifficulty returns the base difficulty of a site's challenges
+func siteDifficulty(site *models.Site) int {
+	if site.Challenge.Difficulty > 0 {
+		return site.Challenge.Difficulty
+	}
+	return challenge.DefaultDifficulty
+}
+
+// checkChallenge verifies the proof of work of a comment, vote or reaction
+// when the site asks for it. Each solution can be used once.
+func checkChallenge(cfg *config.Config, site *models.Site, user *models.User, solution *validators.ChallengeSolution) *errors.AppError {
+	if !challengeRequired(site, user) {
+		return nil
+	}
+	if solution == nil {
+		return errors.New(errors.ErrCodeChallenge, "Proof of work required", http.StatusForbidden)
+	}
+
+	id, expiresAt, err := challenge.Verify(cfg.JWTSecret, site.Domain, solution.Challenge, solution.Nonce)
+	if err != nil {
+		return errors.New(errors.ErrCodeChallenge, "Invalid proof of work", http.StatusForbidden)
+	}
+
+	fresh, err := repository.UseToken("challenge:"+id, expiresAt)
+	if err != nil {
+		logger.Error(err, "Failed to use challenge")
+		return errors.ErrDatabaseError
+	}
+	if !fresh {
+		return errors.New(errors.ErrCodeChallenge, "Invalid proof of work", http.StatusForbidden)
+	}
+	return nil
+}
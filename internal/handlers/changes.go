@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/events"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/services/changefeed"
+)
+
+// PublishChange turns a database change into the events the handlers would publish
+// Used when events come from the change feed (EVENTS_SOURCE=changestream).
+func PublishChange(change changefeed.Change) {
+	publish := func(domain, pageID, eventType string, data any) {
+		events.Default.PublishEvent(events.Event{
+			ID:     change.ID,
+			Key:    change.Key,
+			Type:   eventType,
+			Domain: domain,
+			PageID: pageID,
+			Data:   data,
+		})
+	}
+
+	// Deletes only carry the old document when the server keeps pre-images
+	doc := change.Document
+	if doc == nil {
+		doc = change.Before
+	}
+	if doc == nil {
+		return
+	}
+
+	var err error
+	switch change.Collection {
+	case mgm.CollName(&models.Comment{}):
+		err = publishCommentChange(change, doc, publish)
+	case mgm.CollName(&models.Vote{}):
+		err = publishVoteChange(change, doc, publish)
+	case mgm.CollName(&models.Reaction{}):
+		err = publishReactionChange(change, doc, publish)
+	}
+	if err != nil {
+		logger.Error(err, "Failed to publish "+change.Collection+" change")
+	}
+}
+
+// publishFunc publishes an event with the ID and key of a change
+type publishFunc func(domain, pageID, eventType string, data any)
+
+// publishCommentChange announces a created, edited, moderated or deleted comment
+// Only comments readers could see are announced as deleted, so the feed
+// doesn't give away comments waiting for moderation.
+func publishCommentChange(change changefeed.Change, doc bson.Raw, publish publishFunc) error {
+	comment := &models.Comment{}
+	if err := bson.Unmarshal(doc, comment); err != nil {
+		return err
+	}
+	approved := comment.CurrentStatus() == models.CommentStatusApproved
+	deleted := events.CommentDeletedEvent{ID: comment.ID.Hex(), ParentID: comment.ParentID}
+
+	previous, known := "", false
+	if change.Operation != changefeed.Delete && comment.DeletedAt == nil {
+		previous, known = polledStatuses.swap(comment.ID, comment.CurrentStatus())
+	} else {
+		polledStatuses.forget(comment.ID)
+	}
+
+	switch {
+	case change.Operation == changefeed.Delete:
+		// Purged comments were announced when they were deleted
+		if comment.DeletedAt == nil && approved {
+			publish(comment.Domain, comment.PageID, events.CommentDeleted, deleted)
+		}
+	case comment.DeletedAt != nil:
+		// Deleted, or a tombstone that lost its last reply (deleting doesn't
+		// change the status)
+		if approved && (change.UpdatedFields == nil || change.Changed("deletedAt", "tombstone")) {
+			deleted.Tombstone = comment.Tombstone
+			publish(comment.Domain, comment.PageID, events.CommentDeleted, deleted)
+		}
+	case change.Operation == changefeed.Insert:
+		if approved {
+			publish(comment.Domain, comment.PageID, events.CommentCreated, repository.PublicComment(comment))
+		}
+	case change.UpdatedFields == nil:
+		// Polling can't tell what changed: compare with the status seen last
+		// time, or guess from the edit time for comments not seen before.
+		// Comments not seen before that aren't approved were never shown.
+		switch {
+		case !approved:
+			if known && previous == models.CommentStatusApproved {
+				publish(comment.Domain, comment.PageID, events.CommentDeleted, deleted)
+			}
+		case known && previous != models.CommentStatusApproved,
+			!known && !justEdited(comment):
+			publish(comment.Domain, comment.PageID, events.CommentCreated, repository.PublicComment(comment))
+		default:
+			publish(comment.Domain, comment.PageID, events.CommentEdited, repository.PublicComment(comment))
+		}
+	case change.Changed("status"):
+		if approved {
+			publish(comment.Domain, comment.PageID, events.CommentCreated, repository.PublicComment(comment))
+		} else if wasApproved(change.Before) || known && previous == models.CommentStatusApproved {
+			publish(comment.Domain, comment.PageID, events.CommentDeleted, deleted)
+		}
+	case change.Changed("body") && approved:
+		publish(comment.Domain, comment.PageID, events.CommentEdited, repository.PublicComment(comment))
+	}
+
+	return nil
+}
+
+// wasApproved reports whether the document before a change was an approved
+// comment (false when the server didn't keep it)
+func wasApproved(before bson.Raw) bool {
+	comment := &models.Comment{}
+	if before == nil || bson.Unmarshal(before, comment) != nil {
+		return false
+	}
+	return comment.CurrentStatus() == models.CommentStatusApproved
+}
+
+// justEdited reports whether the last write to a comment was an edit by its author
+func justEdited(comment *models.Comment) bool {
+	return comment.EditedAt != nil && comment.UpdatedAt.Sub(*comment.EditedAt) < time.Second
+}
+
+// maxPolledStatuses bounds how many comment statuses polling remembers
+const maxPolledStatuses = 10000
+
+// statusMemory remembers the moderation status of the comments polling saw
+type statusMemory struct {
+	mu       sync.Mutex
+	statuses map[primitive.ObjectID]string
+}
+
+// polledStatuses tells moderation apart from edits for polled comments
+var polledStatuses = &statusMemory{statuses: make(map[primitive.ObjectID]string)}
+
+// swap remembers the status of a comment and returns the one before, if known
+func (m *statusMemory) swap(id primitive.ObjectID, status string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, known := m.statuses[id]
+	if !known && len(m.statuses) >= maxPolledStatuses {
+		// Start over rather than grow; forgotten comments are guessed
+		m.statuses = make(map[primitive.ObjectID]string)
+	}
+	m.statuses[id] = status
+	return previous, known
+}
+
+// forget drops a deleted comment
+func (m *statusMemory) forget(id primitive.ObjectID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.statuses, id)
+}
+
+// publishVoteChange announces the new vote counts of a comment
+func publishVoteChange(change changefeed.Change, doc bson.Raw, publish publishFunc) error {
+	vote := &models.Vote{}
+	if err := bson.Unmarshal(doc, vote); err != nil {
+		return err
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(vote.CommentID)
+	if err != nil {
+		return nil
+	}
+	comment := &models.Comment{}
+	if err := mgm.Coll(comment).FindByID(commentID, comment); err != nil {
+		// The comment is gone, nobody shows its votes
+		return nil
+	}
+
+	counts, err := calculateVoteCounts(vote.CommentID, "")
+	if err != nil {
+		return err
+	}
+
+	publish(comment.Domain, comment.PageID, events.VoteChanged, events.VoteChangedEvent{
+		CommentID: counts.CommentID,
+		Upvotes:   counts.Upvotes,
+		Downvotes: counts.Downvotes,
+		Score:     counts.Score,
+	})
+	return nil
+}
+
+// publishReactionChange announces the new reaction counts of a page
+func publishReactionChange(change changefeed.Change, doc bson.Raw, publish publishFunc) error {
+	reaction := &models.Reaction{}
+	if err := bson.Unmarshal(doc, reaction); err != nil {
+		return err
+	}
+
+	counts, err := getPageReactions(reaction.PageID, "")
+	if err != nil {
+		return err
+	}
+
+	changed := events.ReactionChangedEvent{Aggregation: counts.Aggregation}
+	if change.Operation != changefeed.Delete {
+		changed.Added = reaction.Reaction
+	}
+	publish(reaction.Domain, reaction.PageID, events.ReactionChanged, changed)
+	return nil
+}
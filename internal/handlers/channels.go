@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/validators"
+)
+
+// maxChannelsPerSite limits how many notification channels a site can have
+const maxChannelsPerSite = 10
+
+// ListChannels returns the notification channels of a site
+// GET /api/sites/:id/channels
+func ListChannels(c *gin.Context) {
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return
+	}
+
+	channels, err := repository.GetSiteChannels(site.ID)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	response := make([]ChannelResponse, 0, len(channels))
+	for i := range channels {
+		response = append(response, ChannelToResponse(&channels[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AddChannel connects a Slack, Discord, Telegram or Matrix chat to a site
+// POST /api/sites/:id/channels
+// New comments are posted there with approve/delete links.
+func AddChannel(c *gin.Context) {
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return
+	}
+
+	var req validators.AddChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.BadRequest("Invalid channel").Response(c)
+		return
+	}
+
+	channel := &models.NotificationChannel{
+		SiteID:        site.ID,
+		Type:          req.Type,
+		Active:        true,
+		WebhookURL:    req.WebhookURL,
+		BotToken:      req.BotToken,
+		ChatID:        req.ChatID,
+		HomeserverURL: req.HomeserverURL,
+		AccessToken:   req.AccessToken,
+		RoomID:        req.RoomID,
+	}
+	if !channel.Configured() {
+		errors.BadRequest("Invalid channel").Response(c)
+		return
+	}
+
+	existing, err := repository.GetSiteChannels(site.ID)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+	if len(existing) >= maxChannelsPerSite {
+		errors.BadRequest("Too many channels").Response(c)
+		return
+	}
+
+	if err := mgm.Coll(channel).Create(channel); err != nil {
+		logger.Error(err, "Failed to create notification channel")
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, ChannelToResponse(channel))
+}
+
+// UpdateChannel changes a notification channel
+// PATCH /api/sites/:id/channels/:channelId
+func UpdateChannel(c *gin.Context) {
+	channel := findOwnedChannel(c)
+	if channel == nil {
+		return
+	}
+
+	var req validators.UpdateChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.BadRequest("Invalid channel").Response(c)
+		return
+	}
+
+	// Only overwrite the settings present in the request
+	if req.WebhookURL != nil {
+		channel.WebhookURL = *req.WebhookURL
+	}
+	if req.BotToken != nil {
+		channel.BotToken = *req.BotToken
+	}
+	if req.ChatID != nil {
+		channel.ChatID = *req.ChatID
+	}
+	if req.HomeserverURL != nil {
+		channel.HomeserverURL = *req.HomeserverURL
+	}
+	if req.AccessToken != nil {
+		channel.AccessToken = *req.AccessToken
+	}
+	if req.RoomID != nil {
+		channel.RoomID = *req.RoomID
+	}
+	if req.Active != nil {
+		channel.Active = *req.Active
+	}
+	if !channel.Configured() {
+		errors.BadRequest("Invalid channel").Response(c)
+		return
+	}
+
+	if err := mgm.Coll(channel).Update(channel); err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, ChannelToResponse(channel))
+}
+
+// DeleteChannel disconnects a notification channel
+// DELETE /api/sites/:id/channels/:channelId
+func DeleteChannel(c *gin.Context) {
+	channel := findOwnedChannel(c)
+	if channel == nil {
+		return
+	}
+
+	if err := mgm.Coll(channel).Delete(channel); err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDeletedResponse(channel.ID.Hex()))
+}
+
+// findOwnedChannel loads the channel in the URL if its site belongs to the current user
+// Responds with 404 and returns nil if it can't be used
+func findOwnedChannel(c *gin.Context) *models.NotificationChannel {
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return nil
+	}
+
+	channelID, err := primitive.ObjectIDFromHex(c.Param("channelId"))
+	if err != nil {
+		errors.NotFound("Channel").Response(c)
+		return nil
+	}
+
+	channel, err := repository.GetChannel(site.ID, channelID)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return nil
+	}
+	if channel == nil {
+		errors.NotFound("Channel").Response(c)
+		return nil
+	}
+
+	return channel
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"net/url"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,16 +11,23 @@ import (
 	"github.com/kamva/mgm/v3"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"zoomment-server/internal/config"
 	"zoomment-server/internal/constants"
 	"zoomment-server/internal/errors"
+	"zoomment-server/internal/events"
+	"zoomment-server/internal/i18n"
 	"zoomment-server/internal/logger"
 	"zoomment-server/internal/middleware"
 	"zoomment-server/internal/models"
 	"zoomment-server/internal/repository"
+	"zoomment-server/internal/services/blocklist"
+	"zoomment-server/internal/services/chat"
 	"zoomment-server/internal/services/mailer"
+	"zoomment-server/internal/services/moderation"
+	"zoomment-server/internal/services/spam"
 	"zoomment-server/internal/utils"
 	"zoomment-server/internal/validators"
 )
@@ -51,7 +59,7 @@ func ListComments(c *gin.Context) {
 	}
 
 	// Fetch paginated comments with reply counts
-	response, err := repository.GetPaginatedComments(pageID, domain, limit, skip, sortOrder)
+	response, err := repository.GetPaginatedComments(pageID, domain, viewerEmail(c), limit, skip, sortOrder)
 	if err != nil {
 		logger.Error(err, "Failed to fetch comments")
 		errors.ErrDatabaseError.Response(c)
@@ -76,7 +84,7 @@ func ListReplies(c *gin.Context) {
 	limit, skip := repository.ParsePagination(c.Query("limit"), c.Query("skip"))
 
 	// Fetch replies
-	response, err := repository.GetRepliesForComment(commentID, limit, skip)
+	response, err := repository.GetRepliesForComment(commentID, viewerEmail(c), limit, skip)
 	if err != nil {
 		logger.Error(err, "Failed to fetch replies")
 		errors.ErrDatabaseError.Response(c)
@@ -89,8 +97,7 @@ func ListReplies(c *gin.Context) {
 // AddComment creates a new comment
 // POST /api/comments
 func AddComment(cfg *config.Config) gin.HandlerFunc {
-	// Create mailer instance
-	mailService := mailer.New(cfg)
+	services := newCommentServices(cfg)
 
 	return func(c *gin.Context) {
 		var req validators.AddCommentRequest
@@ -101,101 +108,216 @@ func AddComment(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		// Parse URL to get domain
-		parsedURL, err := url.Parse(req.PageURL)
-		if err != nil {
-			errors.BadRequest("Invalid page URL").Response(c)
+		comment, appErr := createComment(services, req, commentOriginOf(c))
+		if appErr != nil {
+			appErr.Response(c)
 			return
 		}
 
-		// Sanitize inputs to prevent XSS
-		email := utils.CleanEmail(req.Email)
-		author := utils.SanitizeStrict(utils.CleanName(req.Author)) // Remove ALL HTML from name
-		body := utils.SanitizeComment(req.Body)                      // Allow safe HTML in body
-
-		// Get current user
-		user := middleware.GetUser(c)
-		isVerified := user != nil && user.Email == email
-
-		// Create comment
-		comment := &models.Comment{
-			PageURL:    parsedURL.String(),
-			PageID:     req.PageID,
-			Domain:     parsedURL.Hostname(),
-			Body:       body,
-			Author:     author,
-			Email:      email,
-			Gravatar:   utils.GenerateGravatar(email),
-			ParentID:   req.ParentID,
-			IsVerified: isVerified,
-			Secret:     utils.GenerateSecret(),
-		}
+		// Return 200 OK with _id instead of id
+		c.JSON(http.StatusOK, authorResponse(comment))
+	}
+}
+
+// commentServices are what creating a comment needs besides the request
+// Created once per handler, like the mailer.
+type commentServices struct {
+	cfg  *config.Config
+	mail *mailer.Mailer
+	chat *chat.Sender
+	spam *spam.Pipeline
+}
+
+func newCommentServices(cfg *config.Config) *commentServices {
+	return &commentServices{
+		cfg:  cfg,
+		mail: mailer.New(cfg),
+		chat: chat.NewSender(cfg),
+		spam: spam.Default(cfg),
+	}
+}
+
+// commentOrigin describes who posts a comment (or votes) and from where
+// User is nil for guests and Locale is the language the commenter's browser asked for.
+type commentOrigin struct {
+	User        *models.User
+	Locale      string
+	IP          string
+	UserAgent   string
+	Fingerprint string
+}
+
+// commentOriginOf returns the origin of a request
+func commentOriginOf(c *gin.Context) commentOrigin {
+	return commentOrigin{
+		User:        middleware.GetUser(c),
+		Locale:      i18n.FromContext(c),
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Fingerprint: c.GetHeader("fingerprint"),
+	}
+}
+
+// createComment stores a validated comment, announces it and sends the notifications
+// Shared by the REST and WebSocket APIs. Comments on registered sites are
+// checked for spam and may be held for moderation or go straight to spam.
+func createComment(services *commentServices, req validators.AddCommentRequest, origin commentOrigin) (*models.Comment, *errors.AppError) {
+	cfg, mailService, user := services.cfg, services.mail, origin.User
+
+	// Parse URL to get domain
+	parsedURL, err := url.Parse(req.PageURL)
+	if err != nil {
+		return nil, errors.BadRequest("Invalid page URL")
+	}
+
+	// Sanitize inputs to prevent XSS
+	email := utils.CleanEmail(req.Email)
+	author := utils.SanitizeStrict(utils.CleanName(req.Author)) // Remove ALL HTML from name
 
-		err = mgm.Coll(comment).Create(comment)
+	// Registered site (nil if the domain isn't registered)
+	site := siteForDomain(parsedURL.Hostname())
+
+	// Sites may make guests prove some work first
+	if appErr := checkChallenge(cfg, site, user, req.PoW); appErr != nil {
+		return nil, appErr
+	}
+
+	// Authors on the site's or the global blocklist are refused, held or shadow-banned
+	blockMode, appErr := checkBlocklist(site, blocklist.Subject{
+		Email:       email,
+		IP:          origin.IP,
+		Fingerprint: origin.Fingerprint,
+		Text:        author + "\n" + req.Body,
+	})
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	// Render Markdown (if used) and allow safe HTML in body, using the site's policy
+	format := bodyFormat(req.Format, models.BodyFormatHTML)
+	body, bodySource, err := renderBody(site, format, req.Body)
+	if err != nil {
+		return nil, errors.BadRequest("Invalid comment body")
+	}
+
+	isVerified := user != nil && user.Email == email
+
+	// Returning authors with a good reputation skip moderation
+	var reputation *models.Reputation
+	if site != nil {
+		reputation, err = repository.GetReputation(site.ID, email)
 		if err != nil {
-			logger.Error(err, "Failed to create comment")
-			errors.ErrDatabaseError.Response(c)
-			return
+			logger.Error(err, "Failed to load reputation")
+			return nil, errors.ErrDatabaseError
 		}
+	}
 
-		// Return 200 OK with _id instead of id
-		c.JSON(http.StatusOK, CommentToResponse(comment))
-
-		// Send email notifications asynchronously (don't block the response)
-		go func() {
-			// Send verification email to guest users (not authenticated)
-			if user == nil {
-				// Find or create user for the commenter
-				commenterUser := &models.User{}
-				err := mgm.Coll(commenterUser).First(bson.M{"email": email}, commenterUser)
-				if err != nil {
-					// Create new user
-					commenterUser = models.NewUser(email)
-					commenterUser.Name = author
-					mgm.Coll(commenterUser).Create(commenterUser)
-				}
-
-				// Generate token for email verification
-				token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-					"id":    commenterUser.ID.Hex(),
-					"email": email,
-					"name":  author,
-					"exp":   time.Now().Add(constants.JWTExpirationHours * time.Hour).Unix(),
-				})
-				tokenString, err := token.SignedString([]byte(cfg.JWTSecret))
-				if err != nil {
-					logger.Error(err, "Failed to sign JWT token for verification email")
-					// Continue without verification email rather than failing
-					return
-				}
-
-				// Send verification email
-				mailService.SendEmailVerification(email, tokenString, comment.PageURL)
+	// Create comment
+	comment := &models.Comment{
+		PageURL:    parsedURL.String(),
+		PageID:     req.PageID,
+		Domain:     parsedURL.Hostname(),
+		Body:       body,
+		BodyFormat: format,
+		BodySource: bodySource,
+		Author:     author,
+		Email:      email,
+		Gravatar:   utils.GenerateGravatar(email),
+		ParentID:   req.ParentID,
+		IsVerified: isVerified,
+		Status:     moderation.InitialStatus(site, isVerified, reputation),
+		Secret:     utils.GenerateSecret(),
+		IP:         origin.IP,
+		UserAgent:  origin.UserAgent,
+		BodyHash:   spam.BodyHash(body),
+	}
+
+	// Bots fill in the honeypot, send the form too fast or replay it. Their
+	// comment is accepted like any other but kept as spam.
+	if reason := botTrapReason(cfg, site, comment.Domain, req); reason != "" {
+		comment.SpamScore = 1
+		comment.SpamReasons = []string{reason}
+		comment.Status = models.CommentStatusSpam
+	} else {
+		checkSpam(services.spam, site, comment)
+	}
+	applyBlockMode(comment, blockMode)
+
+	err = mgm.Coll(comment).Create(comment)
+	if err != nil {
+		logger.Error(err, "Failed to create comment")
+		return nil, errors.ErrDatabaseError
+	}
+
+	publishComment(events.CommentCreated, comment)
+
+	// Spam and shadow-banned comments are kept without telling anyone
+	if comment.Status == models.CommentStatusSpam || comment.Shadow {
+		return comment, nil
+	}
+
+	// Send email notifications asynchronously (don't block the response)
+	go func() {
+		// Send verification email to guest users (not authenticated)
+		if user == nil {
+			// Find or create user for the commenter
+			commenterUser := &models.User{}
+			err := mgm.Coll(commenterUser).First(bson.M{"email": email}, commenterUser)
+			if err != nil {
+				// Create new user
+				commenterUser = models.NewUser(email)
+				commenterUser.Name = author
+				mgm.Coll(commenterUser).Create(commenterUser)
 			}
 
-			// Send notification to site owner
-			site := &models.Site{}
-			err := mgm.Coll(site).First(bson.M{"domain": comment.Domain}, site)
-			if err == nil {
-				// Found the site, get the owner
-				siteOwner := &models.User{}
-				err := mgm.Coll(siteOwner).FindByID(site.UserID, siteOwner)
-				if err == nil && siteOwner.Email != email {
-					// Don't notify if the commenter is the site owner
-					mailService.SendCommentNotification(siteOwner.Email, mailer.CommentData{
-						Author:  author,
-						Date:    comment.CreatedAt.Format(constants.DateFormat),
-						PageURL: comment.PageURL,
-						Body:    body,
-					})
-				}
+			// Generate token for email verification
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+				"id":    commenterUser.ID.Hex(),
+				"email": email,
+				"name":  author,
+				"exp":   time.Now().Add(constants.JWTExpirationHours * time.Hour).Unix(),
+			})
+			tokenString, err := token.SignedString([]byte(cfg.JWTSecret))
+			if err != nil {
+				logger.Error(err, "Failed to sign JWT token for verification email")
+				// Continue without verification email rather than failing
+				return
 			}
-		}()
-	}
+
+			// Send verification email
+			mailService.SendEmailVerification(recipient(commenterUser, origin.Locale, siteLocale(site)), tokenString, comment.PageURL)
+		}
+
+		// Send notification to site owner
+		if site != nil {
+			// Post to the site's chats too
+			notifyChannels(cfg, services.chat, site, comment)
+
+			// Found the site, get the owner
+			siteOwner := &models.User{}
+			err := mgm.Coll(siteOwner).FindByID(site.UserID, siteOwner)
+			if err == nil && siteOwner.Email != email {
+				// Don't notify if the commenter is the site owner
+				mailService.SendCommentNotification(recipient(siteOwner, site.Locale), mailer.CommentData{
+					ID:      comment.ID.Hex(),
+					Author:  author,
+					Date:    comment.CreatedAt,
+					PageURL: comment.PageURL,
+					Body:    body,
+				}, moderationLinks(cfg, comment))
+			}
+		}
+
+		// Let the author of the parent comment know about the reply
+		notifyReply(cfg, mailService, comment)
+	}()
+
+	return comment, nil
 }
 
 // DeleteComment removes a comment
 // DELETE /api/comments/:id?secret=xxx
+// The comment is soft-deleted and purged later by the retention job
 func DeleteComment(c *gin.Context) {
 	commentID := c.Param("id")
 	secret := c.Query("secret")
@@ -207,69 +329,223 @@ func DeleteComment(c *gin.Context) {
 		return
 	}
 
-	// Build query
-	query := bson.M{"_id": objID}
-
-	// Check authorization
-	user := middleware.GetUser(c)
-	if secret != "" {
-		// Guest deletion with secret
-		query["secret"] = secret
-	} else if user != nil {
-		// Authenticated user deletion
-		query["email"] = utils.CleanEmail(user.Email)
-	} else {
+	// Build query (also checks authorization)
+	query, ok := authorQuery(c, objID, secret)
+	if !ok {
 		errors.ErrForbidden.Response(c)
 		return
 	}
 
-	// Delete comment
-	result, err := mgm.Coll(&models.Comment{}).DeleteOne(mgm.Ctx(), query)
-	if err != nil {
+	// Find the comment (already deleted comments count as not found)
+	query["deletedAt"] = nil
+	comment := &models.Comment{}
+	if err := mgm.Coll(comment).First(query, comment); err != nil {
+		if err == mongo.ErrNoDocuments {
+			errors.NotFound("Comment").Response(c)
+			return
+		}
 		errors.ErrDatabaseError.Response(c)
 		return
 	}
 
-	if result.DeletedCount == 0 {
-		errors.NotFound("Comment").Response(c)
+	// Soft delete - replies keep their parent as a tombstone
+	tombstone, err := repository.SoftDeleteComment(comment)
+	if err != nil {
+		logger.Error(err, "Failed to delete comment")
+		errors.ErrDatabaseError.Response(c)
 		return
 	}
 
 	c.JSON(http.StatusOK, NewDeletedResponse(commentID))
+
+	// Only approved comments were ever shown to live readers
+	if comment.CurrentStatus() == models.CommentStatusApproved {
+		publishCommentDeleted(comment, tombstone)
+	}
 }
 
-// ListCommentsBySite returns all comments for a site with pagination
-// GET /api/comments/sites/:siteId?limit=10&skip=0
-func ListCommentsBySite(c *gin.Context) {
-	siteID := c.Param("siteId")
+// EditComment changes the body of a comment
+// PATCH /api/comments/:id?secret=xxx
+// Authorized the same way as DeleteComment; the previous body is kept as a revision
+func EditComment(cfg *config.Config) gin.HandlerFunc {
+	spamFilter := spam.Default(cfg)
+
+	return func(c *gin.Context) {
+		commentID := c.Param("id")
+		secret := c.Query("secret")
+
+		objID, err := primitive.ObjectIDFromHex(commentID)
+		if err != nil {
+			errors.BadRequest("Invalid comment ID").Response(c)
+			return
+		}
+
+		var req validators.EditCommentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			errors.BadRequest("Invalid request body").Response(c)
+			return
+		}
+
+		query, ok := authorQuery(c, objID, secret)
+		if !ok {
+			errors.ErrForbidden.Response(c)
+			return
+		}
+		query["deletedAt"] = nil
+
+		comment := &models.Comment{}
+		if err := mgm.Coll(comment).First(query, comment); err != nil {
+			if err == mongo.ErrNoDocuments {
+				errors.NotFound("Comment").Response(c)
+				return
+			}
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
+
+		// Enforce the edit window
+		if cfg.CommentEditWindow > 0 && time.Since(comment.CreatedAt) > cfg.CommentEditWindow {
+			errors.New(errors.ErrCodeForbidden, "The time to edit this comment has passed", http.StatusForbidden).Response(c)
+			return
+		}
+
+		// Keep the comment's format unless the author switches it
+		format := bodyFormat(req.Format, comment.Format())
+		if format == comment.Format() && req.Body == comment.Source() {
+			// Nothing changed - don't create an empty revision
+			c.JSON(http.StatusOK, authorResponse(comment))
+			return
+		}
+
+		// The new body goes through the blocklist and spam checks like a new comment
+		site := siteForDomain(comment.Domain)
+		blockMode, appErr := checkBlocklist(site, blocklist.Subject{
+			Email:       comment.Email,
+			IP:          c.ClientIP(),
+			Fingerprint: c.GetHeader("fingerprint"),
+			Text:        comment.Author + "\n" + req.Body,
+		})
+		if appErr != nil {
+			appErr.Response(c)
+			return
+		}
+
+		body, bodySource, err := renderBody(site, format, req.Body)
+		if err != nil {
+			errors.BadRequest("Invalid comment body").Response(c)
+			return
+		}
+
+		// Keep the previous body
+		revision := &models.CommentRevision{
+			CommentID:  comment.ID.Hex(),
+			Domain:     comment.Domain,
+			Body:       comment.Body,
+			BodyFormat: comment.BodyFormat,
+			BodySource: comment.BodySource,
+		}
+		if err := mgm.Coll(revision).Create(revision); err != nil {
+			logger.Error(err, "Failed to save comment revision")
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
+
+		now := time.Now()
+		previous := comment.CurrentStatus()
+		wasApproved := previous == models.CommentStatusApproved
+		comment.Body = body
+		comment.BodyFormat = format
+		comment.BodySource = bodySource
+		comment.BodyHash = spam.BodyHash(body)
+		comment.EditedAt = &now
+
+		// An edit that no longer passes goes back to the queue or to spam
+		comment.Status = comment.CurrentStatus()
+		checkSpam(spamFilter, site, comment)
+		applyBlockMode(comment, blockMode)
+
+		// The moderator's decision was about the previous body
+		if comment.Counted && comment.Status != previous {
+			if err := moderation.RecordStatus(site, comment.Email, previous, ""); err != nil {
+				logger.Error(err, "Failed to update reputation")
+			}
+			comment.Counted = false
+		}
+
+		if err := mgm.Coll(comment).Update(comment); err != nil {
+			logger.Error(err, "Failed to update comment")
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
+
+		c.JSON(http.StatusOK, authorResponse(comment))
+		if wasApproved && comment.CurrentStatus() != models.CommentStatusApproved {
+			publishCommentDeleted(comment, false)
+			return
+		}
+		publishComment(events.CommentEdited, comment)
+	}
+}
+
+// ListCommentRevisions returns the previous bodies of a comment, newest first
+// GET /api/comments/:commentId/revisions
+// Only the owner of the comment's site can see them
+func ListCommentRevisions(c *gin.Context) {
+	commentID := c.Param("commentId")
 	user := middleware.GetUser(c)
 
-	// Convert string to ObjectID
-	objID, err := primitive.ObjectIDFromHex(siteID)
+	objID, err := primitive.ObjectIDFromHex(commentID)
 	if err != nil {
-		errors.NotFound("Site").Response(c)
+		errors.BadRequest("Invalid comment ID").Response(c)
 		return
 	}
 
-	// Find the site
-	site := &models.Site{}
-	err = mgm.Coll(site).FindByID(objID, site)
-	if err != nil {
-		errors.NotFound("Site").Response(c)
+	comment := &models.Comment{}
+	if err := mgm.Coll(comment).FindByID(objID, comment); err != nil {
+		errors.NotFound("Comment").Response(c)
+		return
+	}
+
+	// Check ownership of the site the comment was posted on
+	site := siteForDomain(comment.Domain)
+	if site == nil || site.UserID != user.ID {
+		errors.NotFound("Comment").Response(c)
 		return
 	}
 
-	// Check ownership (same as Node.js)
-	if site.UserID != user.ID {
-		errors.NotFound("Site").Response(c)
+	var revisions []models.CommentRevision
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	if err := mgm.Coll(&models.CommentRevision{}).SimpleFind(&revisions, bson.M{"commentId": commentID}, opts); err != nil {
+		errors.ErrDatabaseError.Response(c)
 		return
 	}
 
+	c.JSON(http.StatusOK, RevisionsToResponse(revisions))
+}
+
+// ListCommentsBySite returns all comments for a site with pagination
+// GET /api/comments/sites/:siteId?limit=10&skip=0&status=pending
+func ListCommentsBySite(c *gin.Context) {
+	site := findOwnedSite(c, c.Param("siteId"))
+	if site == nil {
+		return
+	}
+
+	// Optional moderation status filter (deleted comments are left out)
+	filter := bson.M{"domain": site.Domain, "deletedAt": nil}
+	if status := c.Query("status"); status != "" {
+		if !models.IsValidCommentStatus(status) {
+			errors.BadRequest("Invalid status").Response(c)
+			return
+		}
+		filter["status"] = statusFilter(status)
+	}
+
 	// Parse pagination parameters
 	limit, skip := repository.ParsePagination(c.Query("limit"), c.Query("skip"))
 
 	// Get total count
-	total, err := mgm.Coll(&models.Comment{}).CountDocuments(mgm.Ctx(), bson.M{"domain": site.Domain})
+	total, err := mgm.Coll(&models.Comment{}).CountDocuments(mgm.Ctx(), filter)
 	if err != nil {
 		errors.ErrDatabaseError.Response(c)
 		return
@@ -281,7 +557,7 @@ func ListCommentsBySite(c *gin.Context) {
 		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
 		SetSkip(int64(skip)).
 		SetLimit(int64(limit))
-	err = mgm.Coll(&models.Comment{}).SimpleFind(&comments, bson.M{"domain": site.Domain}, opts)
+	err = mgm.Coll(&models.Comment{}).SimpleFind(&comments, filter, opts)
 	if err != nil {
 		errors.ErrDatabaseError.Response(c)
 		return
@@ -294,3 +570,195 @@ func ListCommentsBySite(c *gin.Context) {
 
 	c.JSON(http.StatusOK, NewPaginatedCommentsResponse(comments, total, limit, skip))
 }
+
+// ModerateComments changes the moderation status of several comments at once
+// POST /api/comments/sites/:siteId/moderate
+func ModerateComments(cfg *config.Config) gin.HandlerFunc {
+	mailService := mailer.New(cfg)
+	spamFilter := spam.Default(cfg)
+
+	return func(c *gin.Context) {
+		site := findOwnedSite(c, c.Param("siteId"))
+		if site == nil {
+			return
+		}
+
+		var req validators.ModerateCommentsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			errors.BadRequest("commentIds and a valid status are required").Response(c)
+			return
+		}
+
+		ids := make([]primitive.ObjectID, 0, len(req.CommentIDs))
+		for _, id := range req.CommentIDs {
+			objID, err := primitive.ObjectIDFromHex(id)
+			if err != nil {
+				errors.BadRequest("Invalid comment ID").Response(c)
+				return
+			}
+			ids = append(ids, objID)
+		}
+
+		changed, err := moderation.SetStatus(site, ids, req.Status)
+		if err != nil {
+			logger.Error(err, "Failed to moderate comments")
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
+
+		c.JSON(http.StatusOK, ModerateCommentsResponse{Status: req.Status, Modified: len(changed)})
+
+		announceModeration(cfg, mailService, changed, req.Status)
+
+		// Approving and marking as spam train the site's spam classifier
+		go spamFilter.Learn(site, changed, req.Status)
+	}
+}
+
+// announceModeration tells readers about comments moved to a new status
+// changed holds the comments with their previous status, as returned by
+// moderation.SetStatus.
+func announceModeration(cfg *config.Config, mailService *mailer.Mailer, changed []models.Comment, status string) {
+	// Approved comments appear for live readers, unapproved ones disappear
+	for i := range changed {
+		wasApproved := changed[i].CurrentStatus() == models.CommentStatusApproved
+		changed[i].Status = status
+		if changed[i].DeletedAt != nil {
+			continue
+		}
+		if status == models.CommentStatusApproved {
+			publishComment(events.CommentCreated, &changed[i])
+		} else if wasApproved {
+			publishCommentDeleted(&changed[i], false)
+		}
+	}
+
+	// Replies that just got approved can now be announced to their parent authors
+	go func() {
+		for i := range changed {
+			notifyReply(cfg, mailService, &changed[i])
+		}
+	}()
+}
+
+// authorResponse is the response to the author of a new or edited comment
+// Spam looks like a comment waiting for moderation and shadow-banned comments
+// look published, so neither bots nor trolls can tell they were caught.
+func authorResponse(comment *models.Comment) CommentResponse {
+	response := CommentToResponse(comment)
+	switch {
+	case comment.Shadow:
+		response.Status = models.CommentStatusApproved
+	case response.Status == models.CommentStatusSpam:
+		response.Status = models.CommentStatusPending
+	}
+	return response
+}
+
+// checkSpam scores a comment of a site and makes its status stricter if it
+// looks like spam
+// Comments that were checked before keep the signals of their worst check.
+func checkSpam(spamFilter *spam.Pipeline, site *models.Site, comment *models.Comment) {
+	if !spam.Enabled(site) {
+		return
+	}
+	result := spamFilter.Check(site, comment)
+	if result.Score >= comment.SpamScore {
+		comment.SpamScore = result.Score
+		comment.SpamReasons = result.Reasons()
+	}
+	comment.Status = spam.Route(site, result.Score, comment.Status)
+}
+
+// applyBlockMode holds or shadow-bans a comment that matched a blocklist entry
+// that doesn't reject: shadow-banned comments are only shown to their author
+func applyBlockMode(comment *models.Comment, blockMode string) {
+	switch blockMode {
+	case models.BlockModeHold:
+		if comment.Status == models.CommentStatusApproved {
+			comment.Status = models.CommentStatusPending
+		}
+	case models.BlockModeShadow:
+		comment.Status = models.CommentStatusRejected
+		comment.Shadow = true
+	default:
+		return
+	}
+	if !slices.Contains(comment.SpamReasons, blocklistReason) {
+		comment.SpamReasons = append(comment.SpamReasons, blocklistReason)
+	}
+}
+
+// statusFilter matches comments with a moderation status
+func statusFilter(status string) any {
+	if status == models.CommentStatusApproved {
+		// Legacy comments without a status are approved
+		return bson.M{"$in": bson.A{status, nil}}
+	}
+	return status
+}
+
+// bodyFormat returns the requested body format, or fallback if none was given
+func bodyFormat(requested, fallback string) string {
+	if requested == "" {
+		return fallback
+	}
+	return requested
+}
+
+// renderBody turns the submitted body into sanitized HTML
+// Markdown is rendered to HTML first and its source is returned for storage
+// (bodySource is empty for HTML comments, where the body is the source).
+func renderBody(site *models.Site, format, source string) (body, bodySource string, err error) {
+	if format != models.BodyFormatMarkdown {
+		return sanitizeBody(site, source), "", nil
+	}
+
+	rendered, err := utils.RenderMarkdown(source)
+	if err != nil {
+		return "", "", err
+	}
+
+	return sanitizeBody(site, rendered), source, nil
+}
+
+// sanitizeBody sanitizes HTML with the site's own policy, or the default one
+// for sites without settings and unregistered domains
+func sanitizeBody(site *models.Site, input string) string {
+	if site == nil || site.Sanitizer == nil {
+		return utils.SanitizeComment(input)
+	}
+
+	return utils.SanitizeCommentWith(input, utils.SanitizeOptions{
+		TagSet:             site.Sanitizer.TagSet,
+		RewriteLinks:       site.Sanitizer.RewriteLinks,
+		AllowedLinkDomains: site.Sanitizer.AllowedLinkDomains,
+		DeniedLinkDomains:  site.Sanitizer.DeniedLinkDomains,
+		AllowImages:        site.Sanitizer.AllowImages,
+	})
+}
+
+// authorQuery builds a query matching a comment only if the requester wrote it:
+// guests prove it with the comment secret, authenticated users with their email
+func authorQuery(c *gin.Context, objID primitive.ObjectID, secret string) (bson.M, bool) {
+	query := bson.M{"_id": objID}
+
+	user := middleware.GetUser(c)
+	if secret != "" {
+		query["secret"] = secret
+	} else if user != nil {
+		query["email"] = utils.CleanEmail(user.Email)
+	} else {
+		return nil, false
+	}
+
+	return query, true
+}
+
+// viewerEmail returns the authenticated user's email, or "" for guests
+func viewerEmail(c *gin.Context) string {
+	if user := middleware.GetUser(c); user != nil {
+		return utils.CleanEmail(user.Email)
+	}
+	return ""
+}
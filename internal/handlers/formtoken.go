@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/services/spam"
+	"zoomment-server/internal/validators"
+)
+
+// GetFormToken signs the moment a comment form is shown
+// GET /api/comments/form-token?pageId=xxx
+// Widgets send the token back as formToken with the comment. Comments sent
+// too soon after, or with a token that was already used, are kept as spam.
+func GetFormToken(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		domain, err := ExtractDomainFromPageID(c.Query("pageId"))
+		if err != nil || domain == "" {
+			errors.BadRequest("Invalid pageId").Response(c)
+			return
+		}
+
+		now := time.Now()
+		token, err := spam.IssueFormToken(cfg.JWTSecret, domain, now)
+		if err != nil {
+			logger.Error(err, "Failed to sign form token")
+			errors.ErrInternalError.Response(c)
+			return
+		}
+
+		c.JSON(http.StatusOK, FormTokenResponse{Token: token, IssuedAt: now})
+	}
+}
+
+// ========================================
+// Helper Functions
+// ========================================
+
+// botTrapReason returns why a comment form looks like it was sent by a bot ("" = it doesn't)
+// Each form token counts once; a replayed form is caught here.
+func botTrapReason(cfg *config.Config, site *models.Site, domain string, req validators.AddCommentRequest) string {
+	check := spam.CheckForm(cfg.JWTSecret, domain, site, req.Honeypot, req.FormToken, time.Now())
+	if check.TokenID == "" {
+		return check.Reason
+	}
+
+	fresh, err := repository.UseToken("form:"+check.TokenID, check.ExpiresAt)
+	if err != nil {
+		// Not worth failing the comment for
+		logger.Error(err, "Failed to use form token")
+		return check.Reason
+	}
+	if !fresh {
+		return spam.SignalFormToken
+	}
+	return check.Reason
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/i18n"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/services/mailer"
+	"zoomment-server/internal/services/moderation"
+	"zoomment-server/internal/services/spam"
+	"zoomment-server/internal/tokens"
+	"zoomment-server/internal/utils"
+)
+
+// Quick moderation actions
+const (
+	moderationApprove = "approve"
+	moderationDelete  = "delete"
+	moderationSpam    = "spam"
+	moderationBan     = "ban"
+)
+
+// moderationLinkTTL is how long a quick moderation link works
+const moderationLinkTTL = 7 * 24 * time.Hour
+
+// moderationPage is shown when a moderator opens a quick moderation link
+// Like unsubscribe links, opening it only asks for confirmation; the action
+// needs a POST so link previews in chats and mail scanners can't moderate anything.
+var moderationPage = template.Must(template.New("moderate").Funcs(template.FuncMap{"t": i18n.T}).Parse(`<!doctype html>
+<html lang="{{.Locale}}">
+<head>
+	<meta name="viewport" content="width=device-width, initial-scale=1.0">
+	<title>{{.BrandName}}</title>
+	<style>
+		body { font-family: Helvetica, sans-serif; background: #f5f5f5; margin: 0; padding: 24px; }
+		.main { background: #fff; border: 1px solid #eaebed; border-radius: 14px; max-width: 400px; margin: 0 auto; padding: 24px 35px; }
+		blockquote { border-left: 3px solid #eaebed; color: #555; margin: 12px 0; padding: 0 12px; white-space: pre-line; }
+		button { background: #1677ff; border: 0; border-radius: 8px; color: #fff; font-size: 15px; margin: 4px 0; padding: 10px 20px; width: 100%; cursor: pointer; }
+		button.danger { background: #ff4d4f; }
+	</style>
+</head>
+<body>
+	<div class="main">
+		<h2>{{.BrandName}}</h2>
+		{{if .Error}}
+			<p>{{.Error}}</p>
+		{{else if .Done}}
+			{{if eq .Action "approve"}}
+				<p>{{t .Locale "The comment was approved."}}</p>
+			{{else if eq .Action "delete"}}
+				<p>{{t .Locale "The comment was deleted."}}</p>
+			{{else if eq .Action "spam"}}
+				<p>{{t .Locale "The comment was marked as spam."}}</p>
+			{{else}}
+				<p>{{t .Locale "%s can no longer comment on this site." .Author}}</p>
+			{{end}}
+		{{else}}
+			<p><strong>{{.Author}}</strong></p>
+			<blockquote>{{.Body}}</blockquote>
+			<form method="post">
+				{{if eq .Action "approve"}}
+					<button>{{t .Locale "Approve comment"}}</button>
+				{{else if eq .Action "delete"}}
+					<button class="danger">{{t .Locale "Delete comment"}}</button>
+				{{else if eq .Action "spam"}}
+					<button class="danger">{{t .Locale "Mark as spam"}}</button>
+				{{else}}
+					<button class="danger">{{t .Locale "Ban author"}}</button>
+				{{end}}
+			</form>
+		{{end}}
+	</div>
+</body>
+</html>
+`))
+
+// moderationPageData holds data for moderationPage
+type moderationPageData struct {
+	Locale    string
+	BrandName string
+	Action    string
+	Author    string
+	Body      string
+	Done      bool
+	Error     string
+}
+
+// moderationLink returns a signed link that moderates a comment
+// Anyone holding the link can use it once until it expires, so it is only
+// sent to the site owner's email and channels.
+func moderationLink(cfg *config.Config, comment *models.Comment, action string) (string, error) {
+	token, err := tokens.Sign(cfg.JWTSecret, tokens.PurposeModerate, jwt.MapClaims{
+		"jti":     utils.GenerateSecret(),
+		"comment": comment.ID.Hex(),
+		"action":  action,
+	}, moderationLinkTTL)
+	if err != nil {
+		logger.Error(err, "Failed to sign moderation token")
+		return "", err
+	}
+	return cfg.APIURL + "/api/moderate/" + url.PathEscape(token), nil
+}
+
+// moderationLinks returns the one-click actions for the owner notification email
+// Only comments waiting for moderation get an approve link.
+func moderationLinks(cfg *config.Config, comment *models.Comment) mailer.ModerationLinks {
+	var links mailer.ModerationLinks
+	if comment.CurrentStatus() == models.CommentStatusPending {
+		links.Approve, _ = moderationLink(cfg, comment, moderationApprove)
+	}
+	links.Delete, _ = moderationLink(cfg, comment, moderationDelete)
+	links.Spam, _ = moderationLink(cfg, comment, moderationSpam)
+	links.Ban, _ = moderationLink(cfg, comment, moderationBan)
+	return links
+}
+
+// ShowModeration asks to confirm a quick moderation action
+// GET /api/moderate/:token
+func ShowModeration(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, comment, claims := loadModeration(c, cfg)
+		if comment == nil {
+			return
+		}
+
+		used, err := repository.IsTokenUsed(tokens.String(claims, "jti"))
+		if err != nil {
+			logger.Error(err, "Failed to check moderation token")
+			data.Error = i18n.T(data.Locale, "Something went wrong, please try again later.")
+			renderPage(c, http.StatusInternalServerError, moderationPage, data)
+			return
+		}
+		if used {
+			data.Error = i18n.T(data.Locale, "This moderation link has already been used.")
+			renderPage(c, http.StatusGone, moderationPage, data)
+			return
+		}
+
+		data.Author = comment.Author
+		data.Body = utils.HTMLToText(comment.Body)
+		renderPage(c, http.StatusOK, moderationPage, data)
+	}
+}
+
+// ApplyModeration acts on the comment of a quick moderation link
+// POST /api/moderate/:token
+// approve, spam and ban change the moderation status (ban also blocks the
+// author's email on the site), delete removes the comment.
+func ApplyModeration(cfg *config.Config) gin.HandlerFunc {
+	mailService := mailer.New(cfg)
+	spamFilter := spam.Default(cfg)
+
+	return func(c *gin.Context) {
+		data, comment, claims := loadModeration(c, cfg)
+		if comment == nil {
+			return
+		}
+		data.Author = comment.Author
+
+		site := siteForDomain(comment.Domain)
+		if site == nil {
+			data.Error = i18n.T(data.Locale, "This comment no longer exists.")
+			renderPage(c, http.StatusNotFound, moderationPage, data)
+			return
+		}
+
+		// Spend the link first, so opening it twice at once acts only once
+		tokenID := tokens.String(claims, "jti")
+		expiresAt := time.Now().Add(moderationLinkTTL)
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			expiresAt = exp.Time
+		}
+		fresh, err := repository.UseToken(tokenID, expiresAt)
+		if err != nil {
+			logger.Error(err, "Failed to spend moderation token")
+			data.Error = i18n.T(data.Locale, "Something went wrong, please try again later.")
+			renderPage(c, http.StatusInternalServerError, moderationPage, data)
+			return
+		}
+		if !fresh {
+			data.Error = i18n.T(data.Locale, "This moderation link has already been used.")
+			renderPage(c, http.StatusGone, moderationPage, data)
+			return
+		}
+
+		if err := applyModeration(cfg, mailService, spamFilter, site, comment, data.Action); err != nil {
+			logger.Error(err, "Failed to moderate comment")
+			// Let the moderator try again
+			if err := repository.ReleaseToken(tokenID); err != nil {
+				logger.Error(err, "Failed to release moderation token")
+			}
+			data.Error = i18n.T(data.Locale, "Something went wrong, please try again later.")
+			renderPage(c, http.StatusInternalServerError, moderationPage, data)
+			return
+		}
+
+		data.Done = true
+		renderPage(c, http.StatusOK, moderationPage, data)
+	}
+}
+
+// applyModeration carries out a quick moderation action
+func applyModeration(cfg *config.Config, mailService *mailer.Mailer, spamFilter *spam.Pipeline, site *models.Site, comment *models.Comment, action string) error {
+	status := models.CommentStatusApproved
+	switch action {
+	case moderationDelete:
+		tombstone, err := repository.SoftDeleteComment(comment)
+		// Only approved comments were ever shown to live readers
+		if err == nil && comment.CurrentStatus() == models.CommentStatusApproved {
+			publishCommentDeleted(comment, tombstone)
+		}
+		return err
+	case moderationSpam:
+		status = models.CommentStatusSpam
+	case moderationBan:
+		if comment.Email != "" {
+			if err := repository.BlockEmail(site.ID, comment.Email, &comment.ID); err != nil {
+				return err
+			}
+		}
+		status = models.CommentStatusRejected
+	}
+
+	changed, err := moderation.SetStatus(site, []primitive.ObjectID{comment.ID}, status)
+	if err != nil {
+		return err
+	}
+	announceModeration(cfg, mailService, changed, status)
+	go spamFilter.Learn(site, changed, status)
+	return nil
+}
+
+// loadModeration checks a quick moderation link and loads its comment
+// Renders the error page and returns a nil comment when the link can't be used.
+func loadModeration(c *gin.Context, cfg *config.Config) (moderationPageData, *models.Comment, jwt.MapClaims) {
+	locale := i18n.First(i18n.FromContext(c))
+	data := moderationPageData{Locale: locale, BrandName: cfg.BrandName}
+
+	claims, err := tokens.Parse(cfg.JWTSecret, tokens.PurposeModerate, c.Param("token"))
+	data.Action = tokens.String(claims, "action")
+	if err != nil || tokens.String(claims, "jti") == "" || !validModerationAction(data.Action) {
+		data.Error = i18n.T(locale, "This moderation link is invalid or has expired.")
+		renderPage(c, http.StatusBadRequest, moderationPage, data)
+		return data, nil, nil
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(tokens.String(claims, "comment"))
+	comment := &models.Comment{}
+	if err != nil || mgm.Coll(comment).First(bson.M{"_id": commentID, "deletedAt": nil}, comment) != nil {
+		data.Error = i18n.T(locale, "This comment no longer exists.")
+		renderPage(c, http.StatusNotFound, moderationPage, data)
+		return data, nil, nil
+	}
+
+	return data, comment, claims
+}
+
+// validModerationAction reports whether action is a quick moderation action
+func validModerationAction(action string) bool {
+	switch action {
+	case moderationApprove, moderationDelete, moderationSpam, moderationBan:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/i18n"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/services/chat"
+	"zoomment-server/internal/services/mailer"
+	"zoomment-server/internal/tokens"
+)
+
+// notifyReply emails the author of the parent comment about a new reply
+// Skipped while the reply waits for moderation, when people reply to
+// themselves, and when the parent author unsubscribed.
+func notifyReply(cfg *config.Config, mailService *mailer.Mailer, reply *models.Comment) {
+	if reply.ParentID == nil || reply.CurrentStatus() != models.CommentStatusApproved {
+		return
+	}
+
+	parentID, err := primitive.ObjectIDFromHex(*reply.ParentID)
+	if err != nil {
+		return
+	}
+
+	parent := &models.Comment{}
+	if err := mgm.Coll(parent).FindByID(parentID, parent); err != nil {
+		return
+	}
+	if parent.DeletedAt != nil || parent.Email == "" || strings.EqualFold(parent.Email, reply.Email) {
+		return
+	}
+
+	// A thread is identified by its top-level comment
+	threadID := parent.ID.Hex()
+	if parent.ParentID != nil {
+		threadID = *parent.ParentID
+	}
+
+	subscribed, err := repository.IsSubscribed(parent.Email, reply.Domain, threadID)
+	if err != nil {
+		logger.Error(err, "Failed to check reply subscription")
+		return
+	}
+	if !subscribed {
+		return
+	}
+
+	// Unsubscribe links never expire
+	token, err := tokens.Sign(cfg.JWTSecret, tokens.PurposeUnsubscribe, jwt.MapClaims{
+		"email":  parent.Email,
+		"domain": reply.Domain,
+		"thread": threadID,
+	}, 0)
+	if err != nil {
+		logger.Error(err, "Failed to sign unsubscribe token")
+		return
+	}
+
+	unsubscribeURL := cfg.APIURL + "/api/subscriptions/unsubscribe?token=" + url.QueryEscape(token)
+
+	to := recipientForEmail(parent.Email, siteLocale(siteForDomain(reply.Domain)))
+	mailService.SendReplyNotification(to, mailer.CommentData{
+		ID:      reply.ID.Hex(),
+		Author:  reply.Author,
+		Date:    reply.CreatedAt,
+		PageURL: reply.PageURL,
+		Body:    reply.Body,
+	}, unsubscribeURL)
+}
+
+// notifyChannels posts a new comment to the active chats of its site
+// Comments waiting for approval get an approve link besides the delete link.
+func notifyChannels(cfg *config.Config, sender *chat.Sender, site *models.Site, comment *models.Comment) {
+	channels, err := repository.GetActiveChannels(site.ID)
+	if err != nil {
+		logger.Error(err, "Failed to load notification channels")
+		return
+	}
+	if len(channels) == 0 {
+		return
+	}
+
+	locale := i18n.First(site.Locale)
+	pending := comment.CurrentStatus() == models.CommentStatusPending
+	msg := chat.Message{
+		Locale: locale,
+		Domain: site.Domain,
+		Comment: mailer.CommentData{
+			ID:      comment.ID.Hex(),
+			Author:  comment.Author,
+			Date:    comment.CreatedAt,
+			PageURL: comment.PageURL,
+			Body:    comment.Body,
+		},
+		Pending: pending,
+	}
+
+	if pending {
+		if link, err := moderationLink(cfg, comment, moderationApprove); err == nil {
+			msg.Actions = append(msg.Actions, chat.Action{Label: i18n.T(locale, "Approve"), URL: link, Style: chat.StylePrimary})
+		}
+	}
+	if link, err := moderationLink(cfg, comment, moderationDelete); err == nil {
+		msg.Actions = append(msg.Actions, chat.Action{Label: i18n.T(locale, "Delete"), URL: link, Style: chat.StyleDanger})
+	}
+
+	for i := range channels {
+		if err := sender.Send(&channels[i], msg); err != nil {
+			logger.Error(err, "Failed to post comment to "+channels[i].Type+" channel")
+		}
+	}
+}
+
+// recipient builds an email recipient from a user's preferences
+// locales are fallbacks, most specific first, for users without a preference.
+func recipient(user *models.User, locales ...string) mailer.Recipient {
+	return mailer.Recipient{
+		Email:    user.Email,
+		Locale:   i18n.First(append([]string{user.Locale}, locales...)...),
+		Timezone: user.Timezone,
+	}
+}
+
+// recipientForEmail is recipient for an email address that may have an account
+func recipientForEmail(email string, locales ...string) mailer.Recipient {
+	user := &models.User{}
+	if err := mgm.Coll(user).First(bson.M{"email": email}, user); err != nil {
+		user = &models.User{Email: email}
+	}
+	return recipient(user, locales...)
+}
+
+// siteLocale returns the default locale of a site ("" for unregistered sites)
+func siteLocale(site *models.Site) string {
+	if site == nil {
+		return ""
+	}
+	return site.Locale
+}
@@ -8,8 +8,11 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
+	"zoomment-server/internal/config"
 	"zoomment-server/internal/constants"
 	"zoomment-server/internal/errors"
+	"zoomment-server/internal/events"
+	"zoomment-server/internal/middleware"
 	"zoomment-server/internal/models"
 	"zoomment-server/internal/validators"
 )
@@ -55,46 +58,77 @@ func ListReactions(c *gin.Context) {
 
 // AddReaction adds or toggles a reaction
 // POST /api/reactions
-func AddReaction(c *gin.Context) {
-	var req validators.AddReactionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		errors.BadRequest("Invalid request").Response(c)
-		return
-	}
+func AddReaction(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validators.AddReactionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			errors.BadRequest("Invalid request").Response(c)
+			return
+		}
 
-	fingerprint := c.GetHeader("fingerprint")
-	if fingerprint == "" {
-		// Node.js uses 500 and sends plain text - keep for compatibility
-		c.String(http.StatusInternalServerError, "Fingerprint required for reacting.")
-		return
-	}
+		fingerprint := c.GetHeader("fingerprint")
+		if fingerprint == "" {
+			// Node.js uses 500 and sends plain text - keep for compatibility
+			c.String(http.StatusInternalServerError, "Fingerprint required for reacting.")
+			return
+		}
 
-	// Limit reaction length
-	reaction := req.Reaction
-	if len(reaction) > constants.MaxReactionLength {
-		reaction = reaction[:constants.MaxReactionLength]
-	}
+		// Limit reaction length
+		reaction := req.Reaction
+		if len(reaction) > constants.MaxReactionLength {
+			reaction = reaction[:constants.MaxReactionLength]
+		}
 
-	domain, err := ExtractDomainFromPageID(req.PageID)
-	if err != nil {
-		errors.BadRequest("Invalid pageId").Response(c)
-		return
-	}
+		domain, err := ExtractDomainFromPageID(req.PageID)
+		if err != nil {
+			errors.BadRequest("Invalid pageId").Response(c)
+			return
+		}
 
-	// Process reaction (create/update/delete)
-	if err := processReaction(req.PageID, fingerprint, domain, reaction); err != nil {
-		errors.ErrDatabaseError.Response(c)
-		return
-	}
+		// Sites may make guests prove some work first
+		site := siteForDomain(domain)
+		if appErr := checkChallenge(cfg, site, middleware.GetUser(c), req.PoW); appErr != nil {
+			appErr.Response(c)
+			return
+		}
 
-	// Return updated reactions
-	response, err := getPageReactions(req.PageID, fingerprint)
-	if err != nil {
-		errors.ErrDatabaseError.Response(c)
-		return
-	}
+		// Reactions of held and shadow-banned readers are dropped without a word
+		blockMode, appErr := checkBlocklist(site, blocklistSubject(commentOriginOf(c)))
+		if appErr != nil {
+			appErr.Response(c)
+			return
+		}
+		if blockMode != "" {
+			response, err := getPageReactions(req.PageID, fingerprint)
+			if err != nil {
+				errors.ErrDatabaseError.Response(c)
+				return
+			}
+			c.JSON(http.StatusOK, response)
+			return
+		}
 
-	c.JSON(http.StatusOK, response)
+		// Process reaction (create/update/delete)
+		if err := processReaction(req.PageID, fingerprint, domain, reaction); err != nil {
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
+
+		// Return updated reactions
+		response, err := getPageReactions(req.PageID, fingerprint)
+		if err != nil {
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+
+		changed := events.ReactionChangedEvent{Aggregation: response.Aggregation}
+		if response.UserReaction != nil {
+			changed.Added = response.UserReaction.Reaction
+		}
+		publish(domain, req.PageID, events.ReactionChanged, changed)
+	}
 }
 
 // ========================================
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/middleware"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/services/mailer"
+	"zoomment-server/internal/services/moderation"
+	"zoomment-server/internal/services/spam"
+	"zoomment-server/internal/validators"
+)
+
+const (
+	// defaultReportThreshold is how many readers must report a comment before
+	// it's hidden, when a site doesn't set its own
+	defaultReportThreshold = 3
+
+	// maxListedReports limits how many reports the report list loads
+	maxListedReports = 500
+
+	// reportsDismiss closes the reports of a comment and keeps it as it is
+	reportsDismiss = "dismiss"
+)
+
+// ReportComment lets a reader report a comment
+// POST /api/comments/:id/report
+// Readers are told apart by account or fingerprint, and reporting a comment
+// twice changes nothing. Once enough readers reported it, the comment is
+// hidden in the moderation queue. The site owner hears about the first
+// report and about hiding.
+func ReportComment(cfg *config.Config) gin.HandlerFunc {
+	mailService := mailer.New(cfg)
+
+	return func(c *gin.Context) {
+		commentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			errors.BadRequest("Invalid comment ID").Response(c)
+			return
+		}
+
+		var req validators.ReportCommentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			errors.BadRequest("Invalid report").Response(c)
+			return
+		}
+
+		reporter := reporterOf(c)
+		if reporter == "" {
+			errors.BadRequest("Fingerprint required for reporting").Response(c)
+			return
+		}
+
+		// Only comments readers can see can be reported, on registered sites
+		comment := &models.Comment{}
+		if err := mgm.Coll(comment).FindByID(commentID, comment); err != nil ||
+			comment.DeletedAt != nil || comment.CurrentStatus() != models.CommentStatusApproved {
+			errors.NotFound("Comment").Response(c)
+			return
+		}
+		site := siteForDomain(comment.Domain)
+		if site == nil {
+			errors.NotFound("Comment").Response(c)
+			return
+		}
+
+		added, err := repository.AddReport(&models.Report{
+			CommentID: comment.ID,
+			SiteID:    site.ID,
+			Reporter:  reporter,
+			Reason:    req.Reason,
+			Details:   strings.TrimSpace(req.Details),
+		})
+		if err != nil {
+			logger.Error(err, "Failed to store report")
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
+		if !added {
+			c.JSON(http.StatusOK, ReportCommentResponse{Reported: true})
+			return
+		}
+
+		count, err := repository.CountOpenReports(comment.ID)
+		if err != nil {
+			logger.Error(err, "Failed to count reports")
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
+
+		hidden := false
+		if count >= reportThreshold(site) {
+			changed, err := moderation.SetStatus(site, []primitive.ObjectID{comment.ID}, models.CommentStatusPending)
+			if err != nil {
+				logger.Error(err, "Failed to hide reported comment")
+				errors.ErrDatabaseError.Response(c)
+				return
+			}
+			announceModeration(cfg, mailService, changed, models.CommentStatusPending)
+			hidden = len(changed) > 0
+			if hidden {
+				comment.Status = models.CommentStatusPending
+			}
+		}
+
+		c.JSON(http.StatusOK, ReportCommentResponse{Reported: true})
+
+		if count == 1 || hidden {
+			go notifyReport(cfg, mailService, site, comment, mailer.ReportData{
+				Reason:  req.Reason,
+				Details: strings.TrimSpace(req.Details),
+				Count:   count,
+				Hidden:  hidden,
+			})
+		}
+	}
+}
+
+// ListReports returns a site's reported comments with their reports, most
+// recently reported first
+// GET /api/sites/:id/reports?status=open|resolved
+func ListReports(c *gin.Context) {
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return
+	}
+
+	status := c.DefaultQuery("status", "open")
+	if status != "open" && status != "resolved" {
+		errors.BadRequest("Invalid status").Response(c)
+		return
+	}
+
+	reports, err := repository.GetSiteReports(site.ID, status == "resolved", maxListedReports)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	// Group the reports by comment, keeping the order of the newest report
+	var commentIDs []primitive.ObjectID
+	byComment := make(map[primitive.ObjectID][]ReportResponse)
+	for i := range reports {
+		id := reports[i].CommentID
+		if _, ok := byComment[id]; !ok {
+			commentIDs = append(commentIDs, id)
+		}
+		byComment[id] = append(byComment[id], ReportToResponse(&reports[i]))
+	}
+
+	var comments []models.Comment
+	if len(commentIDs) > 0 {
+		err = mgm.Coll(&models.Comment{}).SimpleFind(&comments, bson.M{
+			"_id":    bson.M{"$in": commentIDs},
+			"domain": site.Domain,
+		})
+		if err != nil {
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
+	}
+	commentsByID := make(map[primitive.ObjectID]*models.Comment, len(comments))
+	for i := range comments {
+		commentsByID[comments[i].ID] = &comments[i]
+	}
+
+	// Comments purged since they were reported are left out
+	response := make([]ReportedCommentResponse, 0, len(commentIDs))
+	for _, id := range commentIDs {
+		comment, ok := commentsByID[id]
+		if !ok {
+			continue
+		}
+		response = append(response, ReportedCommentResponse{
+			Comment: CommentToResponse(comment),
+			Reports: byComment[id],
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ResolveReports deals with a reported comment and closes its open reports
+// POST /api/sites/:id/reports/resolve
+// The action is one of the quick moderation actions (approve, delete, spam,
+// ban), or dismiss to keep the comment as it is.
+func ResolveReports(cfg *config.Config) gin.HandlerFunc {
+	mailService := mailer.New(cfg)
+	spamFilter := spam.Default(cfg)
+
+	return func(c *gin.Context) {
+		site := findOwnedSite(c, c.Param("id"))
+		if site == nil {
+			return
+		}
+
+		var req validators.ResolveReportsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			errors.BadRequest("Invalid report").Response(c)
+			return
+		}
+
+		commentID, err := primitive.ObjectIDFromHex(req.CommentID)
+		if err != nil {
+			errors.BadRequest("Invalid comment ID").Response(c)
+			return
+		}
+		comment := &models.Comment{}
+		if err := mgm.Coll(comment).First(bson.M{"_id": commentID, "domain": site.Domain}, comment); err != nil {
+			errors.NotFound("Comment").Response(c)
+			return
+		}
+
+		if req.Action != reportsDismiss {
+			if err := applyModeration(cfg, mailService, spamFilter, site, comment, req.Action); err != nil {
+				logger.Error(err, "Failed to moderate reported comment")
+				errors.ErrDatabaseError.Response(c)
+				return
+			}
+		}
+
+		resolved, err := repository.ResolveReports(comment.ID, req.Action)
+		if err != nil {
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
+
+		// Reports count against the author's reputation once a moderator
+		// upholds them, so readers alone can't sink it
+		if reportsUpheld(req.Action) && resolved > 0 && comment.Email != "" {
+			if err := repository.UpdateReputation(site.ID, comment.Email, 0, 0, int(resolved)); err != nil {
+				logger.Error(err, "Failed to update reputation")
+			}
+		}
+
+		c.JSON(http.StatusOK, ResolveReportsResponse{Action: req.Action, Resolved: resolved})
+	}
+}
+
+// ========================================
+// Helper Functions
+// ========================================
+
+// reporterOf identifies the reader behind a report ("" if they can't be told apart)
+func reporterOf(c *gin.Context) string {
+	if user := middleware.GetUser(c); user != nil {
+		return "user:" + user.ID.Hex()
+	}
+	if fingerprint := c.GetHeader("fingerprint"); fingerprint != "" {
+		return "fingerprint:" + fingerprint
+	}
+	return ""
+}
+
+// reportsUpheld reports whether resolving reports with action agrees with them
+func reportsUpheld(action string) bool {
+	switch action {
+	case moderationDelete, moderationSpam, moderationBan:
+		return true
+	}
+	return false
+}
+
+// reportThreshold returns how many reports hide a comment of a site
+func reportThreshold(site *models.Site) int64 {
+	if site.Reports != nil && site.Reports.HideThreshold > 0 {
+		return int64(site.Reports.HideThreshold)
+	}
+	return defaultReportThreshold
+}
+
+// notifyReport emails the site owner about a reported comment
+func notifyReport(cfg *config.Config, mailService *mailer.Mailer, site *models.Site, comment *models.Comment, report mailer.ReportData) {
+	owner := &models.User{}
+	if err := mgm.Coll(owner).FindByID(site.UserID, owner); err != nil {
+		logger.Error(err, "Failed to find site owner for report notification")
+		return
+	}
+
+	mailService.SendReportNotification(recipient(owner, site.Locale), mailer.CommentData{
+		ID:      comment.ID.Hex(),
+		Author:  comment.Author,
+		Date:    comment.CreatedAt,
+		PageURL: comment.PageURL,
+		Body:    comment.Body,
+	}, report, moderationLinks(cfg, comment))
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/validators"
+)
+
+// ListCommenters returns the reputations of a site's commenters, most
+// recently active first
+// GET /api/sites/:id/commenters?email=&limit=&skip=
+func ListCommenters(c *gin.Context) {
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return
+	}
+
+	limit, skip := repository.ParsePagination(c.Query("limit"), c.Query("skip"))
+	reputations, total, err := repository.GetReputations(site.ID, c.Query("email"), limit, skip)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	commenters := make([]CommenterResponse, 0, len(reputations))
+	for i := range reputations {
+		commenters = append(commenters, CommenterToResponse(site, &reputations[i]))
+	}
+	c.JSON(http.StatusOK, PaginatedCommentersResponse{
+		Commenters: commenters,
+		Total:      total,
+		Limit:      limit,
+		Skip:       skip,
+		HasMore:    int64(skip+len(reputations)) < total,
+	})
+}
+
+// PinCommenter marks a commenter as trusted or untrusted, whatever their
+// reputation says
+// POST /api/sites/:id/commenters/pin
+// An empty pin goes back to the reputation.
+func PinCommenter(c *gin.Context) {
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return
+	}
+
+	var req validators.PinCommenterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.BadRequest("Invalid commenter").Response(c)
+		return
+	}
+
+	reputation, err := repository.PinReputation(site.ID, req.Email, req.Pin)
+	if err != nil {
+		logger.Error(err, "Failed to pin commenter")
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, CommenterToResponse(site, reputation))
+}
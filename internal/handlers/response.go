@@ -4,7 +4,10 @@ import (
 	"net/url"
 	"time"
 
+	"zoomment-server/internal/i18n"
 	"zoomment-server/internal/models"
+	"zoomment-server/internal/services/moderation"
+	"zoomment-server/internal/utils"
 )
 
 // ========================================
@@ -19,24 +22,61 @@ type SiteResponse struct {
 	Verified  bool      `json:"verified"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Moderation settings
+	ModerationMode string                    `json:"moderationMode"`
+	Sanitizer      *models.SanitizerSettings `json:"sanitizer"`
+
+	// Default language of emails about the site
+	Locale string `json:"locale"`
+
+	// Spam check settings (null = defaults)
+	Spam *models.SpamSettings `json:"spam"`
+
+	// Proof-of-work settings (null = off)
+	Challenge *models.ChallengeSettings `json:"challenge"`
+
+	// Reader report settings (null = defaults)
+	Reports *models.ReportSettings `json:"reports"`
+
+	// Commenter reputation settings (null = off)
+	Trust *models.TrustSettings `json:"trust"`
 }
 
 // CommentResponse is the JSON response format for newly created comments
 type CommentResponse struct {
+	ID         string     `json:"_id"`
+	ParentID   *string    `json:"parentId"`
+	Author     string     `json:"author"`
+	Email      string     `json:"email"`
+	Gravatar   string     `json:"gravatar"`
+	Body       string     `json:"body"`
+	BodyFormat string     `json:"bodyFormat"`
+	BodySource string     `json:"bodySource,omitempty"`
+	Domain     string     `json:"domain"`
+	PageURL    string     `json:"pageUrl"`
+	PageID     string     `json:"pageId"`
+	IsVerified bool       `json:"isVerified"`
+	Status     string     `json:"status"`
+	Secret     string     `json:"secret"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	EditedAt   *time.Time `json:"editedAt,omitempty"`
+	IsOwn      bool       `json:"isOwn"`
+
+	// Only shown to moderators
+	SpamScore   float64  `json:"spamScore,omitempty"`
+	SpamReasons []string `json:"spamReasons,omitempty"`
+}
+
+// RevisionResponse is the JSON response format for a previous comment body
+type RevisionResponse struct {
 	ID         string    `json:"_id"`
-	ParentID   *string   `json:"parentId"`
-	Author     string    `json:"author"`
-	Email      string    `json:"email"`
-	Gravatar   string    `json:"gravatar"`
+	CommentID  string    `json:"commentId"`
 	Body       string    `json:"body"`
-	Domain     string    `json:"domain"`
-	PageURL    string    `json:"pageUrl"`
-	PageID     string    `json:"pageId"`
-	IsVerified bool      `json:"isVerified"`
-	Secret     string    `json:"secret"`
+	BodyFormat string    `json:"bodyFormat"`
+	BodySource string    `json:"bodySource,omitempty"`
 	CreatedAt  time.Time `json:"createdAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
-	IsOwn      bool      `json:"isOwn"`
 }
 
 // DeletedResponse is the JSON response for deleted resources
@@ -71,11 +111,182 @@ type PaginatedCommentsResponse struct {
 	HasMore  bool              `json:"hasMore"`
 }
 
+// CommentSearchResultResponse is a comment found by a search
+// Snippet and Author are HTML: escaped text with the matching words in <mark>.
+type CommentSearchResultResponse struct {
+	Comment CommentResponse `json:"comment"`
+	Snippet string          `json:"snippet"`
+	Author  string          `json:"author"`
+}
+
+// CommentSearchResponse is the JSON response for a comment search
+type CommentSearchResponse struct {
+	Results []CommentSearchResultResponse `json:"results"`
+	Total   int64                         `json:"total"`
+	Limit   int                           `json:"limit"`
+	Skip    int                           `json:"skip"`
+	HasMore bool                          `json:"hasMore"`
+}
+
+// ModerateCommentsResponse is the JSON response for bulk moderation
+type ModerateCommentsResponse struct {
+	Status   string `json:"status"`
+	Modified int    `json:"modified"`
+}
+
+// ReportCommentResponse is the JSON response to a reader's report
+// It's the same whether or not the reader reported the comment before.
+type ReportCommentResponse struct {
+	Reported bool `json:"reported"`
+}
+
+// ReportResponse is the JSON response format for a reader's report
+type ReportResponse struct {
+	ID         string     `json:"_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	Resolution string     `json:"resolution,omitempty"`
+}
+
+// ReportedCommentResponse is a reported comment with its reports, newest first
+type ReportedCommentResponse struct {
+	Comment CommentResponse  `json:"comment"`
+	Reports []ReportResponse `json:"reports"`
+}
+
+// ResolveReportsResponse is the JSON response for resolving a comment's reports
+type ResolveReportsResponse struct {
+	Action   string `json:"action"`
+	Resolved int64  `json:"resolved"`
+}
+
+// OutboxMessageResponse is the JSON response format for a queued email
+type OutboxMessageResponse struct {
+	ID            string    `json:"_id"`
+	To            string    `json:"to"`
+	Subject       string    `json:"subject"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"lastError"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// OutboxResponse is the JSON response for the outbox overview
+type OutboxResponse struct {
+	Pending  int64                   `json:"pending"`
+	Sending  int64                   `json:"sending"`
+	Sent     int64                   `json:"sent"`
+	Dead     int64                   `json:"dead"`
+	Failures []OutboxMessageResponse `json:"failures"`
+}
+
+// WebhookResponse is the JSON response format for a webhook
+// Only the site owner sees it, so the signing secret is included.
+type WebhookResponse struct {
+	ID        string    `json:"_id"`
+	SiteID    string    `json:"siteId"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// WebhookDeliveryResponse is the JSON response format for a webhook delivery
+type WebhookDeliveryResponse struct {
+	ID            string                  `json:"_id"`
+	EventID       string                  `json:"eventId"`
+	EventType     string                  `json:"eventType"`
+	Payload       string                  `json:"payload"`
+	RedeliveryOf  *string                 `json:"redeliveryOf,omitempty"`
+	Status        string                  `json:"status"`
+	Attempts      int                     `json:"attempts"`
+	NextAttemptAt time.Time               `json:"nextAttemptAt"`
+	DeliveredAt   *time.Time              `json:"deliveredAt,omitempty"`
+	Log           []models.WebhookAttempt `json:"log"`
+	CreatedAt     time.Time               `json:"createdAt"`
+}
+
+// ChannelResponse is the JSON response format for a notification channel
+// Only the site owner sees it, so the connection secrets are included.
+type ChannelResponse struct {
+	ID            string    `json:"_id"`
+	SiteID        string    `json:"siteId"`
+	Type          string    `json:"type"`
+	Active        bool      `json:"active"`
+	WebhookURL    string    `json:"webhookUrl,omitempty"`
+	BotToken      string    `json:"botToken,omitempty"`
+	ChatID        string    `json:"chatId,omitempty"`
+	HomeserverURL string    `json:"homeserverUrl,omitempty"`
+	AccessToken   string    `json:"accessToken,omitempty"`
+	RoomID        string    `json:"roomId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// BlocklistEntryResponse is the JSON response format for a blocklist entry
+type BlocklistEntryResponse struct {
+	ID        string     `json:"_id"`
+	SiteID    *string    `json:"siteId"` // null for global entries
+	Type      string     `json:"type"`
+	Value     string     `json:"value"`
+	Mode      string     `json:"mode"`
+	Note      string     `json:"note,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	CommentID *string    `json:"commentId,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// CommenterResponse is the JSON response format for a commenter's reputation
+type CommenterResponse struct {
+	Email     string    `json:"email"`
+	Approved  int       `json:"approved"`
+	Rejected  int       `json:"rejected"`
+	Reports   int       `json:"reports"`
+	Score     int       `json:"score"`
+	Pin       string    `json:"pin,omitempty"`
+	Trusted   bool      `json:"trusted"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PaginatedCommentersResponse is the JSON response for a site's commenters
+type PaginatedCommentersResponse struct {
+	Commenters []CommenterResponse `json:"commenters"`
+	Total      int64               `json:"total"`
+	Limit      int                 `json:"limit"`
+	Skip       int                 `json:"skip"`
+	HasMore    bool                `json:"hasMore"`
+}
+
+// ChallengeResponse is a proof-of-work challenge
+// Required is false (and the rest empty) when the site doesn't ask for one.
+type ChallengeResponse struct {
+	Required   bool       `json:"required"`
+	Challenge  string     `json:"challenge,omitempty"`
+	Algorithm  string     `json:"algorithm,omitempty"`
+	Difficulty int        `json:"difficulty,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+// FormTokenResponse is a signed comment form render time
+type FormTokenResponse struct {
+	Token    string    `json:"token"`
+	IssuedAt time.Time `json:"issuedAt"`
+}
+
 // UserProfileResponse is the JSON response for user profile
 type UserProfileResponse struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Locale   string `json:"locale"`
+	Timezone string `json:"timezone"`
 }
 
 // MessageResponse is a generic message response
@@ -96,6 +307,35 @@ func SiteToResponse(site *models.Site) SiteResponse {
 		Verified:  site.Verified,
 		CreatedAt: site.CreatedAt,
 		UpdatedAt: site.UpdatedAt,
+
+		ModerationMode: siteModerationMode(site),
+		Sanitizer:      site.Sanitizer,
+
+		Locale: i18n.First(site.Locale),
+
+		Spam:      site.Spam,
+		Challenge: site.Challenge,
+		Reports:   site.Reports,
+		Trust:     site.Trust,
+	}
+}
+
+// siteModerationMode returns the site's moderation mode with the default applied
+func siteModerationMode(site *models.Site) string {
+	if site.ModerationMode == "" {
+		return models.ModerationNone
+	}
+	return site.ModerationMode
+}
+
+// UserToProfileResponse converts a User model to the profile response format
+func UserToProfileResponse(user *models.User) UserProfileResponse {
+	return UserProfileResponse{
+		ID:       user.ID.Hex(),
+		Name:     user.Name,
+		Email:    user.Email,
+		Locale:   user.Locale,
+		Timezone: user.Timezone,
 	}
 }
 
@@ -117,13 +357,17 @@ func CommentToResponse(comment *models.Comment) CommentResponse {
 		Email:      comment.Email,
 		Gravatar:   comment.Gravatar,
 		Body:       comment.Body,
+		BodyFormat: comment.Format(),
+		BodySource: comment.BodySource,
 		Domain:     comment.Domain,
 		PageURL:    comment.PageURL,
 		PageID:     comment.PageID,
 		IsVerified: comment.IsVerified,
+		Status:     comment.CurrentStatus(),
 		Secret:     comment.Secret,
 		CreatedAt:  comment.CreatedAt,
 		UpdatedAt:  comment.UpdatedAt,
+		EditedAt:   comment.EditedAt,
 		IsOwn:      true, // New comments are always "own"
 	}
 }
@@ -137,6 +381,151 @@ func CommentsToResponse(comments []models.Comment) []CommentResponse {
 	return result
 }
 
+// RevisionsToResponse converts a slice of revisions to response format
+func RevisionsToResponse(revisions []models.CommentRevision) []RevisionResponse {
+	result := make([]RevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		format := revision.BodyFormat
+		if format == "" {
+			format = models.BodyFormatHTML
+		}
+		result = append(result, RevisionResponse{
+			ID:         revision.ID.Hex(),
+			CommentID:  revision.CommentID,
+			Body:       revision.Body,
+			BodyFormat: format,
+			BodySource: revision.BodySource,
+			CreatedAt:  revision.CreatedAt,
+		})
+	}
+	return result
+}
+
+// OutboxMessagesToResponse converts a slice of outbox messages to response format
+// Email bodies are left out; they may contain sign-in links.
+func OutboxMessagesToResponse(messages []models.OutboxMessage) []OutboxMessageResponse {
+	result := make([]OutboxMessageResponse, 0, len(messages))
+	for _, message := range messages {
+		result = append(result, OutboxMessageResponse{
+			ID:            message.ID.Hex(),
+			To:            message.To,
+			Subject:       message.Subject,
+			Status:        message.Status,
+			Attempts:      message.Attempts,
+			LastError:     message.LastError,
+			NextAttemptAt: message.NextAttemptAt,
+			CreatedAt:     message.CreatedAt,
+			UpdatedAt:     message.UpdatedAt,
+		})
+	}
+	return result
+}
+
+// WebhookToResponse converts a Webhook model to response format
+func WebhookToResponse(webhook *models.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:        webhook.ID.Hex(),
+		SiteID:    webhook.SiteID.Hex(),
+		URL:       webhook.URL,
+		Secret:    webhook.Secret,
+		Events:    webhook.Events,
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
+
+// WebhookDeliveryToResponse converts a WebhookDelivery model to response format
+func WebhookDeliveryToResponse(delivery *models.WebhookDelivery) WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		ID:            delivery.ID.Hex(),
+		EventID:       delivery.EventID,
+		EventType:     delivery.EventType,
+		Payload:       delivery.Payload,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		DeliveredAt:   delivery.DeliveredAt,
+		Log:           delivery.Log,
+		CreatedAt:     delivery.CreatedAt,
+	}
+	if delivery.RedeliveryOf != nil {
+		original := delivery.RedeliveryOf.Hex()
+		response.RedeliveryOf = &original
+	}
+	if response.Log == nil {
+		response.Log = []models.WebhookAttempt{}
+	}
+	return response
+}
+
+// CommenterToResponse converts a Reputation model to response format
+func CommenterToResponse(site *models.Site, reputation *models.Reputation) CommenterResponse {
+	return CommenterResponse{
+		Email:     reputation.Email,
+		Approved:  reputation.Approved,
+		Rejected:  reputation.Rejected,
+		Reports:   reputation.Reports,
+		Score:     reputation.Score(),
+		Pin:       reputation.Pin,
+		Trusted:   moderation.Trusted(site, reputation),
+		UpdatedAt: reputation.UpdatedAt,
+	}
+}
+
+// BlocklistEntryToResponse converts a BlocklistEntry model to response format
+func BlocklistEntryToResponse(entry *models.BlocklistEntry) BlocklistEntryResponse {
+	response := BlocklistEntryResponse{
+		ID:        entry.ID.Hex(),
+		Type:      entry.Type,
+		Value:     entry.Value,
+		Mode:      entry.CurrentMode(),
+		Note:      entry.Note,
+		ExpiresAt: entry.ExpiresAt,
+		CreatedAt: entry.CreatedAt,
+		UpdatedAt: entry.UpdatedAt,
+	}
+	if entry.SiteID != nil {
+		siteID := entry.SiteID.Hex()
+		response.SiteID = &siteID
+	}
+	if entry.CommentID != nil {
+		commentID := entry.CommentID.Hex()
+		response.CommentID = &commentID
+	}
+	return response
+}
+
+// ReportToResponse converts a Report model to response format
+func ReportToResponse(report *models.Report) ReportResponse {
+	return ReportResponse{
+		ID:         report.ID.Hex(),
+		Reason:     report.Reason,
+		Details:    report.Details,
+		CreatedAt:  report.CreatedAt,
+		ResolvedAt: report.ResolvedAt,
+		Resolution: report.Resolution,
+	}
+}
+
+// ChannelToResponse converts a NotificationChannel model to response format
+func ChannelToResponse(channel *models.NotificationChannel) ChannelResponse {
+	return ChannelResponse{
+		ID:            channel.ID.Hex(),
+		SiteID:        channel.SiteID.Hex(),
+		Type:          channel.Type,
+		Active:        channel.Active,
+		WebhookURL:    channel.WebhookURL,
+		BotToken:      channel.BotToken,
+		ChatID:        channel.ChatID,
+		HomeserverURL: channel.HomeserverURL,
+		AccessToken:   channel.AccessToken,
+		RoomID:        channel.RoomID,
+		CreatedAt:     channel.CreatedAt,
+		UpdatedAt:     channel.UpdatedAt,
+	}
+}
+
 // NewDeletedResponse creates a deleted response
 func NewDeletedResponse(id string) DeletedResponse {
 	return DeletedResponse{ID: id}
@@ -154,8 +543,15 @@ func NewDomainPagesResponse(domain string, pages []PageViewCount) DomainPagesRes
 
 // NewPaginatedCommentsResponse creates a paginated comments response
 func NewPaginatedCommentsResponse(comments []models.Comment, total int64, limit, skip int) PaginatedCommentsResponse {
+	// Site owners see why comments were held
+	response := CommentsToResponse(comments)
+	for i := range response {
+		response[i].SpamScore = comments[i].SpamScore
+		response[i].SpamReasons = comments[i].SpamReasons
+	}
+
 	return PaginatedCommentsResponse{
-		Comments: CommentsToResponse(comments),
+		Comments: response,
 		Total:    total,
 		Limit:    limit,
 		Skip:     skip,
@@ -163,6 +559,32 @@ func NewPaginatedCommentsResponse(comments []models.Comment, total int64, limit,
 	}
 }
 
+// searchSnippetLength is about how many characters of a body search results show
+const searchSnippetLength = 160
+
+// NewCommentSearchResponse creates a comment search response, highlighting
+// the search terms in each comment
+func NewCommentSearchResponse(comments []models.Comment, terms []string, total int64, limit, skip int) CommentSearchResponse {
+	page := NewPaginatedCommentsResponse(comments, total, limit, skip)
+
+	results := make([]CommentSearchResultResponse, 0, len(comments))
+	for i := range comments {
+		results = append(results, CommentSearchResultResponse{
+			Comment: page.Comments[i],
+			Snippet: utils.Snippet(utils.HTMLToText(comments[i].Body), terms, searchSnippetLength),
+			Author:  utils.Highlight(comments[i].Author, terms),
+		})
+	}
+
+	return CommentSearchResponse{
+		Results: results,
+		Total:   page.Total,
+		Limit:   page.Limit,
+		Skip:    page.Skip,
+		HasMore: page.HasMore,
+	}
+}
+
 // ========================================
 // Common Helpers
 // ========================================
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/utils"
+)
+
+// maxSearchQueryLength bounds the words of a comment search
+const maxSearchQueryLength = 200
+
+// SearchComments finds comments of a site by the words of their body and
+// author name, best matches first
+// GET /api/comments/sites/:siteId/search?q=&pageId=&from=&to=&status=&verified=&email=&hasReplies=&limit=&skip=
+// q supports "exact phrases" and -excluded words; from and to are dates
+// (2024-01-31) or timestamps (RFC 3339), both inclusive.
+func SearchComments(c *gin.Context) {
+	site := findOwnedSite(c, c.Param("siteId"))
+	if site == nil {
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	terms := utils.SearchTerms(query)
+	if len(terms) == 0 || len(query) > maxSearchQueryLength {
+		errors.BadRequest("Invalid search query").Response(c)
+		return
+	}
+
+	// Deleted comments are left out
+	filter := bson.M{
+		"domain":    site.Domain,
+		"deletedAt": nil,
+		"$text":     bson.M{"$search": query},
+	}
+	if pageID := c.Query("pageId"); pageID != "" {
+		filter["pageId"] = pageID
+	}
+	if email := utils.CleanEmail(c.Query("email")); email != "" {
+		filter["email"] = email
+	}
+	if status := c.Query("status"); status != "" {
+		if !models.IsValidCommentStatus(status) {
+			errors.BadRequest("Invalid status").Response(c)
+			return
+		}
+		filter["status"] = statusFilter(status)
+	}
+
+	createdAt := bson.M{}
+	if from := c.Query("from"); from != "" {
+		date, err := parseSearchDate(from, false)
+		if err != nil {
+			errors.BadRequest("Invalid date").Response(c)
+			return
+		}
+		createdAt["$gte"] = date
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := parseSearchDate(to, true)
+		if err != nil {
+			errors.BadRequest("Invalid date").Response(c)
+			return
+		}
+		createdAt["$lte"] = date
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+
+	if verified := c.Query("verified"); verified != "" {
+		isVerified, err := strconv.ParseBool(verified)
+		if err != nil {
+			errors.BadRequest("Invalid filter").Response(c)
+			return
+		}
+		filter["isVerified"] = isVerified
+	}
+	var hasReplies *bool
+	if value := c.Query("hasReplies"); value != "" {
+		replied, err := strconv.ParseBool(value)
+		if err != nil {
+			errors.BadRequest("Invalid filter").Response(c)
+			return
+		}
+		hasReplies = &replied
+	}
+
+	limit, skip := repository.ParsePagination(c.Query("limit"), c.Query("skip"))
+	comments, total, err := repository.SearchComments(filter, hasReplies, limit, skip)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewCommentSearchResponse(comments, terms, total, limit, skip))
+}
+
+// parseSearchDate parses a date or timestamp of a search filter
+// A date ends the day when end is set, so ranges include their last day.
+func parseSearchDate(value string, end bool) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		date = date.Add(24*time.Hour - time.Nanosecond)
+	}
+	return date, nil
+}
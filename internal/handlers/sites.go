@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kamva/mgm/v3"
@@ -13,8 +14,11 @@ import (
 
 	"zoomment-server/internal/config"
 	"zoomment-server/internal/errors"
+	"zoomment-server/internal/i18n"
+	"zoomment-server/internal/logger"
 	"zoomment-server/internal/middleware"
 	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
 	"zoomment-server/internal/services/metadata"
 	"zoomment-server/internal/validators"
 )
@@ -117,5 +121,146 @@ func DeleteSite(c *gin.Context) {
 		return
 	}
 
+	// Stop sending its webhooks
+	if _, err := mgm.Coll(&models.Webhook{}).DeleteMany(mgm.Ctx(), bson.M{"siteId": objID}); err != nil {
+		logger.Error(err, "Failed to delete webhooks of site")
+	}
+	if _, err := mgm.Coll(&models.NotificationChannel{}).DeleteMany(mgm.Ctx(), bson.M{"siteId": objID}); err != nil {
+		logger.Error(err, "Failed to delete notification channels of site")
+	}
+	if _, err := mgm.Coll(&models.BlocklistEntry{}).DeleteMany(mgm.Ctx(), bson.M{"siteId": objID}); err != nil {
+		logger.Error(err, "Failed to delete blocklist of site")
+	}
+	if _, err := mgm.Coll(&models.Report{}).DeleteMany(mgm.Ctx(), bson.M{"siteId": objID}); err != nil {
+		logger.Error(err, "Failed to delete reports of site")
+	}
+	if _, err := mgm.Coll(&models.Reputation{}).DeleteMany(mgm.Ctx(), bson.M{"siteId": objID}); err != nil {
+		logger.Error(err, "Failed to delete commenter reputations of site")
+	}
+	if err := repository.DeleteSpamTraining(objID); err != nil {
+		logger.Error(err, "Failed to delete spam training of site")
+	}
+
 	c.JSON(http.StatusOK, NewDeletedResponse(siteID))
 }
+
+// UpdateSite changes the settings of a site
+// PATCH /api/sites/:id
+func UpdateSite(c *gin.Context) {
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return
+	}
+
+	var req validators.UpdateSiteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.BadRequest("Invalid site settings").Response(c)
+		return
+	}
+
+	if req.Locale != nil && *req.Locale != "" && !i18n.Supported(*req.Locale) {
+		errors.BadRequest("Unsupported locale").Response(c)
+		return
+	}
+
+	// Holding must start below the spam score
+	if spamReq := req.Spam; spamReq != nil && spamReq.HoldScore > 0 && spamReq.SpamScore > 0 && spamReq.HoldScore >= spamReq.SpamScore {
+		errors.BadRequest("Invalid site settings").Response(c)
+		return
+	}
+
+	// Only overwrite the settings present in the request
+	if req.Locale != nil {
+		site.Locale = *req.Locale
+	}
+	if req.ModerationMode != nil {
+		site.ModerationMode = *req.ModerationMode
+	}
+	if req.Sanitizer != nil {
+		site.Sanitizer = &models.SanitizerSettings{
+			TagSet:             req.Sanitizer.TagSet,
+			RewriteLinks:       req.Sanitizer.RewriteLinks,
+			AllowedLinkDomains: lowerAll(req.Sanitizer.AllowedLinkDomains),
+			DeniedLinkDomains:  lowerAll(req.Sanitizer.DeniedLinkDomains),
+			AllowImages:        req.Sanitizer.AllowImages,
+		}
+	}
+	if req.Challenge != nil {
+		site.Challenge = &models.ChallengeSettings{
+			Enabled:    req.Challenge.Enabled,
+			Difficulty: req.Challenge.Difficulty,
+		}
+	}
+	if req.Trust != nil {
+		site.Trust = &models.TrustSettings{Enabled: req.Trust.Enabled, Threshold: req.Trust.Threshold}
+	}
+	if req.Reports != nil {
+		site.Reports = &models.ReportSettings{HideThreshold: req.Reports.HideThreshold}
+	}
+	if req.Spam != nil {
+		site.Spam = &models.SpamSettings{
+			Disabled:     req.Spam.Disabled,
+			HoldScore:    req.Spam.HoldScore,
+			SpamScore:    req.Spam.SpamScore,
+			BlockedTerms: req.Spam.BlockedTerms,
+			AkismetKey:   strings.TrimSpace(req.Spam.AkismetKey),
+
+			MinSubmitSeconds: req.Spam.MinSubmitSeconds,
+			RequireFormToken: req.Spam.RequireFormToken,
+		}
+	}
+
+	if err := mgm.Coll(site).Update(site); err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, SiteToResponse(site))
+}
+
+// ========================================
+// Helper Functions
+// ========================================
+
+// findOwnedSite loads a site by ID and checks that it belongs to the current user
+// Responds with 404 (same as Node.js) and returns nil if the site can't be used
+func findOwnedSite(c *gin.Context, siteID string) *models.Site {
+	user := middleware.GetUser(c)
+
+	objID, err := primitive.ObjectIDFromHex(siteID)
+	if err != nil {
+		errors.NotFound("Site").Response(c)
+		return nil
+	}
+
+	site := &models.Site{}
+	if err := mgm.Coll(site).FindByID(objID, site); err != nil {
+		errors.NotFound("Site").Response(c)
+		return nil
+	}
+
+	if user == nil || site.UserID != user.ID {
+		errors.NotFound("Site").Response(c)
+		return nil
+	}
+
+	return site
+}
+
+// lowerAll lowercases a list of domains (never returns nil, for consistent JSON)
+func lowerAll(domains []string) []string {
+	result := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		result = append(result, strings.ToLower(domain))
+	}
+	return result
+}
+
+// siteForDomain returns the registered site for a domain, or nil if there is none
+func siteForDomain(domain string) *models.Site {
+	site := &models.Site{}
+	if err := mgm.Coll(site).First(bson.M{"domain": domain}, site); err != nil {
+		return nil
+	}
+	return site
+}
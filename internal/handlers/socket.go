@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gorilla/websocket"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/events"
+	"zoomment-server/internal/i18n"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/ratelimit"
+	"zoomment-server/internal/utils"
+	"zoomment-server/internal/validators"
+)
+
+// Messages sent by the client
+const (
+	socketSubscribe   = "subscribe"
+	socketUnsubscribe = "unsubscribe"
+	socketComment     = "comment"
+	socketVote        = "vote"
+)
+
+// Messages sent by the server (besides page events)
+const (
+	socketResult = "result"
+	socketError  = "error"
+)
+
+const (
+	// socketMaxMessage is the largest message a client may send
+	socketMaxMessage = 64 * 1024
+
+	// socketMaxPages is how many pages one connection may follow
+	socketMaxPages = 20
+
+	// socketWriteWait is how long a write may take
+	socketWriteWait = 10 * time.Second
+
+	// socketPongWait is how long the client may stay silent; pings are sent
+	// at heartbeatInterval, which must be shorter
+	socketPongWait = 60 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Widgets are embedded on any site, like the CORS policy allows
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// SocketMessage is a message from the client
+// RequestID is echoed back in the result of a comment or vote.
+type SocketMessage struct {
+	Type      string          `json:"type"`
+	RequestID string          `json:"requestId,omitempty"`
+	PageIDs   []string        `json:"pageIds,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// SocketReply is the result of a comment or vote sent over the socket
+type SocketReply struct {
+	Type      string `json:"type"`
+	RequestID string `json:"requestId,omitempty"`
+	Data      any    `json:"data"`
+}
+
+// CommentsSocket serves the WebSocket API for live widgets
+// GET /api/comments/ws?fingerprint=xxx&token=xxx
+// Clients subscribe to pages and receive the same events as the SSE stream,
+// plus "presence" events with the number of people reading each page.
+// Comments and votes can be posted over the socket too, with the same rate
+// limits as the REST API.
+func CommentsSocket(cfg *config.Config, limiter *ratelimit.Limiter) gin.HandlerFunc {
+	services := newCommentServices(cfg)
+
+	return func(c *gin.Context) {
+		// Browsers can't set headers on WebSocket connections
+		fingerprint := c.GetHeader("fingerprint")
+		if fingerprint == "" {
+			fingerprint = c.Query("fingerprint")
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// The upgrader has already replied with an error
+			logger.Debug("WebSocket upgrade failed: " + err.Error())
+			return
+		}
+
+		origin := commentOriginOf(c)
+		origin.Fingerprint = fingerprint
+
+		s := &socket{
+			services:   services,
+			limiter:    limiter,
+			conn:       conn,
+			sub:        events.Subscribe(),
+			replies:    make(chan SocketReply, 16),
+			done:       make(chan struct{}),
+			writerDone: make(chan struct{}),
+			pages:      make(map[string]struct{}),
+			origin:     origin,
+			// Readers without a fingerprint are counted per connection
+			reader: fingerprint,
+		}
+		if s.reader == "" {
+			s.reader = "conn:" + utils.GenerateSecret()
+		}
+
+		go s.writeLoop()
+		s.readLoop()
+		s.close()
+	}
+}
+
+// socket is one WebSocket connection
+// Only writeLoop writes to the connection.
+type socket struct {
+	services   *commentServices
+	limiter    *ratelimit.Limiter
+	conn       *websocket.Conn
+	sub        *events.Subscription
+	replies    chan SocketReply
+	done       chan struct{} // closed when the reader stops
+	writerDone chan struct{} // closed when the writer stops
+
+	// Only used by readLoop
+	pages  map[string]struct{}
+	origin commentOrigin
+	reader string
+}
+
+// readLoop handles client messages until the connection fails
+func (s *socket) readLoop() {
+	s.conn.SetReadLimit(socketMaxMessage)
+	s.conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	})
+
+	for {
+		var msg SocketMessage
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				s.reply(msg, errors.BadRequest("Invalid message"))
+				continue
+			}
+			return
+		}
+		s.conn.SetReadDeadline(time.Now().Add(socketPongWait))
+
+		switch msg.Type {
+		case socketSubscribe:
+			s.subscribe(msg)
+		case socketUnsubscribe:
+			for _, pageID := range msg.PageIDs {
+				s.unsubscribe(pageID)
+			}
+		case socketComment:
+			s.comment(msg)
+		case socketVote:
+			s.vote(msg)
+		default:
+			s.reply(msg, errors.BadRequest("Unknown message type"))
+		}
+	}
+}
+
+// writeLoop sends page events, replies and pings until the socket closes
+func (s *socket) writeLoop() {
+	ping := time.NewTicker(heartbeatInterval)
+	defer ping.Stop()
+	defer close(s.writerDone)
+	// Unblocks readLoop when writing fails
+	defer s.conn.Close()
+
+	for {
+		select {
+		case <-s.done:
+			s.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		case event, ok := <-s.sub.Events:
+			if !ok {
+				// Fell behind; the client reconnects and reloads
+				s.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"))
+				return
+			}
+			if !s.writeJSON(event) {
+				return
+			}
+		case reply := <-s.replies:
+			if !s.writeJSON(reply) {
+				return
+			}
+		case <-ping.C:
+			if !s.write(websocket.PingMessage, nil) {
+				return
+			}
+		}
+	}
+}
+
+// close leaves all pages and stops the writer
+func (s *socket) close() {
+	for pageID := range s.pages {
+		s.unsubscribe(pageID)
+	}
+	s.sub.Close()
+	close(s.done)
+}
+
+func (s *socket) write(messageType int, data []byte) bool {
+	s.conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	return s.conn.WriteMessage(messageType, data) == nil
+}
+
+func (s *socket) writeJSON(v any) bool {
+	s.conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	return s.conn.WriteJSON(v) == nil
+}
+
+// reply queues the result of a client message (an *errors.AppError for failures)
+func (s *socket) reply(msg SocketMessage, data any) {
+	reply := SocketReply{Type: socketResult, RequestID: msg.RequestID, Data: data}
+	if appErr, ok := data.(*errors.AppError); ok {
+		reply.Type = socketError
+		data := gin.H{
+			"message": i18n.T(s.origin.Locale, appErr.Message),
+			"code":    appErr.Code,
+		}
+		if appErr.Details != nil {
+			data["details"] = appErr.Details
+		}
+		reply.Data = data
+	}
+
+	select {
+	case s.replies <- reply:
+	case <-s.writerDone:
+	}
+}
+
+// subscribe follows pages and joins their presence counts
+func (s *socket) subscribe(msg SocketMessage) {
+	for _, pageID := range msg.PageIDs {
+		if _, ok := s.pages[pageID]; ok || pageID == "" {
+			continue
+		}
+		if len(s.pages) >= socketMaxPages {
+			s.reply(msg, errors.BadRequest("Too many pages"))
+			return
+		}
+		s.pages[pageID] = struct{}{}
+		// Follow first so the presence event reaches this connection too
+		s.sub.Follow(pageID)
+		events.DefaultPresence.Join(pageID, s.reader)
+	}
+}
+
+// unsubscribe stops following a page
+func (s *socket) unsubscribe(pageID string) {
+	if _, ok := s.pages[pageID]; !ok {
+		return
+	}
+	delete(s.pages, pageID)
+	s.sub.Unfollow(pageID)
+	events.DefaultPresence.Leave(pageID, s.reader)
+}
+
+// comment posts a comment, validated like POST /api/comments
+func (s *socket) comment(msg SocketMessage) {
+	var req validators.AddCommentRequest
+	if err := decodeSocketData(msg.Data, &req); err != nil {
+		s.reply(msg, errors.BadRequest("Invalid request body"))
+		return
+	}
+
+	keys := s.rateLimitKeys()
+	if s.origin.User == nil {
+		keys[ratelimit.KeyEmail] = strings.ToLower(req.Email)
+	}
+	if parsed, err := url.Parse(req.PageURL); err == nil {
+		keys[ratelimit.KeySite] = strings.ToLower(parsed.Hostname())
+	}
+	if wait := s.limiter.Allow(ratelimit.RouteComments, keys); wait > 0 {
+		s.reply(msg, errors.TooManyRequests(ratelimit.RetryAfterSeconds(wait)))
+		return
+	}
+
+	comment, appErr := createComment(s.services, req, s.origin)
+	if appErr != nil {
+		s.reply(msg, appErr)
+		return
+	}
+	s.reply(msg, authorResponse(comment))
+}
+
+// vote votes on a comment, validated like POST /api/votes
+func (s *socket) vote(msg SocketMessage) {
+	if s.origin.Fingerprint == "" {
+		s.reply(msg, errors.BadRequest("Fingerprint required for voting"))
+		return
+	}
+
+	var req VoteRequest
+	if err := decodeSocketData(msg.Data, &req); err != nil {
+		s.reply(msg, errors.BadRequest("commentId is required and value must be 1 or -1"))
+		return
+	}
+
+	if wait := s.limiter.Allow(ratelimit.RouteVotes, s.rateLimitKeys()); wait > 0 {
+		s.reply(msg, errors.TooManyRequests(ratelimit.RetryAfterSeconds(wait)))
+		return
+	}
+
+	response, appErr := castVote(s.services.cfg, req, s.origin)
+	if appErr != nil {
+		s.reply(msg, appErr)
+		return
+	}
+	s.reply(msg, response)
+}
+
+// rateLimitKeys returns the rate limit keys of the connection
+func (s *socket) rateLimitKeys() map[string]string {
+	keys := map[string]string{
+		ratelimit.KeyIP:          s.origin.IP,
+		ratelimit.KeyFingerprint: s.origin.Fingerprint,
+	}
+	if s.origin.User != nil {
+		keys[ratelimit.KeyEmail] = s.origin.User.Email
+	}
+	return keys
+}
+
+// decodeSocketData decodes and validates the data of a message
+// Uses the same binding rules as the REST handlers.
+func decodeSocketData(data json.RawMessage, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(v)
+}
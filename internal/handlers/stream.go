@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/events"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+)
+
+// heartbeatInterval keeps idle streams alive through proxies
+const heartbeatInterval = 25 * time.Second
+
+// StreamComments pushes live events for a page over Server-Sent Events
+// GET /api/comments/stream?pageId=xxx
+// Reconnecting clients send Last-Event-ID (or ?lastEventId=) to get the events
+// they missed; a "reset" event means they should reload the comments instead.
+func StreamComments(c *gin.Context) {
+	pageID := c.Query("pageId")
+	if pageID == "" {
+		errors.BadRequest("pageId is required").Response(c)
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+
+	// Subscribe before replaying so nothing is lost in between
+	sub := events.Subscribe(pageID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable nginx buffering
+	c.Status(http.StatusOK)
+
+	// sent is the ID of the last event written, to skip duplicates
+	var sent uint64
+	if lastID, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+		missed, ok := events.Replay(pageID, lastID)
+		if ok {
+			for _, event := range missed {
+				writeSSE(c, event.ID, event.Type, event.Data)
+			}
+			sent = lastID
+			if len(missed) > 0 {
+				sent = missed[len(missed)-1].ID
+			}
+		} else {
+			// The ID is unknown here (another instance or an old process):
+			// the client reloads and gets every new event
+			writeSSE(c, 0, "reset", gin.H{"pageId": pageID})
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				// Fell behind; the client reconnects with Last-Event-ID
+				return
+			}
+			// Skips replayed events and transient ones (ID 0), like presence
+			if event.ID <= sent {
+				continue
+			}
+			writeSSE(c, event.ID, event.Type, event.Data)
+			sent = event.ID
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			writeSSE(c, 0, "ping", gin.H{})
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeSSE writes one Server-Sent Event (id 0 = no id)
+func writeSSE(c *gin.Context, id uint64, eventType string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		logger.Error(err, "Failed to encode event")
+		return
+	}
+
+	if id != 0 {
+		fmt.Fprintf(c.Writer, "id: %d\n", id)
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", eventType, payload)
+}
+
+// ========================================
+// Publishing
+// ========================================
+
+// publish announces a change on a page of a site
+func publish(domain, pageID, eventType string, data any) {
+	events.PublishEvent(events.Event{Domain: domain, PageID: pageID, Type: eventType, Data: data})
+}
+
+// publishComment announces a new or edited comment
+// Comments waiting for moderation are not announced.
+func publishComment(eventType string, comment *models.Comment) {
+	if comment.CurrentStatus() != models.CommentStatusApproved {
+		return
+	}
+	publish(comment.Domain, comment.PageID, eventType, repository.PublicComment(comment))
+}
+
+// publishCommentDeleted announces that a comment is gone
+// tombstone is true when it stays in the thread as "[deleted]".
+func publishCommentDeleted(comment *models.Comment, tombstone bool) {
+	publish(comment.Domain, comment.PageID, events.CommentDeleted, events.CommentDeletedEvent{
+		ID:        comment.ID.Hex(),
+		ParentID:  comment.ParentID,
+		Tombstone: tombstone,
+	})
+}
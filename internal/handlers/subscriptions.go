@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/i18n"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/tokens"
+)
+
+// unsubscribePage is shown when a reader opens an unsubscribe link
+// Opening the link only shows the choices; unsubscribing needs a POST,
+// so link scanners in mail clients can't unsubscribe people by accident.
+var unsubscribePage = template.Must(template.New("unsubscribe").Funcs(template.FuncMap{"t": i18n.T}).Parse(`<!doctype html>
+<html lang="{{.Locale}}">
+<head>
+	<meta name="viewport" content="width=device-width, initial-scale=1.0">
+	<title>{{.BrandName}}</title>
+	<style>
+		body { font-family: Helvetica, sans-serif; background: #f5f5f5; margin: 0; padding: 24px; }
+		.main { background: #fff; border: 1px solid #eaebed; border-radius: 14px; max-width: 400px; margin: 0 auto; padding: 24px 35px; }
+		button { background: #1677ff; border: 0; border-radius: 8px; color: #fff; font-size: 15px; margin: 4px 0; padding: 10px 20px; width: 100%; cursor: pointer; }
+	</style>
+</head>
+<body>
+	<div class="main">
+		<h2>{{.BrandName}}</h2>
+		{{if .Error}}
+			<p>{{.Error}}</p>
+		{{else if .Done}}
+			<p>{{t .Locale "You have been unsubscribed. You won't get these emails anymore."}}</p>
+		{{else}}
+			<p>{{t .Locale "Which reply notifications do you want to stop?"}}</p>
+			<form method="post">
+				<button name="scope" value="thread">{{t .Locale "Only this conversation"}}</button>
+				<button name="scope" value="site">{{t .Locale "Everything from %s" .Domain}}</button>
+				<button name="scope" value="all">{{t .Locale "All reply notifications"}}</button>
+			</form>
+		{{end}}
+	</div>
+</body>
+</html>
+`))
+
+// unsubscribePageData holds data for unsubscribePage
+type unsubscribePageData struct {
+	Locale    string
+	BrandName string
+	Domain    string
+	Done      bool
+	Error     string
+}
+
+// ShowUnsubscribe shows the unsubscribe choices for a signed link
+// GET /api/subscriptions/unsubscribe?token=xxx
+func ShowUnsubscribe(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := i18n.First(i18n.FromContext(c))
+		data := unsubscribePageData{Locale: locale, BrandName: cfg.BrandName}
+
+		claims, err := tokens.Parse(cfg.JWTSecret, tokens.PurposeUnsubscribe, c.Query("token"))
+		if err != nil {
+			data.Error = i18n.T(locale, "This unsubscribe link is invalid.")
+			renderPage(c, http.StatusBadRequest, unsubscribePage, data)
+			return
+		}
+
+		data.Domain = tokens.String(claims, "domain")
+		renderPage(c, http.StatusOK, unsubscribePage, data)
+	}
+}
+
+// Unsubscribe stores the reader's choice
+// POST /api/subscriptions/unsubscribe?token=xxx (form: scope=thread|site|all)
+// Also handles RFC 8058 one-click requests from mail clients, which post
+// "List-Unsubscribe=One-Click" and unsubscribe from the thread.
+func Unsubscribe(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := i18n.First(i18n.FromContext(c))
+		data := unsubscribePageData{Locale: locale, BrandName: cfg.BrandName}
+
+		claims, err := tokens.Parse(cfg.JWTSecret, tokens.PurposeUnsubscribe, c.Query("token"))
+		if err != nil {
+			data.Error = i18n.T(locale, "This unsubscribe link is invalid.")
+			renderPage(c, http.StatusBadRequest, unsubscribePage, data)
+			return
+		}
+
+		scope := c.PostForm("scope")
+		switch scope {
+		case models.SubscriptionScopeThread, models.SubscriptionScopeSite, models.SubscriptionScopeAll:
+		default:
+			scope = models.SubscriptionScopeThread
+		}
+
+		err = repository.SetSubscription(
+			tokens.String(claims, "email"),
+			scope,
+			tokens.String(claims, "domain"),
+			tokens.String(claims, "thread"),
+			false,
+		)
+		if err != nil {
+			logger.Error(err, "Failed to save subscription preference")
+			data.Error = i18n.T(locale, "Something went wrong, please try again later.")
+			renderPage(c, http.StatusInternalServerError, unsubscribePage, data)
+			return
+		}
+
+		data.Done = true
+		renderPage(c, http.StatusOK, unsubscribePage, data)
+	}
+}
+
+// renderPage renders an HTML page template
+func renderPage(c *gin.Context, status int, page *template.Template, data any) {
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := page.Execute(c.Writer, data); err != nil {
+		logger.Error(err, "Failed to render page")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"zoomment-server/internal/config"
 	"zoomment-server/internal/constants"
 	"zoomment-server/internal/errors"
+	"zoomment-server/internal/i18n"
 	"zoomment-server/internal/logger"
 	"zoomment-server/internal/middleware"
 	"zoomment-server/internal/models"
@@ -71,15 +72,14 @@ func AuthUser(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		// Send magic link email (async - don't block the response)
-		go func() {
-			if err := mailService.SendMagicLink(email, tokenString); err != nil {
-				// Error is already logged in mailer.SendMagicLink
-				// We don't fail the request, just log the error
-			}
-		}()
+		// Queue magic link email (the outbox workers send it)
+		if err := mailService.SendMagicLink(recipient(user, i18n.FromContext(c)), tokenString); err != nil {
+			// Error is already logged in mailer.SendMagicLink
+			errors.ErrDatabaseError.Response(c)
+			return
+		}
 
-		c.JSON(http.StatusOK, MessageResponse{Message: "Magic link sent to your email"})
+		c.JSON(http.StatusOK, MessageResponse{Message: i18n.T(i18n.FromContext(c), "Magic link sent to your email")})
 	}
 }
 
@@ -93,11 +93,49 @@ func GetProfile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, UserProfileResponse{
-		ID:    user.ID.Hex(),
-		Name:  user.Name,
-		Email: user.Email,
-	})
+	c.JSON(http.StatusOK, UserToProfileResponse(user))
+}
+
+// UpdateProfile changes the current user's language and timezone
+// PATCH /api/users/profile
+func UpdateProfile(c *gin.Context) {
+	user := middleware.GetUser(c)
+
+	if user == nil {
+		errors.ErrForbidden.Response(c)
+		return
+	}
+
+	var req validators.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.BadRequest("Invalid request body").Response(c)
+		return
+	}
+
+	// Empty values reset the preference
+	if req.Locale != nil && *req.Locale != "" && !i18n.Supported(*req.Locale) {
+		errors.BadRequest("Unsupported locale").Response(c)
+		return
+	}
+	if req.Timezone != nil && *req.Timezone != "" && !i18n.ValidTimezone(*req.Timezone) {
+		errors.BadRequest("Unknown timezone").Response(c)
+		return
+	}
+
+	if req.Locale != nil {
+		user.Locale = *req.Locale
+	}
+	if req.Timezone != nil {
+		user.Timezone = *req.Timezone
+	}
+
+	if err := mgm.Coll(user).Update(user); err != nil {
+		logger.Error(err, "Failed to update user profile")
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, UserToProfileResponse(user))
 }
 
 // DeleteUser deletes the current user and their sites
@@ -10,14 +10,18 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 
+	"zoomment-server/internal/config"
 	"zoomment-server/internal/errors"
+	"zoomment-server/internal/events"
 	"zoomment-server/internal/models"
+	"zoomment-server/internal/validators"
 )
 
 // VoteRequest represents the request body for voting
 type VoteRequest struct {
-	CommentID string `json:"commentId" binding:"required"`
-	Value     int    `json:"value" binding:"required,oneof=1 -1"`
+	CommentID string                        `json:"commentId" binding:"required"`
+	Value     int                           `json:"value" binding:"required,oneof=1 -1"`
+	PoW       *validators.ChallengeSolution `json:"pow"`
 }
 
 // Vote handles voting on a comment (upvote/downvote)
@@ -25,46 +29,86 @@ type VoteRequest struct {
 // - If no vote exists: create vote
 // - If same vote exists: remove vote (toggle off)
 // - If opposite vote exists: update vote
-func Vote(c *gin.Context) {
-	fingerprint := c.GetHeader("fingerprint")
-	if fingerprint == "" {
-		errors.BadRequest("Fingerprint required for voting").Response(c)
-		return
-	}
+func Vote(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fingerprint := c.GetHeader("fingerprint")
+		if fingerprint == "" {
+			errors.BadRequest("Fingerprint required for voting").Response(c)
+			return
+		}
 
-	var req VoteRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		errors.BadRequest("commentId is required and value must be 1 or -1").Response(c)
-		return
+		var req VoteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			errors.BadRequest("commentId is required and value must be 1 or -1").Response(c)
+			return
+		}
+
+		response, appErr := castVote(cfg, req, commentOriginOf(c))
+		if appErr != nil {
+			appErr.Response(c)
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
 	}
+}
+
+// castVote applies a validated vote and announces the new counts
+// Shared by the REST and WebSocket APIs. origin must have a fingerprint.
+func castVote(cfg *config.Config, req VoteRequest, origin commentOrigin) (*models.VoteResponse, *errors.AppError) {
+	fingerprint := origin.Fingerprint
 
 	// Verify comment exists
 	commentObjID, err := primitive.ObjectIDFromHex(req.CommentID)
 	if err != nil {
-		errors.BadRequest("Invalid comment ID").Response(c)
-		return
+		return nil, errors.BadRequest("Invalid comment ID")
 	}
 
+	// Only comments readers can see take votes
 	comment := &models.Comment{}
-	if err := mgm.Coll(comment).FindByID(commentObjID, comment); err != nil {
-		errors.NotFound("Comment").Response(c)
-		return
+	if err := mgm.Coll(comment).FindByID(commentObjID, comment); err != nil ||
+		comment.DeletedAt != nil || comment.CurrentStatus() != models.CommentStatusApproved {
+		return nil, errors.NotFound("Comment")
+	}
+
+	// Sites may make guests prove some work first
+	site := siteForDomain(comment.Domain)
+	if appErr := checkChallenge(cfg, site, origin.User, req.PoW); appErr != nil {
+		return nil, appErr
+	}
+
+	// Votes of held and shadow-banned voters are dropped without a word
+	blockMode, appErr := checkBlocklist(site, blocklistSubject(origin))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if blockMode != "" {
+		response, err := calculateVoteCounts(req.CommentID, fingerprint)
+		if err != nil {
+			return nil, errors.ErrDatabaseError
+		}
+		return response, nil
 	}
 
 	// Handle vote logic
 	if err := processVote(req.CommentID, fingerprint, req.Value); err != nil {
-		errors.ErrDatabaseError.Response(c)
-		return
+		return nil, errors.ErrDatabaseError
 	}
 
 	// Return updated vote counts
 	response, err := calculateVoteCounts(req.CommentID, fingerprint)
 	if err != nil {
-		errors.ErrDatabaseError.Response(c)
-		return
+		return nil, errors.ErrDatabaseError
 	}
 
-	c.JSON(http.StatusOK, response)
+	publish(comment.Domain, comment.PageID, events.VoteChanged, events.VoteChangedEvent{
+		CommentID: response.CommentID,
+		Upvotes:   response.Upvotes,
+		Downvotes: response.Downvotes,
+		Score:     response.Score,
+	})
+
+	return response, nil
 }
 
 // GetVote returns vote counts for a single comment
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/utils"
+	"zoomment-server/internal/validators"
+)
+
+const (
+	// maxWebhooksPerSite limits how many webhooks a site can have
+	maxWebhooksPerSite = 10
+
+	// Delivery log page size
+	defaultDeliveriesLimit = 20
+	maxDeliveriesLimit     = 100
+)
+
+// ListWebhooks returns the webhooks of a site
+// GET /api/sites/:id/webhooks
+func ListWebhooks(c *gin.Context) {
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return
+	}
+
+	webhooks, err := repository.GetSiteWebhooks(site.ID)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	response := make([]WebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		response = append(response, WebhookToResponse(&webhooks[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AddWebhook registers a webhook for a site
+// POST /api/sites/:id/webhooks
+// The response contains the secret used to sign the payloads.
+func AddWebhook(c *gin.Context) {
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return
+	}
+
+	var req validators.AddWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.BadRequest("Invalid webhook").Response(c)
+		return
+	}
+
+	existing, err := repository.GetSiteWebhooks(site.ID)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+	if len(existing) >= maxWebhooksPerSite {
+		errors.BadRequest("Too many webhooks").Response(c)
+		return
+	}
+
+	webhook := &models.Webhook{
+		SiteID: site.ID,
+		URL:    req.URL,
+		Secret: utils.GenerateSecret(),
+		Events: uniqueStrings(req.Events),
+		Active: true,
+	}
+	if err := mgm.Coll(webhook).Create(webhook); err != nil {
+		logger.Error(err, "Failed to create webhook")
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookToResponse(webhook))
+}
+
+// UpdateWebhook changes a webhook
+// PATCH /api/sites/:id/webhooks/:webhookId
+// Set rotateSecret to get a new signing secret.
+func UpdateWebhook(c *gin.Context) {
+	webhook := findOwnedWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	var req validators.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.BadRequest("Invalid webhook").Response(c)
+		return
+	}
+
+	// Only overwrite the settings present in the request
+	if req.URL != nil {
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		webhook.Events = uniqueStrings(req.Events)
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if req.RotateSecret {
+		webhook.Secret = utils.GenerateSecret()
+	}
+
+	if err := mgm.Coll(webhook).Update(webhook); err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookToResponse(webhook))
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+// DELETE /api/sites/:id/webhooks/:webhookId
+func DeleteWebhook(c *gin.Context) {
+	webhook := findOwnedWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	if _, err := repository.DeleteWebhook(webhook.SiteID, webhook.ID); err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewDeletedResponse(webhook.ID.Hex()))
+}
+
+// ListWebhookDeliveries returns the delivery log of a webhook, newest first
+// GET /api/sites/:id/webhooks/:webhookId/deliveries?limit=20
+func ListWebhookDeliveries(c *gin.Context) {
+	webhook := findOwnedWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(defaultDeliveriesLimit)), 10, 64)
+	if err != nil || limit < 1 {
+		limit = defaultDeliveriesLimit
+	}
+	if limit > maxDeliveriesLimit {
+		limit = maxDeliveriesLimit
+	}
+
+	deliveries, err := repository.GetWebhookDeliveries(webhook.ID, limit)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	response := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		response = append(response, WebhookDeliveryToResponse(&deliveries[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RedeliverWebhook sends a past delivery again
+// POST /api/sites/:id/webhooks/:webhookId/deliveries/:deliveryId/redeliver
+// The payload (and its id) is unchanged; the new delivery gets its own log.
+func RedeliverWebhook(c *gin.Context) {
+	webhook := findOwnedWebhook(c)
+	if webhook == nil {
+		return
+	}
+
+	deliveryID, err := primitive.ObjectIDFromHex(c.Param("deliveryId"))
+	if err != nil {
+		errors.NotFound("Delivery").Response(c)
+		return
+	}
+	original, err := repository.GetWebhookDelivery(webhook.ID, deliveryID)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+	if original == nil {
+		errors.NotFound("Delivery").Response(c)
+		return
+	}
+
+	redelivery := &models.WebhookDelivery{
+		WebhookID:    original.WebhookID,
+		SiteID:       original.SiteID,
+		EventID:      original.EventID,
+		EventType:    original.EventType,
+		Payload:      original.Payload,
+		RedeliveryOf: &original.ID,
+	}
+	redelivery.ID = primitive.NewObjectID()
+	redelivery.IdempotencyKey = original.IdempotencyKey + ":redelivery:" + redelivery.ID.Hex()
+
+	if _, err := repository.EnqueueWebhookDelivery(redelivery); err != nil {
+		logger.Error(err, "Failed to queue webhook redelivery")
+		errors.ErrDatabaseError.Response(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookDeliveryToResponse(redelivery))
+}
+
+// ========================================
+// Helper Functions
+// ========================================
+
+// findOwnedWebhook loads the webhook in the URL if its site belongs to the current user
+// Responds with 404 and returns nil if it can't be used
+func findOwnedWebhook(c *gin.Context) *models.Webhook {
+	site := findOwnedSite(c, c.Param("id"))
+	if site == nil {
+		return nil
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("webhookId"))
+	if err != nil {
+		errors.NotFound("Webhook").Response(c)
+		return nil
+	}
+
+	webhook, err := repository.GetWebhook(site.ID, webhookID)
+	if err != nil {
+		errors.ErrDatabaseError.Response(c)
+		return nil
+	}
+	if webhook == nil {
+		errors.NotFound("Webhook").Response(c)
+		return nil
+	}
+
+	return webhook
+}
+
+// uniqueStrings removes duplicates, keeping the first occurrence
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Timezones work even without zoneinfo on the host
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultLocale is the language the messages are written in
+// Messages are their own keys: a missing translation falls back to English.
+const DefaultLocale = "en"
+
+// contextKey is where the request locale is stored in the gin context
+const contextKey = "locale"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogs maps a locale to its translations (English message -> translation)
+var catalogs = loadCatalogs()
+
+// loadCatalogs reads the embedded locale files
+func loadCatalogs() map[string]map[string]string {
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	result := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		data, err := localeFiles.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		catalog := make(map[string]string)
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: invalid locale file %s: %v", file.Name(), err))
+		}
+		result[strings.TrimSuffix(file.Name(), ".json")] = catalog
+	}
+	return result
+}
+
+// Locales returns all supported locales
+func Locales() []string {
+	locales := []string{DefaultLocale}
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales[1:])
+	return locales
+}
+
+// Supported reports whether a locale can be used
+func Supported(locale string) bool {
+	if locale == DefaultLocale {
+		return true
+	}
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// T translates a message to a locale
+// With args, the translated message is used as a fmt format string.
+func T(locale, message string, args ...any) string {
+	if translated, ok := catalogs[locale][message]; ok && translated != "" {
+		message = translated
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// First returns the first supported locale, or DefaultLocale
+// Use it to pick between preferences, most specific first.
+func First(locales ...string) string {
+	for _, locale := range locales {
+		if Supported(locale) {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Match picks the best supported locale from an Accept-Language header
+// Returns "" when none of the languages is supported.
+func Match(header string) string {
+	type candidate struct {
+		locale string
+		q      float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		// Only the language matters: "pt-BR" -> "pt"
+		language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if Supported(language) {
+			candidates = append(candidates, candidate{locale: language, q: q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].locale
+}
+
+// SetLocale stores the locale of the current request
+func SetLocale(c *gin.Context, locale string) {
+	c.Set(contextKey, locale)
+}
+
+// FromContext returns the locale of the current request
+// Returns "" when neither the user nor the browser asked for a supported language.
+func FromContext(c *gin.Context) string {
+	return c.GetString(contextKey)
+}
+
+// Timezone returns the location for an IANA timezone name
+// Unknown or empty names use the server's local time.
+func Timezone(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
+
+// ValidTimezone reports whether name is a known IANA timezone
+func ValidTimezone(name string) bool {
+	_, err := time.LoadLocation(name)
+	return err == nil && name != "" && name != "Local"
+}
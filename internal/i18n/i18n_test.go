@@ -0,0 +1,81 @@
+package i18n
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "empty header", header: "", expected: ""},
+		{name: "exact language", header: "de", expected: "de"},
+		{name: "region is ignored", header: "ru-RU,ru;q=0.9", expected: "ru"},
+		{name: "highest quality wins", header: "es;q=0.5, de;q=0.8", expected: "de"},
+		{name: "skips unsupported languages", header: "fr-FR, fr;q=0.9, es;q=0.7", expected: "es"},
+		{name: "q=0 means not acceptable", header: "ru;q=0, en;q=0.1", expected: "en"},
+		{name: "nothing supported", header: "fr, ja", expected: ""},
+		{name: "wildcard is ignored", header: "*", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Match(tt.header)
+			if result != tt.expected {
+				t.Errorf("Match(%q) = %q, want %q", tt.header, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   string
+		message  string
+		args     []any
+		expected string
+	}{
+		{name: "english is the source", locale: "en", message: "Site not found", expected: "Site not found"},
+		{name: "translated", locale: "de", message: "Site not found", expected: "Website nicht gefunden"},
+		{name: "unknown locale falls back", locale: "fr", message: "Site not found", expected: "Site not found"},
+		{name: "missing translation falls back", locale: "ru", message: "No such message", expected: "No such message"},
+		{name: "formats arguments", locale: "es", message: "Sign in to %s", args: []any{"Zoomment"}, expected: "Inicia sesión en Zoomment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := T(tt.locale, tt.message, tt.args...)
+			if result != tt.expected {
+				t.Errorf("T(%q, %q) = %q, want %q", tt.locale, tt.message, result, tt.expected)
+			}
+		})
+	}
+}
+
+// Every locale must translate the same messages
+func TestCatalogsAreComplete(t *testing.T) {
+	keys := make(map[string]bool)
+	for _, catalog := range catalogs {
+		for key := range catalog {
+			keys[key] = true
+		}
+	}
+
+	for locale, catalog := range catalogs {
+		for key := range keys {
+			if catalog[key] == "" {
+				t.Errorf("locale %s is missing a translation for %q", locale, key)
+			}
+		}
+	}
+}
+
+func TestFirst(t *testing.T) {
+	if got := First("", "fr", "ru", "de"); got != "ru" {
+		t.Errorf("First() = %q, want %q", got, "ru")
+	}
+	if got := First("", "fr"); got != DefaultLocale {
+		t.Errorf("First() = %q, want %q", got, DefaultLocale)
+	}
+}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/kamva/mgm/v3"
@@ -16,6 +18,10 @@ func Auth(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from header (like req.headers.token)
 		tokenString := c.GetHeader("token")
+		if tokenString == "" && isWebSocket(c) {
+			// Browsers can't set headers on WebSocket connections
+			tokenString = c.Query("token")
+		}
 
 		if tokenString == "" {
 			// No token - continue as guest
@@ -128,3 +134,8 @@ func Access(level ...string) gin.HandlerFunc {
 		c.AbortWithStatusJSON(403, gin.H{"message": "Forbidden"})
 	}
 }
+
+// isWebSocket reports whether the request is a WebSocket handshake
+func isWebSocket(c *gin.Context) bool {
+	return strings.EqualFold(c.GetHeader("Upgrade"), "websocket")
+}
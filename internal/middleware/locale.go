@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"zoomment-server/internal/i18n"
+)
+
+// Locale middleware picks the language for the request
+// The signed-in user's preference wins over the Accept-Language header.
+// Must run after Auth.
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user := GetUser(c); user != nil && user.Locale != "" && i18n.Supported(user.Locale) {
+			i18n.SetLocale(c, user.Locale)
+		} else if locale := i18n.Match(c.GetHeader("Accept-Language")); locale != "" {
+			i18n.SetLocale(c, locale)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"zoomment-server/internal/errors"
+	"zoomment-server/internal/ratelimit"
+)
+
+// maxPeekedBody is the largest JSON body read for rate limit keys
+const maxPeekedBody = 64 * 1024
+
+// RateLimit middleware limits how often clients may call a route
+// Requests are counted by client IP, fingerprint header, and the email and
+// site (domain of pageUrl or pageId) in the JSON body. Over the limit, it
+// responds with 429 and a Retry-After header.
+func RateLimit(limiter *ratelimit.Limiter, route string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if wait := limiter.Allow(route, RateLimitKeys(c)); wait > 0 {
+			errors.TooManyRequests(ratelimit.RetryAfterSeconds(wait)).Abort(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitFields are the body fields used as rate limit keys
+type rateLimitFields struct {
+	Email   string `json:"email"`
+	PageURL string `json:"pageUrl"`
+	PageID  string `json:"pageId"`
+}
+
+// RateLimitKeys returns the rate limit keys of a request
+// The body is put back for the handler.
+func RateLimitKeys(c *gin.Context) map[string]string {
+	keys := map[string]string{
+		ratelimit.KeyIP:          c.ClientIP(),
+		ratelimit.KeyFingerprint: c.GetHeader("fingerprint"),
+	}
+	if user := GetUser(c); user != nil {
+		keys[ratelimit.KeyEmail] = user.Email
+	}
+
+	if c.Request.Body == nil || c.ContentType() != gin.MIMEJSON {
+		return keys
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekedBody))
+	// The handler reads what we read, then the rest
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return keys
+	}
+
+	var fields rateLimitFields
+	if json.Unmarshal(body, &fields) != nil {
+		return keys
+	}
+	if email := strings.ToLower(strings.TrimSpace(fields.Email)); email != "" && keys[ratelimit.KeyEmail] == "" {
+		keys[ratelimit.KeyEmail] = email
+	}
+	keys[ratelimit.KeySite] = rateLimitSite(fields)
+	return keys
+}
+
+// rateLimitSite returns the domain a request is about
+func rateLimitSite(fields rateLimitFields) string {
+	if parsed, err := url.Parse(fields.PageURL); err == nil && parsed.Hostname() != "" {
+		return strings.ToLower(parsed.Hostname())
+	}
+	if fields.PageID != "" {
+		if parsed, err := url.Parse("https://" + fields.PageID); err == nil {
+			return strings.ToLower(parsed.Hostname())
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"zoomment-server/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+		"auth:email": {Burst: 1, Per: time.Minute},
+	})
+
+	router := gin.New()
+	router.POST("/auth", RateLimit(limiter, ratelimit.RouteAuth), func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.String(http.StatusOK, req.Email)
+	})
+
+	post := func(email string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(`{"email":"`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The handler still gets the body
+	if w := post("a@example.com"); w.Code != http.StatusOK || w.Body.String() != "a@example.com" {
+		t.Fatalf("first request = %d %q", w.Code, w.Body.String())
+	}
+
+	w := post("A@example.com ")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "60" {
+		t.Errorf("Retry-After = %q, want 60", w.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(w.Body.String(), `"code":"rate_limited"`) {
+		t.Errorf("body = %s", w.Body.String())
+	}
+
+	if w := post("b@example.com"); w.Code != http.StatusOK {
+		t.Errorf("other email = %d, want 200", w.Code)
+	}
+}
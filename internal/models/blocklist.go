@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BlocklistEntry keeps someone from commenting on a site
+type BlocklistEntry struct {
+	BaseModel `bson:",inline"`
+
+	// SiteID is nil for global entries, which apply to every site
+	SiteID *primitive.ObjectID `bson:"siteId" json:"siteId"`
+	Type   string              `bson:"type" json:"type"`
+	Value  string              `bson:"value" json:"value"`
+
+	// Mode is what happens to a match (reject/hold/shadow)
+	// Entries created before modes existed have none and reject.
+	Mode string `bson:"mode,omitempty" json:"mode"`
+
+	// Note is the owner's reminder of why the entry exists
+	Note string `bson:"note,omitempty" json:"note,omitempty"`
+
+	// ExpiresAt is when the entry stops applying (nil = never)
+	ExpiresAt *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+
+	// CommentID is the comment the author was banned for, if any
+	CommentID *primitive.ObjectID `bson:"commentId,omitempty" json:"commentId,omitempty"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (b *BlocklistEntry) CollectionName() string {
+	return "blocklist"
+}
+
+// CurrentMode returns the mode, treating entries without one as rejecting
+func (b *BlocklistEntry) CurrentMode() string {
+	if b.Mode == "" {
+		return BlockModeReject
+	}
+	return b.Mode
+}
+
+// Expired reports whether the entry no longer applies at now
+func (b *BlocklistEntry) Expired(now time.Time) bool {
+	return b.ExpiresAt != nil && !now.Before(*b.ExpiresAt)
+}
+
+// Constants for blocklist entry types
+const (
+	// BlockEmail matches the author email (lowercase)
+	BlockEmail = "email"
+	// BlockDomain matches the domain of the author email and its subdomains
+	BlockDomain = "domain"
+	// BlockIP matches an IP address or a CIDR range
+	BlockIP = "ip"
+	// BlockFingerprint matches the browser fingerprint sent by widgets
+	BlockFingerprint = "fingerprint"
+	// BlockWord matches whole words (or a phrase) in the author name and body
+	BlockWord = "word"
+	// BlockRegex matches a case-insensitive regular expression in the author name and body
+	BlockRegex = "regex"
+)
+
+// Constants for blocklist entry modes
+const (
+	// BlockModeReject refuses the comment, vote or reaction
+	BlockModeReject = "reject"
+	// BlockModeHold keeps comments for moderation
+	BlockModeHold = "hold"
+	// BlockModeShadow hides comments from everyone but their author
+	BlockModeShadow = "shadow"
+)
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Comment represents a comment on a page
 type Comment struct {
 	BaseModel `bson:",inline"`
@@ -13,7 +15,14 @@ type Comment struct {
 	Gravatar string `bson:"gravatar" json:"gravatar"`
 
 	// Comment content
-	Body string `bson:"body" json:"body"`
+	// Body is always sanitized HTML. For Markdown comments the original
+	// source is kept in BodySource so edits and exports don't lose it.
+	Body       string `bson:"body" json:"body"`
+	BodyFormat string `bson:"bodyFormat,omitempty" json:"bodyFormat,omitempty"`
+	BodySource string `bson:"bodySource,omitempty" json:"bodySource,omitempty"`
+
+	// EditedAt is set when the author changes the body (previous bodies are kept as revisions)
+	EditedAt *time.Time `bson:"editedAt,omitempty" json:"editedAt,omitempty"`
 
 	// Location info
 	Domain  string `bson:"domain" json:"domain"`
@@ -23,11 +32,96 @@ type Comment struct {
 	// Status
 	IsVerified bool `bson:"isVerified" json:"isVerified"`
 
+	// Moderation status (pending/approved/rejected/spam)
+	// Comments created before moderation existed have no status and count as approved
+	Status string `bson:"status" json:"status"`
+
+	// Soft deletion: DeletedAt is set when the author deletes the comment.
+	// Tombstone is true while the deleted comment still has replies, so it keeps
+	// its place in the thread (rendered without author and body).
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+	Tombstone bool       `bson:"tombstone,omitempty" json:"tombstone,omitempty"`
+
+	// Shadow is set for comments of shadow-banned authors, which only they can see
+	Shadow bool `bson:"shadow,omitempty" json:"-"`
+
 	// Secret for guest deletion (not exposed in JSON)
 	Secret string `bson:"secret" json:"-"`
+
+	// Where the comment was posted from, for spam checks (not exposed in JSON)
+	IP        string `bson:"ip,omitempty" json:"-"`
+	UserAgent string `bson:"userAgent,omitempty" json:"-"`
+
+	// BodyHash identifies the body for duplicate checks
+	BodyHash string `bson:"bodyHash,omitempty" json:"-"`
+
+	// SpamScore (0-1) and the signals behind it, from when the comment was posted
+	SpamScore   float64  `bson:"spamScore,omitempty" json:"-"`
+	SpamReasons []string `bson:"spamReasons,omitempty" json:"-"`
+
+	// Counted is set once a moderator decided the status; only their decisions
+	// count in the reputation of the author
+	Counted bool `bson:"counted,omitempty" json:"-"`
+
+	// TrainedAs is the status the spam classifier learned the comment as
+	TrainedAs string `bson:"trainedAs,omitempty" json:"-"`
+}
+
+// CommentPersonalFields are the stored fields of a comment with the author's
+// personal data or what they wrote. Purging a deleted comment that must stay
+// as a tombstone clears them; keep the list in sync with the fields above.
+var CommentPersonalFields = []string{
+	"author", "email", "gravatar", "body", "bodySource", "secret", "ip", "userAgent", "bodyHash",
 }
 
 // CollectionName returns the MongoDB collection name
 func (c *Comment) CollectionName() string {
 	return "comments"
 }
+
+// CurrentStatus returns the moderation status, treating legacy comments as approved
+func (c *Comment) CurrentStatus() string {
+	if c.Status == "" {
+		return CommentStatusApproved
+	}
+	return c.Status
+}
+
+// Format returns the input format of the body, treating legacy comments as HTML
+func (c *Comment) Format() string {
+	if c.BodyFormat == "" {
+		return BodyFormatHTML
+	}
+	return c.BodyFormat
+}
+
+// Source returns the body as the author wrote it
+func (c *Comment) Source() string {
+	if c.BodyFormat == BodyFormatMarkdown {
+		return c.BodySource
+	}
+	return c.Body
+}
+
+// Constants for comment body formats
+const (
+	BodyFormatHTML     = "html"
+	BodyFormatMarkdown = "markdown"
+)
+
+// Constants for comment moderation status
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusRejected = "rejected"
+	CommentStatusSpam     = "spam"
+)
+
+// IsValidCommentStatus reports whether status is one of the known moderation states
+func IsValidCommentStatus(status string) bool {
+	switch status {
+	case CommentStatusPending, CommentStatusApproved, CommentStatusRejected, CommentStatusSpam:
+		return true
+	}
+	return false
+}
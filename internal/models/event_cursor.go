@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// EventCursor records how far the change feed has read, so a restart
+// continues where it stopped. Change streams use ResumeToken; polling uses
+// Position (the last updatedAt seen) and IDs, the documents seen at Position.
+type EventCursor struct {
+	BaseModel `bson:",inline"`
+
+	Name        string    `bson:"name" json:"name"`
+	ResumeToken bson.Raw  `bson:"resumeToken,omitempty" json:"-"`
+	Position    time.Time `bson:"position,omitempty" json:"position,omitempty"`
+	IDs         []string  `bson:"ids,omitempty" json:"-"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (c *EventCursor) CollectionName() string {
+	return "event_cursors"
+}
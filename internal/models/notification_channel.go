@@ -0,0 +1,53 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationChannel is a chat a site owner wants new comments posted to
+// Which connection fields are used depends on Type.
+type NotificationChannel struct {
+	BaseModel `bson:",inline"`
+
+	SiteID primitive.ObjectID `bson:"siteId" json:"siteId"`
+	Type   string             `bson:"type" json:"type"`
+	Active bool               `bson:"active" json:"active"`
+
+	// WebhookURL is the incoming webhook of a Slack or Discord channel
+	WebhookURL string `bson:"webhookUrl,omitempty" json:"webhookUrl,omitempty"`
+
+	// BotToken and ChatID address a Telegram chat
+	BotToken string `bson:"botToken,omitempty" json:"botToken,omitempty"`
+	ChatID   string `bson:"chatId,omitempty" json:"chatId,omitempty"`
+
+	// HomeserverURL, AccessToken and RoomID address a Matrix room
+	HomeserverURL string `bson:"homeserverUrl,omitempty" json:"homeserverUrl,omitempty"`
+	AccessToken   string `bson:"accessToken,omitempty" json:"accessToken,omitempty"`
+	RoomID        string `bson:"roomId,omitempty" json:"roomId,omitempty"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (n *NotificationChannel) CollectionName() string {
+	return "notification_channels"
+}
+
+// Configured reports whether the fields its type needs are set
+func (n *NotificationChannel) Configured() bool {
+	switch n.Type {
+	case ChannelSlack, ChannelDiscord:
+		return n.WebhookURL != ""
+	case ChannelTelegram:
+		return n.BotToken != "" && n.ChatID != ""
+	case ChannelMatrix:
+		return n.HomeserverURL != "" && n.AccessToken != "" && n.RoomID != ""
+	}
+	return false
+}
+
+// Constants for notification channel types
+const (
+	ChannelSlack    = "slack"
+	ChannelDiscord  = "discord"
+	ChannelTelegram = "telegram"
+	ChannelMatrix   = "matrix"
+)
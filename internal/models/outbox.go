@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// OutboxMessage is an email waiting in the outbox to be sent
+// Messages are written by the mailer and delivered by the outbox workers.
+// IdempotencyKey is unique, so enqueuing the same email twice is a no-op.
+type OutboxMessage struct {
+	BaseModel `bson:",inline"`
+
+	IdempotencyKey string            `bson:"idempotencyKey" json:"idempotencyKey"`
+	From           string            `bson:"from" json:"from"`
+	To             string            `bson:"to" json:"to"`
+	Subject        string            `bson:"subject" json:"subject"`
+	HTML           string            `bson:"html" json:"html"`
+	Text           string            `bson:"text,omitempty" json:"text,omitempty"`
+	Headers        map[string]string `bson:"headers,omitempty" json:"headers,omitempty"`
+
+	Status        string     `bson:"status" json:"status"`
+	Attempts      int        `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time  `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	LockedUntil   *time.Time `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	LastError     string     `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	SentAt        *time.Time `bson:"sentAt,omitempty" json:"sentAt,omitempty"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (m *OutboxMessage) CollectionName() string {
+	return "mail_outbox"
+}
+
+// Constants for outbox message statuses
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSending = "sending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusDead    = "dead"
+)
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// RateLimitBucket is a token bucket shared by every instance
+// UpdatedAt is when Tokens was last computed. Buckets are removed by a TTL
+// index once they would be full again.
+type RateLimitBucket struct {
+	BaseModel `bson:",inline"`
+
+	Key       string    `bson:"key" json:"key"`
+	Tokens    float64   `bson:"tokens" json:"tokens"`
+	Allowed   bool      `bson:"allowed" json:"allowed"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (r *RateLimitBucket) CollectionName() string {
+	return "rate_limits"
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Report is a reader's complaint about a comment
+// Each reader can report a comment once.
+type Report struct {
+	BaseModel `bson:",inline"`
+
+	CommentID primitive.ObjectID `bson:"commentId" json:"commentId"`
+	SiteID    primitive.ObjectID `bson:"siteId" json:"siteId"`
+
+	// Reporter identifies the reader: "user:<id>" or "fingerprint:<fingerprint>"
+	Reporter string `bson:"reporter" json:"-"`
+
+	Reason  string `bson:"reason" json:"reason"`
+	Details string `bson:"details,omitempty" json:"details,omitempty"`
+
+	// ResolvedAt and Resolution are set once a moderator dealt with the comment
+	ResolvedAt *time.Time `bson:"resolvedAt,omitempty" json:"resolvedAt,omitempty"`
+	Resolution string     `bson:"resolution,omitempty" json:"resolution,omitempty"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (r *Report) CollectionName() string {
+	return "reports"
+}
+
+// Constants for report reasons
+const (
+	ReportSpam       = "spam"
+	ReportAbuse      = "abuse"
+	ReportHarassment = "harassment"
+	ReportOffTopic   = "off_topic"
+	ReportOther      = "other"
+)
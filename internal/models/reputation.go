@@ -0,0 +1,40 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reputation is what a site knows about a commenter, tracked by email
+type Reputation struct {
+	BaseModel `bson:",inline"`
+
+	SiteID primitive.ObjectID `bson:"siteId" json:"siteId"`
+	Email  string             `bson:"email" json:"email"`
+
+	// Approved and Rejected count the author's comments moderators approved and
+	// rejected (spam counts as rejected); Reports counts the reports about them
+	// that moderators upheld
+	Approved int `bson:"approved" json:"approved"`
+	Rejected int `bson:"rejected" json:"rejected"`
+	Reports  int `bson:"reports" json:"reports"`
+
+	// Pin is the site owner's own decision about the author (empty = none)
+	Pin string `bson:"pin,omitempty" json:"pin,omitempty"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (r *Reputation) CollectionName() string {
+	return "reputations"
+}
+
+// Score weighs the author's history: each approved comment counts one point,
+// each rejected comment takes two and each report one
+func (r *Reputation) Score() int {
+	return r.Approved - 2*r.Rejected - r.Reports
+}
+
+// Constants for reputation pins
+const (
+	PinTrusted   = "trusted"
+	PinUntrusted = "untrusted"
+)
@@ -0,0 +1,18 @@
+package models
+
+// CommentRevision stores a previous body of an edited comment
+// Site owners can review them to see what a comment said before it was changed
+type CommentRevision struct {
+	BaseModel `bson:",inline"`
+
+	CommentID  string `bson:"commentId" json:"commentId"`
+	Domain     string `bson:"domain" json:"domain"`
+	Body       string `bson:"body" json:"body"`
+	BodyFormat string `bson:"bodyFormat,omitempty" json:"bodyFormat,omitempty"`
+	BodySource string `bson:"bodySource,omitempty" json:"bodySource,omitempty"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (r *CommentRevision) CollectionName() string {
+	return "revisions"
+}
@@ -11,9 +11,102 @@ type Site struct {
 	UserID   primitive.ObjectID `bson:"userId" json:"userId"`
 	Domain   string             `bson:"domain" json:"domain"`
 	Verified bool               `bson:"verified" json:"verified"`
+
+	// ModerationMode controls which new comments wait for approval (empty = none)
+	ModerationMode string `bson:"moderationMode,omitempty" json:"moderationMode"`
+
+	// Sanitizer overrides the default HTML policy for comment bodies (nil = default)
+	Sanitizer *SanitizerSettings `bson:"sanitizer,omitempty" json:"sanitizer,omitempty"`
+
+	// Locale is the default language of emails about this site (empty = English)
+	Locale string `bson:"locale,omitempty" json:"locale"`
+
+	// Spam controls spam checks of new comments (nil = defaults)
+	Spam *SpamSettings `bson:"spam,omitempty" json:"spam,omitempty"`
+
+	// Challenge makes anonymous comments, votes and reactions solve a proof of work (nil = off)
+	Challenge *ChallengeSettings `bson:"challenge,omitempty" json:"challenge,omitempty"`
+
+	// Reports controls what happens to comments readers report (nil = defaults)
+	Reports *ReportSettings `bson:"reports,omitempty" json:"reports,omitempty"`
+
+	// Trust lets returning commenters with a good reputation skip moderation (nil = off)
+	Trust *TrustSettings `bson:"trust,omitempty" json:"trust,omitempty"`
+}
+
+// TrustSettings controls commenter reputation on a site
+type TrustSettings struct {
+	// Enabled publishes comments of trusted authors right away and sends
+	// comments of first-time authors to the moderation queue
+	Enabled bool `bson:"enabled" json:"enabled"`
+
+	// Threshold is the reputation score that makes an author trusted (0 = default)
+	Threshold int `bson:"threshold" json:"threshold"`
+}
+
+// ReportSettings controls reader reports of a site's comments
+type ReportSettings struct {
+	// HideThreshold is how many readers must report a comment before it's
+	// hidden in the moderation queue (0 = default)
+	HideThreshold int `bson:"hideThreshold" json:"hideThreshold"`
+}
+
+// ChallengeSettings controls the proof-of-work gate of a site
+type ChallengeSettings struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+
+	// Difficulty is the number of leading zero bits a solution needs (0 = default)
+	Difficulty int `bson:"difficulty" json:"difficulty"`
+}
+
+// SpamSettings controls how a site's new comments are checked for spam
+type SpamSettings struct {
+	// Disabled turns spam checks off
+	Disabled bool `bson:"disabled" json:"disabled"`
+
+	// Comments scoring at least HoldScore wait for moderation, at least
+	// SpamScore go straight to spam (0 = default)
+	HoldScore float64 `bson:"holdScore" json:"holdScore"`
+	SpamScore float64 `bson:"spamScore" json:"spamScore"`
+
+	// BlockedTerms are words and phrases that mark a comment as likely spam
+	BlockedTerms []string `bson:"blockedTerms" json:"blockedTerms"`
+
+	// AkismetKey turns on Akismet checks with the site's own API key
+	AkismetKey string `bson:"akismetKey,omitempty" json:"akismetKey"`
+
+	// Comment forms sent sooner than MinSubmitSeconds after they were rendered
+	// are spam (0 = default); RequireFormToken also catches forms without a token
+	MinSubmitSeconds int  `bson:"minSubmitSeconds,omitempty" json:"minSubmitSeconds"`
+	RequireFormToken bool `bson:"requireFormToken,omitempty" json:"requireFormToken"`
+}
+
+// SanitizerSettings controls which HTML a site accepts in comment bodies
+type SanitizerSettings struct {
+	// TagSet is "plain", "basic" or "rich"
+	TagSet string `bson:"tagSet" json:"tagSet"`
+
+	// RewriteLinks forces rel="nofollow ugc noopener" and target="_blank" on links
+	RewriteLinks bool `bson:"rewriteLinks" json:"rewriteLinks"`
+
+	// Links to domains outside AllowedLinkDomains (if set) or inside DeniedLinkDomains are removed
+	AllowedLinkDomains []string `bson:"allowedLinkDomains" json:"allowedLinkDomains"`
+	DeniedLinkDomains  []string `bson:"deniedLinkDomains" json:"deniedLinkDomains"`
+
+	AllowImages bool `bson:"allowImages" json:"allowImages"`
 }
 
 // CollectionName returns the MongoDB collection name
 func (s *Site) CollectionName() string {
 	return "sites"
 }
+
+// Constants for site moderation modes
+const (
+	// ModerationNone publishes every comment immediately
+	ModerationNone = "none"
+	// ModerationGuests holds comments from unverified (guest) authors
+	ModerationGuests = "guests"
+	// ModerationAll holds every comment until a moderator approves it
+	ModerationAll = "all"
+)
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SpamCorpus is how many comments a site's spam classifier learned from
+type SpamCorpus struct {
+	BaseModel `bson:",inline"`
+
+	SiteID primitive.ObjectID `bson:"siteId" json:"siteId"`
+	Ham    int                `bson:"ham" json:"ham"`
+	Spam   int                `bson:"spam" json:"spam"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (s *SpamCorpus) CollectionName() string {
+	return "spam_corpora"
+}
+
+// SpamToken is how many ham and spam comments of a site contained a token
+type SpamToken struct {
+	BaseModel `bson:",inline"`
+
+	SiteID primitive.ObjectID `bson:"siteId" json:"siteId"`
+	Token  string             `bson:"token" json:"token"`
+	Ham    int                `bson:"ham" json:"ham"`
+	Spam   int                `bson:"spam" json:"spam"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (s *SpamToken) CollectionName() string {
+	return "spam_tokens"
+}
@@ -0,0 +1,26 @@
+package models
+
+// Subscription stores a reader's email notification preference
+// Preferences can be set for everything, for one site, or for one thread;
+// the most specific one wins. Without any preference, readers are subscribed.
+type Subscription struct {
+	BaseModel `bson:",inline"`
+
+	Email      string `bson:"email" json:"email"`
+	Scope      string `bson:"scope" json:"scope"`
+	Domain     string `bson:"domain,omitempty" json:"domain,omitempty"`
+	ThreadID   string `bson:"threadId,omitempty" json:"threadId,omitempty"`
+	Subscribed bool   `bson:"subscribed" json:"subscribed"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (s *Subscription) CollectionName() string {
+	return "subscriptions"
+}
+
+// Constants for subscription scopes
+const (
+	SubscriptionScopeAll    = "all"
+	SubscriptionScopeSite   = "site"
+	SubscriptionScopeThread = "thread"
+)
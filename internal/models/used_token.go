@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// UsedToken marks a single-use token as spent
+// Kept until the token expires anyway, then removed by a TTL index.
+type UsedToken struct {
+	BaseModel `bson:",inline"`
+
+	TokenID   string    `bson:"tokenId" json:"tokenId"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (u *UsedToken) CollectionName() string {
+	return "used_tokens"
+}
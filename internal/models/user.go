@@ -8,6 +8,10 @@ type User struct {
 	Email      string `bson:"email" json:"email"`
 	Role       int    `bson:"role" json:"role"`
 	IsVerified bool   `bson:"isVerified" json:"isVerified"`
+
+	// Preferences for emails and API messages (empty = not set)
+	Locale   string `bson:"locale,omitempty" json:"locale"`
+	Timezone string `bson:"timezone,omitempty" json:"timezone"`
 }
 
 // NewUser creates a new user with default values
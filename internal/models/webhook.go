@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook is an endpoint a site owner registered to receive events
+// Payloads are signed with Secret so the receiver can check they came from us.
+type Webhook struct {
+	BaseModel `bson:",inline"`
+
+	SiteID primitive.ObjectID `bson:"siteId" json:"siteId"`
+	URL    string             `bson:"url" json:"url"`
+	Secret string             `bson:"secret" json:"secret"`
+	Events []string           `bson:"events" json:"events"`
+	Active bool               `bson:"active" json:"active"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (w *Webhook) CollectionName() string {
+	return "webhooks"
+}
+
+// Wants reports whether the webhook subscribed to an event type
+func (w *Webhook) Wants(eventType string) bool {
+	for _, wanted := range w.Events {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Constants for webhook event types
+const (
+	WebhookEventCommentCreated = "comment.created"
+	WebhookEventCommentDeleted = "comment.deleted"
+	WebhookEventReactionAdded  = "reaction.added"
+	WebhookEventVoteCast       = "vote.cast"
+)
+
+// WebhookEvents lists the event types a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookEventCommentCreated,
+	WebhookEventCommentDeleted,
+	WebhookEventReactionAdded,
+	WebhookEventVoteCast,
+}
+
+// WebhookDelivery is one event sent (or to be sent) to a webhook
+// It is queued like an outbox message and keeps a log of every attempt.
+// IdempotencyKey is unique, so an event seen by several instances is sent once.
+type WebhookDelivery struct {
+	BaseModel `bson:",inline"`
+
+	IdempotencyKey string             `bson:"idempotencyKey" json:"-"`
+	WebhookID      primitive.ObjectID `bson:"webhookId" json:"webhookId"`
+	SiteID         primitive.ObjectID `bson:"siteId" json:"siteId"`
+	EventID        string             `bson:"eventId" json:"eventId"`
+	EventType      string             `bson:"eventType" json:"eventType"`
+	Payload        string             `bson:"payload" json:"payload"`
+
+	// RedeliveryOf is set when the delivery was resent manually
+	RedeliveryOf *primitive.ObjectID `bson:"redeliveryOf,omitempty" json:"redeliveryOf,omitempty"`
+
+	Status        string           `bson:"status" json:"status"`
+	Attempts      int              `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time        `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	LockedUntil   *time.Time       `bson:"lockedUntil,omitempty" json:"-"`
+	DeliveredAt   *time.Time       `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+	Log           []WebhookAttempt `bson:"log" json:"log"`
+}
+
+// CollectionName returns the MongoDB collection name
+func (d *WebhookDelivery) CollectionName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookAttempt records one try to deliver a webhook
+type WebhookAttempt struct {
+	At           time.Time `bson:"at" json:"at"`
+	ResponseCode int       `bson:"responseCode,omitempty" json:"responseCode,omitempty"`
+	ResponseBody string    `bson:"responseBody,omitempty" json:"responseBody,omitempty"`
+	Error        string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMs   int64     `bson:"durationMs" json:"durationMs"`
+}
+
+// Constants for webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySending   = "sending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
@@ -0,0 +1,209 @@
+// Package ratelimit limits how often clients may call expensive or abusable routes
+// Every limit is a token bucket: a client may make Burst requests at once and
+// gets them back evenly over Per. Buckets are kept per route and per key (an
+// IP, a fingerprint, an email or a site), so one noisy client can't use up
+// everyone's allowance.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/logger"
+)
+
+// Key kinds
+const (
+	KeyIP          = "ip"
+	KeyFingerprint = "fingerprint"
+	KeyEmail       = "email"
+	KeySite        = "site"
+)
+
+// Routes with limits
+const (
+	RouteAuth      = "auth"
+	RouteComments  = "comments"
+	RouteVotes     = "votes"
+	RouteReactions = "reactions"
+	RouteReports   = "reports"
+)
+
+// Stores (RATE_LIMIT_STORE)
+const (
+	// StoreMemory keeps buckets in this instance; each replica counts on its own
+	StoreMemory = "memory"
+	// StoreMongo keeps buckets in MongoDB, shared by every replica
+	StoreMongo = "mongo"
+)
+
+// Limit allows Burst requests, refilled evenly over Per
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// rate is how many requests come back per second
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Per.Seconds()
+}
+
+// DefaultLimits are the limits of each route and key kind ("route:kind")
+// Logging in sends an email, so it is the strictest.
+var DefaultLimits = map[string]Limit{
+	"auth:ip":               {Burst: 10, Per: time.Hour},
+	"auth:email":            {Burst: 3, Per: 15 * time.Minute},
+	"comments:ip":           {Burst: 10, Per: 5 * time.Minute},
+	"comments:fingerprint":  {Burst: 10, Per: 5 * time.Minute},
+	"comments:email":        {Burst: 5, Per: 5 * time.Minute},
+	"comments:site":         {Burst: 300, Per: 5 * time.Minute},
+	"votes:ip":              {Burst: 60, Per: time.Minute},
+	"votes:fingerprint":     {Burst: 30, Per: time.Minute},
+	"reactions:ip":          {Burst: 30, Per: time.Minute},
+	"reactions:fingerprint": {Burst: 20, Per: time.Minute},
+	"reports:ip":            {Burst: 20, Per: time.Hour},
+	"reports:fingerprint":   {Burst: 10, Per: time.Hour},
+}
+
+// Store keeps token buckets
+type Store interface {
+	// Take removes a token from the bucket of key
+	// Returns 0 when the request is allowed, otherwise how long until it would be.
+	Take(key string, limit Limit) (time.Duration, error)
+}
+
+// Limiter checks requests against the limits of their route
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+}
+
+// New creates a limiter
+// limits maps "route:kind" to a limit; missing entries aren't limited.
+func New(store Store, limits map[string]Limit) *Limiter {
+	return &Limiter{store: store, limits: limits}
+}
+
+// FromConfig creates the limiter configured by RATE_LIMIT_STORE and RATE_LIMITS
+// Invalid overrides are logged and ignored.
+func FromConfig(cfg *config.Config) *Limiter {
+	limits, err := ParseLimits(DefaultLimits, cfg.RateLimits)
+	if err != nil {
+		logger.Warn("Ignoring invalid RATE_LIMITS: " + err.Error())
+		limits = DefaultLimits
+	}
+
+	var store Store
+	switch cfg.RateLimitStore {
+	case StoreMongo:
+		store = MongoStore{}
+	case StoreMemory, "":
+		store = NewMemoryStore()
+	default:
+		logger.Warn("Unknown RATE_LIMIT_STORE " + cfg.RateLimitStore + ", using memory")
+		store = NewMemoryStore()
+	}
+	return New(store, limits)
+}
+
+// Allow takes a token from the bucket of every key of a request
+// keys maps key kinds to values; empty values are skipped. Returns 0 when
+// the request is allowed, otherwise how long the client should wait.
+// A failing store lets the request through: better some spam than an outage.
+func (l *Limiter) Allow(route string, keys map[string]string) time.Duration {
+	// Sorted so buckets are always taken in the same order
+	kinds := make([]string, 0, len(keys))
+	for kind := range keys {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var wait time.Duration
+	for _, kind := range kinds {
+		value := keys[kind]
+		limit, ok := l.limits[route+":"+kind]
+		if value == "" || !ok {
+			continue
+		}
+
+		retryAfter, err := l.store.Take(route+":"+kind+":"+value, limit)
+		if err != nil {
+			logger.Error(err, "Rate limit check failed")
+			continue
+		}
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+	}
+	return wait
+}
+
+// ParseLimits applies overrides to defaults
+// Overrides look like "comments:ip=20/1m,auth:email=off": a burst and the
+// time it takes to refill, or "off" to remove a limit.
+func ParseLimits(defaults map[string]Limit, overrides string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(defaults))
+	for name, limit := range defaults {
+		limits[name] = limit
+	}
+
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !strings.Contains(name, ":") {
+			return nil, fmt.Errorf("invalid entry %q", entry)
+		}
+		if strings.TrimSpace(value) == "off" {
+			delete(limits, name)
+			continue
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit for %s: %w", name, err)
+		}
+		limits[name] = limit
+	}
+	return limits, nil
+}
+
+// ParseLimit reads a limit like "10/5m" (10 requests, refilled over 5 minutes)
+func ParseLimit(value string) (Limit, error) {
+	burst, per, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q is not requests/duration", value)
+	}
+	n, err := strconv.Atoi(burst)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("%q is not a positive number", burst)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%q is not a positive duration", per)
+	}
+	return Limit{Burst: n, Per: d}, nil
+}
+
+// RetryAfterSeconds rounds a wait up to whole seconds, for the Retry-After header
+func RetryAfterSeconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
+
+// take updates a bucket that had tokens left at updated
+// Returns the tokens left now and the wait (0 when a token was taken).
+func take(tokens float64, updated, now time.Time, limit Limit) (float64, time.Duration) {
+	rate := limit.rate()
+	tokens = math.Min(float64(limit.Burst), tokens+now.Sub(updated).Seconds()*rate)
+	if tokens >= 1 {
+		return tokens - 1, 0
+	}
+	return tokens, time.Duration((1 - tokens) / rate * float64(time.Second))
+}
@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Limit
+		wantErr  bool
+	}{
+		{value: "10/5m", expected: Limit{Burst: 10, Per: 5 * time.Minute}},
+		{value: " 3/1h ", expected: Limit{Burst: 3, Per: time.Hour}},
+		{value: "10", wantErr: true},
+		{value: "0/1m", wantErr: true},
+		{value: "x/1m", wantErr: true},
+		{value: "5/soon", wantErr: true},
+		{value: "5/-1m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := ParseLimit(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseLimit(%q) should fail", tt.value)
+				}
+				return
+			}
+			if err != nil || result != tt.expected {
+				t.Errorf("ParseLimit(%q) = %v, %v; want %v", tt.value, result, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	defaults := map[string]Limit{
+		"auth:email":  {Burst: 3, Per: time.Minute},
+		"comments:ip": {Burst: 10, Per: time.Minute},
+	}
+
+	limits, err := ParseLimits(defaults, "comments:ip=20/1h, auth:email=off,votes:ip=5/1s")
+	if err != nil {
+		t.Fatalf("ParseLimits() error = %v", err)
+	}
+	if _, ok := limits["auth:email"]; ok {
+		t.Error("auth:email should be turned off")
+	}
+	if limits["comments:ip"] != (Limit{Burst: 20, Per: time.Hour}) {
+		t.Errorf("comments:ip = %v", limits["comments:ip"])
+	}
+	if limits["votes:ip"] != (Limit{Burst: 5, Per: time.Second}) {
+		t.Errorf("votes:ip = %v", limits["votes:ip"])
+	}
+	if len(defaults) != 2 || defaults["comments:ip"].Burst != 10 {
+		t.Error("defaults should not change")
+	}
+
+	for _, invalid := range []string{"comments", "comments=5/1m", "comments:ip=5"} {
+		if _, err := ParseLimits(defaults, invalid); err == nil {
+			t.Errorf("ParseLimits(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Burst: 2, Per: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		if wait, _ := store.Take("a", limit); wait != 0 {
+			t.Fatalf("request %d should be allowed, wait = %v", i+1, wait)
+		}
+	}
+	if wait, _ := store.Take("a", limit); wait != 30*time.Second {
+		t.Errorf("third request wait = %v, want 30s", wait)
+	}
+	if wait, _ := store.Take("b", limit); wait != 0 {
+		t.Error("other keys should have their own bucket")
+	}
+
+	// One token comes back every 30 seconds
+	now = now.Add(30 * time.Second)
+	if wait, _ := store.Take("a", limit); wait != 0 {
+		t.Errorf("request after refill should be allowed, wait = %v", wait)
+	}
+	if wait, _ := store.Take("a", limit); wait == 0 {
+		t.Error("bucket should be empty again")
+	}
+
+	// Refilled buckets are forgotten
+	now = now.Add(time.Hour)
+	store.Take("c", limit)
+	if _, ok := store.buckets["a"]; ok {
+		t.Error("full bucket should be swept")
+	}
+}
+
+// failingStore always fails
+type failingStore struct{}
+
+func (failingStore) Take(key string, limit Limit) (time.Duration, error) {
+	return 0, errors.New("database down")
+}
+
+func TestLimiterAllow(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	limiter := New(store, map[string]Limit{
+		"comments:ip":    {Burst: 5, Per: time.Minute},
+		"comments:email": {Burst: 1, Per: time.Minute},
+	})
+
+	keys := map[string]string{KeyIP: "203.0.113.7", KeyEmail: "a@example.com", KeySite: "example.com"}
+	if wait := limiter.Allow(RouteComments, keys); wait != 0 {
+		t.Fatalf("first comment wait = %v", wait)
+	}
+	// The strictest key decides
+	if wait := limiter.Allow(RouteComments, keys); wait != time.Minute {
+		t.Errorf("second comment wait = %v, want 1m", wait)
+	}
+	// Another email from the same IP is still allowed
+	keys[KeyEmail] = "b@example.com"
+	if wait := limiter.Allow(RouteComments, keys); wait != 0 {
+		t.Errorf("other email wait = %v", wait)
+	}
+	// Routes without limits and empty keys are not counted
+	if wait := limiter.Allow(RouteVotes, keys); wait != 0 {
+		t.Errorf("unlimited route wait = %v", wait)
+	}
+	if _, ok := store.buckets["comments:site:example.com"]; ok {
+		t.Error("keys without a limit should not get a bucket")
+	}
+
+	failing := New(failingStore{}, map[string]Limit{"comments:ip": {Burst: 1, Per: time.Minute}})
+	if wait := failing.Allow(RouteComments, keys); wait != 0 {
+		t.Error("a failing store should let requests through")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	if seconds := RetryAfterSeconds(1500 * time.Millisecond); seconds != 2 {
+		t.Errorf("RetryAfterSeconds(1.5s) = %d, want 2", seconds)
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"zoomment-server/internal/repository"
+)
+
+// sweepInterval is how often the memory store forgets full buckets
+const sweepInterval = time.Minute
+
+// bucket is a token bucket of the memory store
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	per     time.Duration
+}
+
+// MemoryStore keeps buckets in memory
+// Each instance counts on its own; use MongoStore behind a load balancer.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(key string, limit Limit) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	var wait time.Duration
+	b.tokens, wait = take(b.tokens, b.updated, now, limit)
+	b.updated = now
+	b.per = limit.Per
+	return wait, nil
+}
+
+// sweep forgets buckets that have refilled; they start full anyway
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.per {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// MongoStore keeps buckets in MongoDB, shared by every instance
+type MongoStore struct{}
+
+// Take implements Store
+func (MongoStore) Take(key string, limit Limit) (time.Duration, error) {
+	tokens, allowed, err := repository.TakeRateLimitToken(key, float64(limit.Burst), limit.rate(), limit.Per)
+	if err != nil || allowed {
+		return 0, err
+	}
+	return time.Duration((1 - tokens) / limit.rate() * float64(time.Second)), nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+// BlockEmail keeps an email address from commenting on a site
+// An existing entry for the address is turned into a permanent ban.
+func BlockEmail(siteID primitive.ObjectID, email string, commentID *primitive.ObjectID) error {
+	now := time.Now()
+	insert := bson.M{"createdAt": now}
+	if commentID != nil {
+		insert["commentId"] = commentID
+	}
+
+	_, err := mgm.Coll(&models.BlocklistEntry{}).UpdateOne(mgm.Ctx(),
+		bson.M{"siteId": siteID, "type": models.BlockEmail, "value": strings.ToLower(email)},
+		bson.M{
+			"$set":         bson.M{"mode": models.BlockModeReject, "updatedAt": now},
+			"$unset":       bson.M{"expiresAt": ""},
+			"$setOnInsert": insert,
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetBlocklist returns the entries of a site (nil = the global entries), newest first
+func GetBlocklist(siteID *primitive.ObjectID) ([]models.BlocklistEntry, error) {
+	entries := []models.BlocklistEntry{}
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	if err := mgm.Coll(&models.BlocklistEntry{}).SimpleFind(&entries, bson.M{"siteId": siteID}, opts); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CountBlocklist returns how many entries a site (nil = the global list) has
+func CountBlocklist(siteID *primitive.ObjectID) (int64, error) {
+	return mgm.Coll(&models.BlocklistEntry{}).CountDocuments(mgm.Ctx(), bson.M{"siteId": siteID})
+}
+
+// ActiveBlocklist returns the entries that apply to a site: its own and the
+// global ones that haven't expired. siteID is nil for unregistered domains,
+// where only the global entries apply.
+func ActiveBlocklist(siteID *primitive.ObjectID) ([]models.BlocklistEntry, error) {
+	scopes := bson.A{nil}
+	if siteID != nil {
+		scopes = append(scopes, *siteID)
+	}
+
+	var entries []models.BlocklistEntry
+	err := mgm.Coll(&models.BlocklistEntry{}).SimpleFind(&entries, bson.M{
+		"siteId": bson.M{"$in": scopes},
+		"$or": bson.A{
+			bson.M{"expiresAt": nil},
+			bson.M{"expiresAt": bson.M{"$gt": time.Now()}},
+		},
+	})
+	return entries, err
+}
+
+// GetBlocklistEntry returns an entry of a site (nil = global; nil if there is none)
+func GetBlocklistEntry(siteID *primitive.ObjectID, entryID primitive.ObjectID) (*models.BlocklistEntry, error) {
+	entry := &models.BlocklistEntry{}
+	err := mgm.Coll(entry).First(bson.M{"_id": entryID, "siteId": siteID}, entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+// GetSiteChannels returns the notification channels of a site, oldest first
+func GetSiteChannels(siteID primitive.ObjectID) ([]models.NotificationChannel, error) {
+	channels := []models.NotificationChannel{}
+	opts := options.Find().SetSort(bson.M{"createdAt": 1})
+	if err := mgm.Coll(&models.NotificationChannel{}).SimpleFind(&channels, bson.M{"siteId": siteID}, opts); err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// GetActiveChannels returns the channels new comments of a site are posted to
+func GetActiveChannels(siteID primitive.ObjectID) ([]models.NotificationChannel, error) {
+	var channels []models.NotificationChannel
+	err := mgm.Coll(&models.NotificationChannel{}).SimpleFind(&channels, bson.M{"siteId": siteID, "active": true})
+	return channels, err
+}
+
+// GetChannel returns a notification channel of a site (nil if there is none)
+func GetChannel(siteID, channelID primitive.ObjectID) (*models.NotificationChannel, error) {
+	channel := &models.NotificationChannel{}
+	err := mgm.Coll(channel).First(bson.M{"_id": channelID, "siteId": siteID}, channel)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
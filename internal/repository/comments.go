@@ -8,6 +8,9 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/constants"
+	"zoomment-server/internal/models"
 )
 
 const (
@@ -24,10 +27,16 @@ type CommentWithReplies struct {
 	Email      string             `bson:"email" json:"-"` // Hidden from JSON
 	Gravatar   string             `bson:"gravatar" json:"gravatar"`
 	Body       string             `bson:"body" json:"body"`
+	BodyFormat string             `bson:"bodyFormat" json:"-"`
+	BodySource string             `bson:"bodySource" json:"-"`
 	Domain     string             `bson:"domain" json:"-"`
 	PageURL    string             `bson:"pageUrl" json:"-"`
 	PageID     string             `bson:"pageId" json:"-"`
 	IsVerified bool               `bson:"isVerified" json:"isVerified"`
+	Status     string             `bson:"status" json:"-"`
+	Shadow     bool               `bson:"shadow" json:"-"`
+	EditedAt   *time.Time         `bson:"editedAt" json:"editedAt,omitempty"`
+	DeletedAt  *time.Time         `bson:"deletedAt" json:"-"`
 	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
 
@@ -47,11 +56,16 @@ type CommentPublicResponse struct {
 	ID           primitive.ObjectID      `json:"_id"`
 	IsOwn        bool                    `json:"isOwn"`
 	Body         string                  `json:"body"`
+	BodyFormat   string                  `json:"bodyFormat,omitempty"`
+	BodySource   string                  `json:"bodySource,omitempty"` // Only for the author's own Markdown comments
 	Author       string                  `json:"author"`
 	Gravatar     string                  `json:"gravatar"`
 	ParentID     *string                 `json:"parentId"`
 	CreatedAt    time.Time               `json:"createdAt"`
+	EditedAt     *time.Time              `json:"editedAt,omitempty"`
 	IsVerified   bool                    `json:"isVerified"`
+	IsDeleted    bool                    `json:"isDeleted,omitempty"`
+	Status       string                  `json:"status,omitempty"` // Only set for the author's own unapproved comments
 	RepliesCount int                     `json:"repliesCount,omitempty"`
 	Replies      []CommentPublicResponse `json:"replies,omitempty"`
 }
@@ -110,8 +124,39 @@ func parseInt(s string) int {
 	return result
 }
 
+// visibleTo restricts a query to comments the viewer is allowed to see:
+// approved comments (legacy comments have no status) plus the viewer's own
+// pending and shadow-banned ones.
+// Deleted comments are only kept when they are tombstones for a thread with replies.
+func visibleTo(matchCondition bson.M, viewerEmail string) bson.M {
+	visibility := bson.A{
+		bson.M{"status": bson.M{"$in": bson.A{models.CommentStatusApproved, nil}}},
+	}
+	if viewerEmail != "" {
+		visibility = append(visibility, bson.M{
+			"email":  viewerEmail,
+			"status": models.CommentStatusPending,
+		}, bson.M{
+			"email":  viewerEmail,
+			"shadow": true,
+		})
+	}
+
+	notDeleted := bson.A{
+		bson.M{"deletedAt": nil},
+		bson.M{"tombstone": true},
+	}
+
+	return bson.M{"$and": bson.A{
+		matchCondition,
+		bson.M{"$or": visibility},
+		bson.M{"$or": notDeleted},
+	}}
+}
+
 // GetPaginatedComments fetches parent comments with pagination and reply counts
-func GetPaginatedComments(pageID, domain string, limit, skip int, sortOrder string) (*PaginatedCommentsResponse, error) {
+// viewerEmail is the authenticated user's email ("" for guests)
+func GetPaginatedComments(pageID, domain, viewerEmail string, limit, skip int, sortOrder string) (*PaginatedCommentsResponse, error) {
 	// Build match condition for parent comments only
 	matchCondition := bson.M{"parentId": nil}
 	if pageID != "" {
@@ -119,6 +164,7 @@ func GetPaginatedComments(pageID, domain string, limit, skip int, sortOrder stri
 	} else if domain != "" {
 		matchCondition["domain"] = domain
 	}
+	filter := visibleTo(matchCondition, viewerEmail)
 
 	// Determine sort order
 	sortDirection := 1 // asc (oldest first) is default
@@ -129,7 +175,7 @@ func GetPaginatedComments(pageID, domain string, limit, skip int, sortOrder stri
 	coll := mgm.Coll(&commentModel{})
 
 	// Get total count
-	total, err := coll.CountDocuments(mgm.Ctx(), matchCondition)
+	total, err := coll.CountDocuments(mgm.Ctx(), filter)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +186,7 @@ func GetPaginatedComments(pageID, domain string, limit, skip int, sortOrder stri
 		SetSkip(int64(skip)).
 		SetLimit(int64(limit))
 
-	cursor, err := coll.Find(mgm.Ctx(), matchCondition, opts)
+	cursor, err := coll.Find(mgm.Ctx(), filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +204,7 @@ func GetPaginatedComments(pageID, domain string, limit, skip int, sortOrder stri
 			commentIds[i] = c.ID.Hex()
 		}
 
-		replyCounts, err := getReplyCountsForComments(commentIds)
+		replyCounts, err := getReplyCountsForComments(commentIds, viewerEmail)
 		if err != nil {
 			return nil, err
 		}
@@ -166,7 +212,7 @@ func GetPaginatedComments(pageID, domain string, limit, skip int, sortOrder stri
 		// Build response with reply counts
 		result := make([]CommentPublicResponse, 0, len(comments))
 		for _, comment := range comments {
-			response := comment.ToPublicResponseWithoutReplies("")
+			response := comment.ToPublicResponseWithoutReplies(viewerEmail)
 			response.RepliesCount = replyCounts[comment.ID.Hex()]
 			result = append(result, response)
 		}
@@ -190,12 +236,12 @@ func GetPaginatedComments(pageID, domain string, limit, skip int, sortOrder stri
 }
 
 // GetRepliesForComment fetches replies for a specific comment with pagination
-func GetRepliesForComment(commentID string, limit, skip int) (*PaginatedRepliesResponse, error) {
+func GetRepliesForComment(commentID, viewerEmail string, limit, skip int) (*PaginatedRepliesResponse, error) {
 	coll := mgm.Coll(&commentModel{})
-	matchCondition := bson.M{"parentId": commentID}
+	filter := visibleTo(bson.M{"parentId": commentID}, viewerEmail)
 
 	// Get total count
-	total, err := coll.CountDocuments(mgm.Ctx(), matchCondition)
+	total, err := coll.CountDocuments(mgm.Ctx(), filter)
 	if err != nil {
 		return nil, err
 	}
@@ -206,7 +252,7 @@ func GetRepliesForComment(commentID string, limit, skip int) (*PaginatedRepliesR
 		SetSkip(int64(skip)).
 		SetLimit(int64(limit))
 
-	cursor, err := coll.Find(mgm.Ctx(), matchCondition, opts)
+	cursor, err := coll.Find(mgm.Ctx(), filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -220,7 +266,7 @@ func GetRepliesForComment(commentID string, limit, skip int) (*PaginatedRepliesR
 	// Build response
 	result := make([]CommentPublicResponse, 0, len(replies))
 	for _, reply := range replies {
-		result = append(result, reply.ToPublicResponseWithoutReplies(""))
+		result = append(result, reply.ToPublicResponseWithoutReplies(viewerEmail))
 	}
 
 	return &PaginatedRepliesResponse{
@@ -233,11 +279,12 @@ func GetRepliesForComment(commentID string, limit, skip int) (*PaginatedRepliesR
 }
 
 // getReplyCountsForComments returns a map of commentId -> replyCount
-func getReplyCountsForComments(commentIds []string) (map[string]int, error) {
+// Only replies visible to the viewer are counted
+func getReplyCountsForComments(commentIds []string, viewerEmail string) (map[string]int, error) {
 	coll := mgm.Coll(&commentModel{})
 
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"parentId": bson.M{"$in": commentIds}}}},
+		{{Key: "$match", Value: visibleTo(bson.M{"parentId": bson.M{"$in": commentIds}}, viewerEmail)}},
 		{{Key: "$group", Value: bson.M{
 			"_id":   "$parentId",
 			"count": bson.M{"$sum": 1},
@@ -269,18 +316,7 @@ func getReplyCountsForComments(commentIds []string) (map[string]int, error) {
 // ToPublicResponse converts CommentWithReplies to public response
 // Matches Node.js getCommentPublicData() output exactly
 func (c *CommentWithReplies) ToPublicResponse(currentUserEmail string) CommentPublicResponse {
-	isOwn := currentUserEmail != "" && currentUserEmail == c.Email
-
-	response := CommentPublicResponse{
-		ID:         c.ID,
-		Author:     c.Author,
-		Gravatar:   c.Gravatar,
-		Body:       c.Body,
-		ParentID:   c.ParentID,
-		IsVerified: c.IsVerified,
-		IsOwn:      isOwn,
-		CreatedAt:  c.CreatedAt,
-	}
+	response := c.ToPublicResponseWithoutReplies(currentUserEmail)
 
 	// Convert replies
 	if len(c.Replies) > 0 {
@@ -295,18 +331,283 @@ func (c *CommentWithReplies) ToPublicResponse(currentUserEmail string) CommentPu
 
 // ToPublicResponseWithoutReplies converts CommentWithReplies to public response without embedding replies
 func (c *CommentWithReplies) ToPublicResponseWithoutReplies(currentUserEmail string) CommentPublicResponse {
+	if c.DeletedAt != nil {
+		return c.toTombstone()
+	}
+
 	isOwn := currentUserEmail != "" && currentUserEmail == c.Email
 
+	// Authors need the Markdown source to edit their comment
+	bodySource := ""
+	if isOwn {
+		bodySource = c.BodySource
+	}
+
 	return CommentPublicResponse{
 		ID:         c.ID,
 		Author:     c.Author,
 		Gravatar:   c.Gravatar,
 		Body:       c.Body,
+		BodyFormat: c.BodyFormat,
+		BodySource: bodySource,
 		ParentID:   c.ParentID,
 		IsVerified: c.IsVerified,
+		Status:     c.privateStatus(),
 		IsOwn:      isOwn,
 		CreatedAt:  c.CreatedAt,
+		EditedAt:   c.EditedAt,
+	}
+}
+
+// PublicComment converts a stored comment to what anonymous readers see
+// Used for live events, which are not addressed to a single viewer.
+func PublicComment(comment *models.Comment) CommentPublicResponse {
+	c := CommentWithReplies{
+		ID:         comment.ID,
+		ParentID:   comment.ParentID,
+		Author:     comment.Author,
+		Email:      comment.Email,
+		Gravatar:   comment.Gravatar,
+		Body:       comment.Body,
+		BodyFormat: comment.Format(),
+		IsVerified: comment.IsVerified,
+		Status:     comment.CurrentStatus(),
+		EditedAt:   comment.EditedAt,
+		DeletedAt:  comment.DeletedAt,
+		CreatedAt:  comment.CreatedAt,
+		UpdatedAt:  comment.UpdatedAt,
+	}
+	return c.ToPublicResponseWithoutReplies("")
+}
+
+// toTombstone renders a deleted comment that still has replies
+// Author and body are stripped so only the thread structure remains
+func (c *CommentWithReplies) toTombstone() CommentPublicResponse {
+	return CommentPublicResponse{
+		ID:        c.ID,
+		Body:      constants.DeletedCommentBody,
+		ParentID:  c.ParentID,
+		IsDeleted: true,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// privateStatus returns the moderation status only when the comment isn't approved,
+// so authors can tell their own comment is still waiting in the queue.
+// Shadow-banned authors must not find out, so their comments look approved.
+func (c *CommentWithReplies) privateStatus() string {
+	if c.Status == models.CommentStatusApproved || c.Shadow {
+		return ""
+	}
+	return c.Status
+}
+
+// SoftDeleteComment marks a comment as deleted
+// A comment that still has replies stays in the thread as a tombstone so the
+// replies aren't orphaned. Deleting the last reply of a tombstone hides it too.
+// Returns whether the comment was kept as a tombstone.
+func SoftDeleteComment(comment *models.Comment) (bool, error) {
+	coll := mgm.Coll(&models.Comment{})
+
+	hasReplies, err := hasLiveReplies(comment.ID.Hex())
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	_, err = coll.UpdateOne(mgm.Ctx(), bson.M{"_id": comment.ID}, bson.M{"$set": bson.M{
+		"deletedAt": now,
+		"tombstone": hasReplies,
+		"updatedAt": now,
+	}})
+	if err != nil {
+		return false, err
+	}
+	comment.DeletedAt = &now
+	comment.Tombstone = hasReplies
+
+	return hasReplies, hideEmptyTombstones(comment.ParentID)
+}
+
+// hideEmptyTombstones walks up the thread and hides tombstones that lost their last reply
+func hideEmptyTombstones(parentID *string) error {
+	coll := mgm.Coll(&models.Comment{})
+
+	for parentID != nil {
+		objID, err := primitive.ObjectIDFromHex(*parentID)
+		if err != nil {
+			return nil
+		}
+
+		parent := &models.Comment{}
+		if err := coll.FindByID(objID, parent); err != nil || !parent.Tombstone {
+			return nil
+		}
+
+		stillNeeded, err := hasLiveReplies(parent.ID.Hex())
+		if err != nil || stillNeeded {
+			return err
+		}
+
+		if _, err := coll.UpdateOne(mgm.Ctx(), bson.M{"_id": parent.ID}, bson.M{"$set": bson.M{"tombstone": false, "updatedAt": time.Now()}}); err != nil {
+			return err
+		}
+		parentID = parent.ParentID
+	}
+
+	return nil
+}
+
+// hasLiveReplies reports whether a comment has replies that are not deleted
+// (tombstones count, since they still hold replies of their own)
+func hasLiveReplies(commentID string) (bool, error) {
+	count, err := mgm.Coll(&models.Comment{}).CountDocuments(mgm.Ctx(), bson.M{
+		"parentId": commentID,
+		"$or": bson.A{
+			bson.M{"deletedAt": nil},
+			bson.M{"tombstone": true},
+		},
+	})
+	return count > 0, err
+}
+
+// PurgeDeletedComments removes comments deleted before the cutoff, along with
+// their revisions, votes and reports
+// Tombstones that still hold replies keep an empty skeleton (no personal data
+// or content) until their replies are gone. Returns the number of removed comments.
+func PurgeDeletedComments(cutoff time.Time) (int64, error) {
+	coll := mgm.Coll(&models.Comment{})
+	expired := bson.M{"deletedAt": bson.M{"$lt": cutoff}}
+
+	var comments []models.Comment
+	if err := coll.SimpleFind(&comments, expired); err != nil {
+		return 0, err
+	}
+	if len(comments) == 0 {
+		return 0, nil
+	}
+
+	plan := planPurge(comments)
+
+	// Revisions contain old bodies, votes and reports who read the comment;
+	// they go in both cases
+	if _, err := mgm.Coll(&models.CommentRevision{}).DeleteMany(mgm.Ctx(), bson.M{"commentId": bson.M{"$in": plan.hexIDs}}); err != nil {
+		return 0, err
+	}
+	if _, err := mgm.Coll(&models.Vote{}).DeleteMany(mgm.Ctx(), bson.M{"commentId": bson.M{"$in": plan.hexIDs}}); err != nil {
+		return 0, err
+	}
+	if _, err := mgm.Coll(&models.Report{}).DeleteMany(mgm.Ctx(), bson.M{"commentId": bson.M{"$in": plan.ids}}); err != nil {
+		return 0, err
+	}
+
+	// Strip personal data from tombstones
+	_, err := coll.UpdateMany(mgm.Ctx(), bson.M{"$and": bson.A{expired, bson.M{"tombstone": true}}}, stripPersonalData())
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := coll.DeleteMany(mgm.Ctx(), bson.M{"_id": bson.M{"$in": plan.leafIDs}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// purgePlan is what purging a batch of deleted comments touches
+type purgePlan struct {
+	ids     []primitive.ObjectID // every comment of the batch
+	hexIDs  []string             // the same, as stored in revisions and votes
+	leafIDs []primitive.ObjectID // comments without replies, which are removed
+}
+
+// planPurge sorts deleted comments into those removed and tombstones kept
+func planPurge(comments []models.Comment) purgePlan {
+	plan := purgePlan{
+		ids:     make([]primitive.ObjectID, 0, len(comments)),
+		hexIDs:  make([]string, 0, len(comments)),
+		leafIDs: make([]primitive.ObjectID, 0, len(comments)),
+	}
+	for _, comment := range comments {
+		plan.ids = append(plan.ids, comment.ID)
+		plan.hexIDs = append(plan.hexIDs, comment.ID.Hex())
+		if !comment.Tombstone {
+			plan.leafIDs = append(plan.leafIDs, comment.ID)
+		}
+	}
+	return plan
+}
+
+// stripPersonalData is the update that empties a tombstone
+func stripPersonalData() bson.M {
+	unset := bson.M{}
+	for _, field := range models.CommentPersonalFields {
+		unset[field] = ""
+	}
+	return bson.M{"$unset": unset, "$set": bson.M{"updatedAt": time.Now()}}
+}
+
+// SearchComments finds the comments matching a filter with a $text search,
+// best matches first
+// hasReplies (nil = either) keeps the comments that have replies, or those
+// that don't.
+func SearchComments(filter bson.M, hasReplies *bool, limit, skip int) ([]models.Comment, int64, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+
+	if hasReplies != nil {
+		// Look for one live reply per matching comment
+		pipeline = append(pipeline,
+			bson.D{{Key: "$lookup", Value: bson.M{
+				"from": mgm.CollName(&models.Comment{}),
+				"let":  bson.M{"id": bson.M{"$toString": "$_id"}},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$parentId", "$$id"}}, "deletedAt": nil}},
+					bson.M{"$limit": 1},
+					bson.M{"$project": bson.M{"_id": 1}},
+				},
+				"as": "replies",
+			}}},
+			bson.D{{Key: "$match", Value: bson.M{"replies.0": bson.M{"$exists": *hasReplies}}}},
+			bson.D{{Key: "$project", Value: bson.M{"replies": 0}}},
+		)
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "createdAt", Value: -1}}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"total":    bson.A{bson.M{"$count": "count"}},
+			"comments": bson.A{bson.M{"$skip": skip}, bson.M{"$limit": limit}},
+		}}},
+	)
+
+	cursor, err := mgm.Coll(&models.Comment{}).Aggregate(mgm.Ctx(), pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(mgm.Ctx())
+
+	var results []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Comments []models.Comment `bson:"comments"`
+	}
+	if err := cursor.All(mgm.Ctx(), &results); err != nil {
+		return nil, 0, err
+	}
+
+	comments := []models.Comment{}
+	var total int64
+	if len(results) > 0 {
+		if results[0].Comments != nil {
+			comments = results[0].Comments
+		}
+		if len(results[0].Total) > 0 {
+			total = results[0].Total[0].Count
+		}
 	}
+	return comments, total, nil
 }
 
 // commentModel is a helper struct for mgm.Coll()
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/constants"
+	"zoomment-server/internal/models"
+)
+
+func TestPlanPurge(t *testing.T) {
+	leaf := models.Comment{}
+	leaf.ID = primitive.NewObjectID()
+	tombstone := models.Comment{Tombstone: true}
+	tombstone.ID = primitive.NewObjectID()
+
+	plan := planPurge([]models.Comment{leaf, tombstone})
+
+	if !reflect.DeepEqual(plan.ids, []primitive.ObjectID{leaf.ID, tombstone.ID}) {
+		t.Errorf("ids = %v, want both comments", plan.ids)
+	}
+	if !reflect.DeepEqual(plan.hexIDs, []string{leaf.ID.Hex(), tombstone.ID.Hex()}) {
+		t.Errorf("hexIDs = %v, want both comments", plan.hexIDs)
+	}
+	if !reflect.DeepEqual(plan.leafIDs, []primitive.ObjectID{leaf.ID}) {
+		t.Errorf("leafIDs = %v, want only the comment without replies", plan.leafIDs)
+	}
+}
+
+func TestStripPersonalData(t *testing.T) {
+	unset, _ := stripPersonalData()["$unset"].(bson.M)
+
+	// Every listed field exists on the comment and is cleared
+	stored := make(map[string]reflect.StructField)
+	commentType := reflect.TypeOf(models.Comment{})
+	for i := 0; i < commentType.NumField(); i++ {
+		field := commentType.Field(i)
+		stored[strings.Split(field.Tag.Get("bson"), ",")[0]] = field
+	}
+	for _, name := range models.CommentPersonalFields {
+		if _, ok := stored[name]; !ok {
+			t.Errorf("CommentPersonalFields lists %q, which isn't a comment field", name)
+		}
+		if _, ok := unset[name]; !ok {
+			t.Errorf("tombstones keep %q", name)
+		}
+	}
+
+	// What places the tombstone in its thread stays
+	for _, name := range []string{"parentId", "domain", "pageId", "deletedAt", "tombstone"} {
+		if _, ok := unset[name]; ok {
+			t.Errorf("tombstones lose %q", name)
+		}
+	}
+}
+
+func TestToTombstone(t *testing.T) {
+	deletedAt := time.Now()
+	parentID := primitive.NewObjectID().Hex()
+	comment := CommentWithReplies{
+		ID:         primitive.NewObjectID(),
+		ParentID:   &parentID,
+		Author:     "Jane",
+		Email:      "jane@example.com",
+		Gravatar:   "abc",
+		Body:       "<p>Secret thoughts</p>",
+		BodySource: "Secret thoughts",
+		IsVerified: true,
+		DeletedAt:  &deletedAt,
+	}
+
+	for _, viewer := range []string{"", "jane@example.com"} {
+		response := comment.ToPublicResponseWithoutReplies(viewer)
+		if !response.IsDeleted || response.Body != constants.DeletedCommentBody {
+			t.Errorf("viewer %q: response = %+v, want a tombstone", viewer, response)
+		}
+		if response.Author != "" || response.Gravatar != "" || response.BodySource != "" || response.IsOwn {
+			t.Errorf("viewer %q: tombstone shows the author: %+v", viewer, response)
+		}
+		if response.ID != comment.ID || response.ParentID != comment.ParentID {
+			t.Errorf("viewer %q: tombstone lost its place in the thread", viewer)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+// GetEventCursor returns the saved position of a change feed (nil if there is none)
+func GetEventCursor(name string) (*models.EventCursor, error) {
+	cursor := &models.EventCursor{}
+	err := mgm.Coll(cursor).First(bson.M{"name": name}, cursor)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+// SaveEventCursor stores the position of a change feed
+func SaveEventCursor(cursor *models.EventCursor) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"resumeToken": cursor.ResumeToken,
+			"position":    cursor.Position,
+			"ids":         cursor.IDs,
+			"updatedAt":   now,
+		},
+		"$setOnInsert": bson.M{"createdAt": now},
+	}
+
+	_, err := mgm.Coll(cursor).UpdateOne(mgm.Ctx(), bson.M{"name": cursor.Name}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// DeleteEventCursor forgets the position of a change feed
+func DeleteEventCursor(name string) error {
+	_, err := mgm.Coll(&models.EventCursor{}).DeleteOne(mgm.Ctx(), bson.M{"name": name})
+	return err
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+// OutboxStats holds the number of outbox messages per status
+type OutboxStats struct {
+	Pending int64
+	Sending int64
+	Sent    int64
+	Dead    int64
+}
+
+// EnqueueMail adds a message to the outbox
+// Returns false when a message with the same idempotency key is already queued.
+func EnqueueMail(msg *models.OutboxMessage) (bool, error) {
+	msg.Status = models.OutboxStatusPending
+	msg.NextAttemptAt = time.Now()
+
+	err := mgm.Coll(msg).Create(msg)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ClaimMail locks the next message that is due for delivery
+// A claim expires after lease, so messages held by a crashed worker are retried.
+// Returns nil when nothing is due.
+func ClaimMail(lease time.Duration) (*models.OutboxMessage, error) {
+	now := time.Now()
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"status": models.OutboxStatusPending, "nextAttemptAt": bson.M{"$lte": now}},
+			bson.M{"status": models.OutboxStatusSending, "lockedUntil": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.OutboxStatusSending,
+			"lockedUntil": now.Add(lease),
+			"updatedAt":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"nextAttemptAt": 1}).
+		SetReturnDocument(options.After)
+
+	msg := &models.OutboxMessage{}
+	err := mgm.Coll(msg).FindOneAndUpdate(mgm.Ctx(), filter, update, opts).Decode(msg)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// MarkMailSent records a successful delivery
+func MarkMailSent(msg *models.OutboxMessage) error {
+	now := time.Now()
+	_, err := mgm.Coll(msg).UpdateByID(mgm.Ctx(), msg.ID, bson.M{
+		"$set":   bson.M{"status": models.OutboxStatusSent, "sentAt": now, "updatedAt": now},
+		"$unset": bson.M{"lockedUntil": "", "lastError": ""},
+	})
+	return err
+}
+
+// MarkMailFailed records a failed delivery
+// The message is retried at nextAttempt, or dead-lettered when dead is true.
+func MarkMailFailed(msg *models.OutboxMessage, sendErr error, nextAttempt time.Time, dead bool) error {
+	status := models.OutboxStatusPending
+	if dead {
+		status = models.OutboxStatusDead
+	}
+
+	_, err := mgm.Coll(msg).UpdateByID(mgm.Ctx(), msg.ID, bson.M{
+		"$set": bson.M{
+			"status":        status,
+			"lastError":     sendErr.Error(),
+			"nextAttemptAt": nextAttempt,
+			"updatedAt":     time.Now(),
+		},
+		"$unset": bson.M{"lockedUntil": ""},
+	})
+	return err
+}
+
+// GetOutboxStats counts outbox messages per status
+func GetOutboxStats() (*OutboxStats, error) {
+	pipeline := bson.A{
+		bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+	}
+
+	cursor, err := mgm.Coll(&models.OutboxMessage{}).Aggregate(mgm.Ctx(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mgm.Ctx())
+
+	var results []struct {
+		ID    string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cursor.All(mgm.Ctx(), &results); err != nil {
+		return nil, err
+	}
+
+	stats := &OutboxStats{}
+	for _, result := range results {
+		switch result.ID {
+		case models.OutboxStatusPending:
+			stats.Pending = result.Count
+		case models.OutboxStatusSending:
+			stats.Sending = result.Count
+		case models.OutboxStatusSent:
+			stats.Sent = result.Count
+		case models.OutboxStatusDead:
+			stats.Dead = result.Count
+		}
+	}
+
+	return stats, nil
+}
+
+// GetFailedMail returns the most recent messages that failed at least once
+// Both dead-lettered messages and messages still waiting for a retry are included.
+func GetFailedMail(limit int64) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := mgm.Coll(&models.OutboxMessage{}).SimpleFind(&messages,
+		bson.M{
+			"status":    bson.M{"$in": bson.A{models.OutboxStatusPending, models.OutboxStatusDead}},
+			"lastError": bson.M{"$exists": true},
+		},
+		options.Find().SetSort(bson.M{"updatedAt": -1}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+// TakeRateLimitToken takes a token from a shared token bucket in one atomic update
+// The bucket starts with burst tokens and gets rate tokens back per second.
+// Returns the tokens left and whether one was taken. Uses the database clock
+// so every instance agrees on the time.
+func TakeRateLimitToken(key string, burst, rate float64, per time.Duration) (float64, bool, error) {
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", burst}},
+				bson.M{"$multiply": bson.A{
+					bson.M{"$divide": bson.A{
+						bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$updatedAt", "$$NOW"}}}},
+						1000,
+					}},
+					rate,
+				}},
+			}}}},
+		}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":    bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"createdAt": bson.M{"$ifNull": bson.A{"$createdAt", "$$NOW"}},
+			"updatedAt": "$$NOW",
+			// A full bucket is the same as none
+			"expiresAt": bson.M{"$add": bson.A{"$$NOW", per.Milliseconds()}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	bucket := &models.RateLimitBucket{}
+	err := mgm.Coll(bucket).FindOneAndUpdate(mgm.Ctx(), bson.M{"key": key}, update, opts).Decode(bucket)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another request created the bucket at the same time
+		err = mgm.Coll(bucket).FindOneAndUpdate(mgm.Ctx(), bson.M{"key": key}, update, opts).Decode(bucket)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return bucket.Tokens, bucket.Allowed, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+// AddReport stores a reader's report
+// Returns false when the reader already reported the comment.
+func AddReport(report *models.Report) (bool, error) {
+	err := mgm.Coll(report).Create(report)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CountOpenReports returns how many readers reported a comment that no
+// moderator has dealt with yet
+func CountOpenReports(commentID primitive.ObjectID) (int64, error) {
+	return mgm.Coll(&models.Report{}).CountDocuments(mgm.Ctx(), bson.M{
+		"commentId":  commentID,
+		"resolvedAt": nil,
+	})
+}
+
+// GetSiteReports returns the open (or resolved) reports of a site, newest first
+func GetSiteReports(siteID primitive.ObjectID, resolved bool, limit int64) ([]models.Report, error) {
+	filter := bson.M{"siteId": siteID, "resolvedAt": nil}
+	if resolved {
+		filter["resolvedAt"] = bson.M{"$ne": nil}
+	}
+
+	reports := []models.Report{}
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit)
+	if err := mgm.Coll(&models.Report{}).SimpleFind(&reports, filter, opts); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// ResolveReports closes the open reports of a comment
+// Returns how many were open.
+func ResolveReports(commentID primitive.ObjectID, resolution string) (int64, error) {
+	now := time.Now()
+	result, err := mgm.Coll(&models.Report{}).UpdateMany(mgm.Ctx(),
+		bson.M{"commentId": commentID, "resolvedAt": nil},
+		bson.M{"$set": bson.M{"resolvedAt": now, "resolution": resolution, "updatedAt": now}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
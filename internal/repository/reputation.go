@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+// GetReputation returns an author's reputation on a site (nil if they have none)
+func GetReputation(siteID primitive.ObjectID, email string) (*models.Reputation, error) {
+	reputation := &models.Reputation{}
+	err := mgm.Coll(reputation).First(bson.M{"siteId": siteID, "email": strings.ToLower(email)}, reputation)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reputation, nil
+}
+
+// GetReputations returns the reputations of a site's authors, most recently
+// changed first. email ("" = all) looks up a single author.
+func GetReputations(siteID primitive.ObjectID, email string, limit, skip int) ([]models.Reputation, int64, error) {
+	filter := bson.M{"siteId": siteID}
+	if email != "" {
+		filter["email"] = strings.ToLower(email)
+	}
+
+	total, err := mgm.Coll(&models.Reputation{}).CountDocuments(mgm.Ctx(), filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reputations := []models.Reputation{}
+	opts := options.Find().SetSort(bson.M{"updatedAt": -1}).SetLimit(int64(limit)).SetSkip(int64(skip))
+	if err := mgm.Coll(&models.Reputation{}).SimpleFind(&reputations, filter, opts); err != nil {
+		return nil, 0, err
+	}
+	return reputations, total, nil
+}
+
+// UpdateReputation adds to the counters of an author's reputation on a site
+// Counters don't go below zero: comments from before reputations existed
+// were never counted.
+func UpdateReputation(siteID primitive.ObjectID, email string, approved, rejected, reports int) error {
+	counter := func(field string, delta int) bson.M {
+		return bson.M{"$max": bson.A{0, bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + field, 0}}, delta}}}}
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"approved":  counter("approved", approved),
+			"rejected":  counter("rejected", rejected),
+			"reports":   counter("reports", reports),
+			"createdAt": bson.M{"$ifNull": bson.A{"$createdAt", "$$NOW"}},
+			"updatedAt": "$$NOW",
+		}}},
+	}
+
+	filter := bson.M{"siteId": siteID, "email": strings.ToLower(email)}
+	opts := options.Update().SetUpsert(true)
+	_, err := mgm.Coll(&models.Reputation{}).UpdateOne(mgm.Ctx(), filter, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another comment of the author was counted at the same time
+		_, err = mgm.Coll(&models.Reputation{}).UpdateOne(mgm.Ctx(), filter, update, opts)
+	}
+	return err
+}
+
+// PinReputation records the site owner's decision about an author
+// An empty pin removes it. Returns the updated reputation.
+func PinReputation(siteID primitive.ObjectID, email, pin string) (*models.Reputation, error) {
+	now := time.Now()
+	set := bson.M{"updatedAt": now}
+	update := bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{"createdAt": now, "approved": 0, "rejected": 0, "reports": 0},
+	}
+	if pin == "" {
+		update["$unset"] = bson.M{"pin": ""}
+	} else {
+		set["pin"] = pin
+	}
+
+	reputation := &models.Reputation{}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := mgm.Coll(reputation).FindOneAndUpdate(mgm.Ctx(),
+		bson.M{"siteId": siteID, "email": strings.ToLower(email)}, update, opts,
+	).Decode(reputation)
+	if err != nil {
+		return nil, err
+	}
+	return reputation, nil
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+// CountRecentComments counts the comments posted on a domain since a time
+// by an email address or (when given) from an IP address
+func CountRecentComments(domain, email, ip string, since time.Time) (int64, error) {
+	authors := bson.A{bson.M{"email": email}}
+	if ip != "" {
+		authors = append(authors, bson.M{"ip": ip})
+	}
+	return mgm.Coll(&models.Comment{}).CountDocuments(mgm.Ctx(), bson.M{
+		"domain":    domain,
+		"createdAt": bson.M{"$gte": since},
+		"$or":       authors,
+	})
+}
+
+// CountDuplicateComments counts the comments posted on a domain since a time with a body hash
+func CountDuplicateComments(domain, bodyHash string, since time.Time) (int64, error) {
+	return mgm.Coll(&models.Comment{}).CountDocuments(mgm.Ctx(), bson.M{
+		"domain":    domain,
+		"bodyHash":  bodyHash,
+		"createdAt": bson.M{"$gte": since},
+	})
+}
+
+// CountApprovedComments counts the approved comments of an email address on a domain
+func CountApprovedComments(domain, email string) (int64, error) {
+	return mgm.Coll(&models.Comment{}).CountDocuments(mgm.Ctx(), bson.M{
+		"domain": domain,
+		"email":  email,
+		// Legacy comments without a status are approved
+		"status": bson.M{"$in": bson.A{models.CommentStatusApproved, nil}},
+	})
+}
+
+// GetSpamCorpus returns how many ham and spam comments a site's classifier learned from
+func GetSpamCorpus(siteID primitive.ObjectID) (int, int, error) {
+	corpus := &models.SpamCorpus{}
+	err := mgm.Coll(corpus).First(bson.M{"siteId": siteID}, corpus)
+	if err == mongo.ErrNoDocuments {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return corpus.Ham, corpus.Spam, nil
+}
+
+// GetSpamTokens returns the counts of the tokens a site's classifier has seen
+func GetSpamTokens(siteID primitive.ObjectID, tokens []string) ([]models.SpamToken, error) {
+	var counts []models.SpamToken
+	err := mgm.Coll(&models.SpamToken{}).SimpleFind(&counts, bson.M{
+		"siteId": siteID,
+		"token":  bson.M{"$in": tokens},
+	})
+	return counts, err
+}
+
+// AddSpamTraining changes the totals of a site's classifier and the counts of tokens
+// ham and spam are the changes (-1 when a comment is unlearned).
+func AddSpamTraining(siteID primitive.ObjectID, tokens []string, ham, spam int) error {
+	now := time.Now()
+	update := bson.M{
+		"$inc":         bson.M{"ham": ham, "spam": spam},
+		"$set":         bson.M{"updatedAt": now},
+		"$setOnInsert": bson.M{"createdAt": now},
+	}
+
+	_, err := mgm.Coll(&models.SpamCorpus{}).UpdateOne(mgm.Ctx(), bson.M{"siteId": siteID}, update, options.Update().SetUpsert(true))
+	if err != nil || len(tokens) == 0 {
+		return err
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(tokens))
+	for _, token := range tokens {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"siteId": siteID, "token": token}).
+			SetUpdate(update).
+			SetUpsert(true))
+	}
+	_, err = mgm.Coll(&models.SpamToken{}).BulkWrite(mgm.Ctx(), writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// SetCommentTrainedAs records what the spam classifier learned a comment as
+// Leaves updatedAt alone: nothing readers see has changed.
+func SetCommentTrainedAs(commentID primitive.ObjectID, as string) error {
+	update := bson.M{"$set": bson.M{"trainedAs": as}}
+	if as == "" {
+		update = bson.M{"$unset": bson.M{"trainedAs": ""}}
+	}
+	_, err := mgm.Coll(&models.Comment{}).UpdateByID(mgm.Ctx(), commentID, update)
+	return err
+}
+
+// DeleteSpamTraining forgets everything a site's classifier learned
+func DeleteSpamTraining(siteID primitive.ObjectID) error {
+	if _, err := mgm.Coll(&models.SpamCorpus{}).DeleteMany(mgm.Ctx(), bson.M{"siteId": siteID}); err != nil {
+		return err
+	}
+	_, err := mgm.Coll(&models.SpamToken{}).DeleteMany(mgm.Ctx(), bson.M{"siteId": siteID})
+	return err
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+// IsSubscribed reports whether an email should get notifications for a thread
+// A thread preference beats a site preference, which beats a global one.
+func IsSubscribed(email, domain, threadID string) (bool, error) {
+	var preferences []models.Subscription
+	err := mgm.Coll(&models.Subscription{}).SimpleFind(&preferences, bson.M{
+		"email": email,
+		"$or": bson.A{
+			bson.M{"scope": models.SubscriptionScopeAll},
+			bson.M{"scope": models.SubscriptionScopeSite, "domain": domain},
+			bson.M{"scope": models.SubscriptionScopeThread, "threadId": threadID},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	rank := map[string]int{
+		models.SubscriptionScopeAll:    1,
+		models.SubscriptionScopeSite:   2,
+		models.SubscriptionScopeThread: 3,
+	}
+
+	subscribed, best := true, 0
+	for _, preference := range preferences {
+		if rank[preference.Scope] > best {
+			subscribed, best = preference.Subscribed, rank[preference.Scope]
+		}
+	}
+
+	return subscribed, nil
+}
+
+// SetSubscription stores a preference for an email
+// domain is used for the site scope and threadID for the thread scope.
+func SetSubscription(email, scope, domain, threadID string, subscribed bool) error {
+	filter := bson.M{"email": email, "scope": scope}
+	switch scope {
+	case models.SubscriptionScopeSite:
+		filter["domain"] = domain
+	case models.SubscriptionScopeThread:
+		filter["domain"] = domain
+		filter["threadId"] = threadID
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set":         bson.M{"subscribed": subscribed, "updatedAt": now},
+		"$setOnInsert": bson.M{"createdAt": now},
+	}
+
+	_, err := mgm.Coll(&models.Subscription{}).UpdateOne(mgm.Ctx(), filter, update, options.Update().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"zoomment-server/internal/models"
+)
+
+// UseToken marks a single-use token as spent
+// Returns false when it was already used. The unique index on tokenId makes
+// this safe when a link is opened twice at the same time.
+func UseToken(tokenID string, expiresAt time.Time) (bool, error) {
+	err := mgm.Coll(&models.UsedToken{}).Create(&models.UsedToken{TokenID: tokenID, ExpiresAt: expiresAt})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseToken makes a spent token usable again (when acting on it failed)
+func ReleaseToken(tokenID string) error {
+	_, err := mgm.Coll(&models.UsedToken{}).DeleteOne(mgm.Ctx(), bson.M{"tokenId": tokenID})
+	return err
+}
+
+// IsTokenUsed reports whether a single-use token was spent
+func IsTokenUsed(tokenID string) (bool, error) {
+	count, err := mgm.Coll(&models.UsedToken{}).CountDocuments(mgm.Ctx(), bson.M{"tokenId": tokenID})
+	return count > 0, err
+}
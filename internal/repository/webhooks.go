@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/models"
+)
+
+// webhookLogSize is how many attempts a delivery keeps in its log
+const webhookLogSize = 20
+
+// GetSiteWebhooks returns the webhooks of a site, oldest first
+func GetSiteWebhooks(siteID primitive.ObjectID) ([]models.Webhook, error) {
+	webhooks := []models.Webhook{}
+	opts := options.Find().SetSort(bson.M{"createdAt": 1})
+	if err := mgm.Coll(&models.Webhook{}).SimpleFind(&webhooks, bson.M{"siteId": siteID}, opts); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetActiveWebhooks returns the active webhooks of a site that want an event type
+func GetActiveWebhooks(siteID primitive.ObjectID, eventType string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := mgm.Coll(&models.Webhook{}).SimpleFind(&webhooks, bson.M{
+		"siteId": siteID,
+		"active": true,
+		"events": eventType,
+	})
+	return webhooks, err
+}
+
+// GetWebhook returns a webhook of a site (nil if there is none)
+func GetWebhook(siteID, webhookID primitive.ObjectID) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	err := mgm.Coll(webhook).First(bson.M{"_id": webhookID, "siteId": siteID}, webhook)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+// Returns false when the site has no such webhook.
+func DeleteWebhook(siteID, webhookID primitive.ObjectID) (bool, error) {
+	result, err := mgm.Coll(&models.Webhook{}).DeleteOne(mgm.Ctx(), bson.M{"_id": webhookID, "siteId": siteID})
+	if err != nil || result.DeletedCount == 0 {
+		return false, err
+	}
+
+	_, err = mgm.Coll(&models.WebhookDelivery{}).DeleteMany(mgm.Ctx(), bson.M{"webhookId": webhookID})
+	return true, err
+}
+
+// EnqueueWebhookDelivery queues an event for a webhook
+// Returns false when a delivery with the same idempotency key already exists.
+func EnqueueWebhookDelivery(delivery *models.WebhookDelivery) (bool, error) {
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.NextAttemptAt = time.Now()
+	if delivery.Log == nil {
+		delivery.Log = []models.WebhookAttempt{}
+	}
+
+	err := mgm.Coll(delivery).Create(delivery)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ClaimWebhookDelivery locks the next delivery that is due
+// Works like ClaimMail. Returns nil when nothing is due.
+func ClaimWebhookDelivery(lease time.Duration) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"status": models.WebhookDeliveryPending, "nextAttemptAt": bson.M{"$lte": now}},
+			bson.M{"status": models.WebhookDeliverySending, "lockedUntil": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.WebhookDeliverySending,
+			"lockedUntil": now.Add(lease),
+			"updatedAt":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"nextAttemptAt": 1}).
+		SetReturnDocument(options.After)
+
+	delivery := &models.WebhookDelivery{}
+	err := mgm.Coll(delivery).FindOneAndUpdate(mgm.Ctx(), filter, update, opts).Decode(delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// RecordWebhookAttempt logs an attempt and sets the delivery's new status
+// nextAttempt is only used when the status is pending (a retry).
+func RecordWebhookAttempt(delivery *models.WebhookDelivery, attempt models.WebhookAttempt, status string, nextAttempt time.Time) error {
+	set := bson.M{"status": status, "updatedAt": time.Now()}
+	switch status {
+	case models.WebhookDeliveryPending:
+		set["nextAttemptAt"] = nextAttempt
+	case models.WebhookDeliveryDelivered:
+		set["deliveredAt"] = attempt.At
+	}
+
+	_, err := mgm.Coll(delivery).UpdateByID(mgm.Ctx(), delivery.ID, bson.M{
+		"$set":   set,
+		"$unset": bson.M{"lockedUntil": ""},
+		"$push": bson.M{"log": bson.M{
+			"$each":  bson.A{attempt},
+			"$slice": -webhookLogSize,
+		}},
+	})
+	return err
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook, newest first
+func GetWebhookDeliveries(webhookID primitive.ObjectID, limit int64) ([]models.WebhookDelivery, error) {
+	deliveries := []models.WebhookDelivery{}
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit)
+	if err := mgm.Coll(&models.WebhookDelivery{}).SimpleFind(&deliveries, bson.M{"webhookId": webhookID}, opts); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDelivery returns a delivery of a webhook (nil if there is none)
+func GetWebhookDelivery(webhookID, deliveryID primitive.ObjectID) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	err := mgm.Coll(delivery).First(bson.M{"_id": deliveryID, "webhookId": webhookID}, delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
@@ -6,6 +6,7 @@ import (
 	"zoomment-server/internal/config"
 	"zoomment-server/internal/handlers"
 	"zoomment-server/internal/middleware"
+	"zoomment-server/internal/ratelimit"
 )
 
 // Setup configures all API routes
@@ -16,52 +17,87 @@ func Setup(router *gin.Engine, cfg *config.Config) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Limits for routes that send emails or take writes from anyone
+	limiter := ratelimit.FromConfig(cfg)
+
 	// API routes group
 	api := router.Group("/api")
 	{
 		// Comments routes
-		setupCommentRoutes(api, cfg)
+		setupCommentRoutes(api, cfg, limiter)
 
 		// Users routes
-		setupUserRoutes(api, cfg)
+		setupUserRoutes(api, cfg, limiter)
 
 		// Sites routes
 		setupSiteRoutes(api, cfg)
 
 		// Reactions routes
-		setupReactionRoutes(api)
+		setupReactionRoutes(api, cfg, limiter)
 
 		// Visitors routes
 		setupVisitorRoutes(api)
 
 		// Votes routes
-		setupVoteRoutes(api)
+		setupVoteRoutes(api, cfg, limiter)
+
+		// Email subscription routes
+		setupSubscriptionRoutes(api, cfg)
+
+		// Quick moderation links
+		setupModerationRoutes(api, cfg)
+
+		// Proof-of-work challenges for guests
+		api.GET("/challenge", handlers.GetChallenge(cfg))
+
+		// Instance administration routes
+		setupAdminRoutes(api)
 	}
 }
 
 // setupCommentRoutes configures /api/comments routes
-func setupCommentRoutes(api *gin.RouterGroup, cfg *config.Config) {
+func setupCommentRoutes(api *gin.RouterGroup, cfg *config.Config, limiter *ratelimit.Limiter) {
 	comments := api.Group("/comments")
 	{
+		addComment := handlers.AddComment(cfg)
+		limit := middleware.RateLimit(limiter, ratelimit.RouteComments)
+
 		// Register both with and without trailing slash since RedirectTrailingSlash is disabled
 		comments.GET("/", handlers.ListComments)
 		comments.GET("", handlers.ListComments)
-		comments.POST("/", handlers.AddComment(cfg))
-		comments.POST("", handlers.AddComment(cfg))
+		comments.POST("/", limit, addComment)
+		comments.POST("", limit, addComment)
+		comments.PATCH("/:id", handlers.EditComment(cfg))
 		comments.DELETE("/:id", handlers.DeleteComment)
+		// Signed form render time for the bot trap
+		comments.GET("/form-token", handlers.GetFormToken(cfg))
+		// Live events for a page (Server-Sent Events)
+		comments.GET("/stream", handlers.StreamComments)
+		// Live events, presence, comments and votes for widgets (WebSocket)
+		comments.GET("/ws", handlers.CommentsSocket(cfg, limiter))
 		// Load more replies for a specific comment
 		comments.GET("/:commentId/replies", handlers.ListReplies)
+		// Readers report abusive comments
+		comments.POST("/:id/report", middleware.RateLimit(limiter, ratelimit.RouteReports), handlers.ReportComment(cfg))
+		// Previous bodies of an edited comment (site owner only)
+		comments.GET("/:commentId/revisions", middleware.Access("admin"), handlers.ListCommentRevisions)
 		// Node.js uses access('admin') for this route
 		comments.GET("/sites/:siteId", middleware.Access("admin"), handlers.ListCommentsBySite)
+		// Full-text search over a site's comments
+		comments.GET("/sites/:siteId/search", middleware.Access("admin"), handlers.SearchComments)
+		// Approve/reject comments from the moderation queue in bulk
+		comments.POST("/sites/:siteId/moderate", middleware.Access("admin"), handlers.ModerateComments(cfg))
 	}
 }
 
 // setupUserRoutes configures /api/users routes
-func setupUserRoutes(api *gin.RouterGroup, cfg *config.Config) {
+func setupUserRoutes(api *gin.RouterGroup, cfg *config.Config, limiter *ratelimit.Limiter) {
 	users := api.Group("/users")
 	{
-		users.POST("/auth", handlers.AuthUser(cfg))
+		// Every request sends an email
+		users.POST("/auth", middleware.RateLimit(limiter, ratelimit.RouteAuth), handlers.AuthUser(cfg))
 		users.GET("/profile", middleware.Access(), handlers.GetProfile)
+		users.PATCH("/profile", middleware.Access(), handlers.UpdateProfile)
 		users.DELETE("/", middleware.Access(), handlers.DeleteUser)
 	}
 }
@@ -75,19 +111,46 @@ func setupSiteRoutes(api *gin.RouterGroup, cfg *config.Config) {
 		sites.GET("", middleware.Access("admin"), handlers.ListSites)
 		sites.POST("/", middleware.Access("admin"), handlers.AddSite(cfg))
 		sites.POST("", middleware.Access("admin"), handlers.AddSite(cfg))
+		sites.PATCH("/:id", middleware.Access("admin"), handlers.UpdateSite)
 		sites.DELETE("/:id", middleware.Access("admin"), handlers.DeleteSite)
+		// Outgoing webhooks and their delivery log
+		sites.GET("/:id/webhooks", middleware.Access("admin"), handlers.ListWebhooks)
+		sites.POST("/:id/webhooks", middleware.Access("admin"), handlers.AddWebhook)
+		sites.PATCH("/:id/webhooks/:webhookId", middleware.Access("admin"), handlers.UpdateWebhook)
+		sites.DELETE("/:id/webhooks/:webhookId", middleware.Access("admin"), handlers.DeleteWebhook)
+		sites.GET("/:id/webhooks/:webhookId/deliveries", middleware.Access("admin"), handlers.ListWebhookDeliveries)
+		sites.POST("/:id/webhooks/:webhookId/deliveries/:deliveryId/redeliver", middleware.Access("admin"), handlers.RedeliverWebhook)
+		// Slack, Discord, Telegram and Matrix chats that get new comments
+		sites.GET("/:id/channels", middleware.Access("admin"), handlers.ListChannels)
+		sites.POST("/:id/channels", middleware.Access("admin"), handlers.AddChannel)
+		sites.PATCH("/:id/channels/:channelId", middleware.Access("admin"), handlers.UpdateChannel)
+		sites.DELETE("/:id/channels/:channelId", middleware.Access("admin"), handlers.DeleteChannel)
+		// Comments reported by readers
+		sites.GET("/:id/reports", middleware.Access("admin"), handlers.ListReports)
+		sites.POST("/:id/reports/resolve", middleware.Access("admin"), handlers.ResolveReports(cfg))
+		// Reputation of commenters, and owners' trust decisions about them
+		sites.GET("/:id/commenters", middleware.Access("admin"), handlers.ListCommenters)
+		sites.POST("/:id/commenters/pin", middleware.Access("admin"), handlers.PinCommenter)
+		// Emails, domains, IPs, fingerprints and words kept from posting
+		sites.GET("/:id/blocklist", middleware.Access("admin"), handlers.ListBlocklist)
+		sites.POST("/:id/blocklist", middleware.Access("admin"), handlers.AddBlocklistEntry)
+		sites.PATCH("/:id/blocklist/:entryId", middleware.Access("admin"), handlers.UpdateBlocklistEntry)
+		sites.DELETE("/:id/blocklist/:entryId", middleware.Access("admin"), handlers.DeleteBlocklistEntry)
 	}
 }
 
 // setupReactionRoutes configures /api/reactions routes
-func setupReactionRoutes(api *gin.RouterGroup) {
+func setupReactionRoutes(api *gin.RouterGroup, cfg *config.Config, limiter *ratelimit.Limiter) {
 	reactions := api.Group("/reactions")
 	{
+		limit := middleware.RateLimit(limiter, ratelimit.RouteReactions)
+
 		// Register both with and without trailing slash since RedirectTrailingSlash is disabled
 		reactions.GET("/", handlers.ListReactions)
 		reactions.GET("", handlers.ListReactions)
-		reactions.POST("/", handlers.AddReaction)
-		reactions.POST("", handlers.AddReaction)
+		addReaction := handlers.AddReaction(cfg)
+		reactions.POST("/", limit, addReaction)
+		reactions.POST("", limit, addReaction)
 	}
 }
 
@@ -105,15 +168,47 @@ func setupVisitorRoutes(api *gin.RouterGroup) {
 }
 
 // setupVoteRoutes configures /api/votes routes
-func setupVoteRoutes(api *gin.RouterGroup) {
+func setupVoteRoutes(api *gin.RouterGroup, cfg *config.Config, limiter *ratelimit.Limiter) {
 	votes := api.Group("/votes")
 	{
+		limit := middleware.RateLimit(limiter, ratelimit.RouteVotes)
+
 		// Register both with and without trailing slash since RedirectTrailingSlash is disabled
-		votes.POST("/", handlers.Vote)
-		votes.POST("", handlers.Vote)
+		vote := handlers.Vote(cfg)
+		votes.POST("/", limit, vote)
+		votes.POST("", limit, vote)
 		votes.GET("/", handlers.GetVotesBulk)
 		votes.GET("", handlers.GetVotesBulk)
 		votes.GET("/:commentId", handlers.GetVote)
 	}
 }
 
+// setupSubscriptionRoutes configures /api/subscriptions routes
+func setupSubscriptionRoutes(api *gin.RouterGroup, cfg *config.Config) {
+	subscriptions := api.Group("/subscriptions")
+	{
+		// Signed links from reply notification emails (no session needed)
+		subscriptions.GET("/unsubscribe", handlers.ShowUnsubscribe(cfg))
+		subscriptions.POST("/unsubscribe", handlers.Unsubscribe(cfg))
+	}
+}
+
+// setupModerationRoutes configures /api/moderate routes
+func setupModerationRoutes(api *gin.RouterGroup, cfg *config.Config) {
+	// Signed single-use links from notification emails and chats (no session needed)
+	api.GET("/moderate/:token", handlers.ShowModeration(cfg))
+	api.POST("/moderate/:token", handlers.ApplyModeration(cfg))
+}
+
+// setupAdminRoutes configures /api/admin routes (superadmin only)
+func setupAdminRoutes(api *gin.RouterGroup) {
+	admin := api.Group("/admin", middleware.Access("superadmin"))
+	{
+		admin.GET("/outbox", handlers.GetOutbox)
+		// Blocklist entries that apply to every site
+		admin.GET("/blocklist", handlers.ListBlocklist)
+		admin.POST("/blocklist", handlers.AddBlocklistEntry)
+		admin.PATCH("/blocklist/:entryId", handlers.UpdateBlocklistEntry)
+		admin.DELETE("/blocklist/:entryId", handlers.DeleteBlocklistEntry)
+	}
+}
@@ -0,0 +1,198 @@
+// Package blocklist matches comments, votes and reactions against the
+// blocklist entries of a site and the global ones
+package blocklist
+
+import (
+	"errors"
+	"net/netip"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"zoomment-server/internal/models"
+)
+
+// MaxPatternLength bounds regex entries (RE2 runs in linear time, but big
+// patterns still cost memory)
+const MaxPatternLength = 200
+
+// ErrInvalidValue is returned by Normalize for values that can't be matched
+var ErrInvalidValue = errors.New("invalid blocklist value")
+
+// Subject is who wrote something and what they wrote
+// Empty fields don't match anything.
+type Subject struct {
+	Email       string
+	IP          string
+	Fingerprint string
+	// Text is the author name and body of a comment
+	Text string
+}
+
+// Normalize checks a value for an entry type and returns it the way it's matched
+// Emails, domains and words are lowercased, IP ranges are masked and
+// regular expressions must compile.
+func Normalize(entryType, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", ErrInvalidValue
+	}
+
+	switch entryType {
+	case models.BlockEmail:
+		value = strings.ToLower(value)
+		if !strings.Contains(value, "@") {
+			return "", ErrInvalidValue
+		}
+		return value, nil
+	case models.BlockDomain:
+		value = strings.ToLower(strings.TrimPrefix(value, "@"))
+		if value == "" || strings.ContainsAny(value, "@/ ") {
+			return "", ErrInvalidValue
+		}
+		return value, nil
+	case models.BlockIP:
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return "", ErrInvalidValue
+			}
+			return prefix.Masked().String(), nil
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return "", ErrInvalidValue
+		}
+		return addr.Unmap().String(), nil
+	case models.BlockFingerprint:
+		return value, nil
+	case models.BlockWord:
+		phrase := words(value)
+		if phrase == "" {
+			return "", ErrInvalidValue
+		}
+		return phrase, nil
+	case models.BlockRegex:
+		if len(value) > MaxPatternLength {
+			return "", ErrInvalidValue
+		}
+		if _, err := compile(value); err != nil {
+			return "", ErrInvalidValue
+		}
+		return value, nil
+	}
+	return "", ErrInvalidValue
+}
+
+// Match returns the matching entry with the strictest mode (nil if none does)
+// Entries that expired are skipped.
+func Match(entries []models.BlocklistEntry, subject Subject, now time.Time) *models.BlocklistEntry {
+	var text string
+	if subject.Text != "" {
+		text = " " + words(subject.Text) + " "
+	}
+
+	var found *models.BlocklistEntry
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Expired(now) || !matches(entry, subject, text) {
+			continue
+		}
+		if found == nil || strictness[entry.CurrentMode()] > strictness[found.CurrentMode()] {
+			found = entry
+		}
+	}
+	return found
+}
+
+// strictness orders the modes: a rejecting entry wins over a hiding one
+var strictness = map[string]int{
+	models.BlockModeHold:   1,
+	models.BlockModeShadow: 2,
+	models.BlockModeReject: 3,
+}
+
+// matches reports whether an entry matches a subject
+// text is the subject's text as words, padded with spaces.
+func matches(entry *models.BlocklistEntry, subject Subject, text string) bool {
+	switch entry.Type {
+	case models.BlockEmail:
+		return subject.Email != "" && strings.ToLower(subject.Email) == entry.Value
+	case models.BlockDomain:
+		at := strings.LastIndex(subject.Email, "@")
+		if at < 0 {
+			return false
+		}
+		domain := strings.ToLower(subject.Email[at+1:])
+		return domain == entry.Value || strings.HasSuffix(domain, "."+entry.Value)
+	case models.BlockIP:
+		return ipMatches(entry.Value, subject.IP)
+	case models.BlockFingerprint:
+		return subject.Fingerprint != "" && subject.Fingerprint == entry.Value
+	case models.BlockWord:
+		return text != "" && strings.Contains(text, " "+entry.Value+" ")
+	case models.BlockRegex:
+		if subject.Text == "" {
+			return false
+		}
+		re, err := compile(entry.Value)
+		return err == nil && re.MatchString(subject.Text)
+	}
+	return false
+}
+
+// ipMatches reports whether ip is the address or in the range of value
+func ipMatches(value, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return err == nil && prefix.Contains(addr)
+	}
+	blocked, err := netip.ParseAddr(value)
+	return err == nil && blocked == addr
+}
+
+// words lowercases text and joins its words with single spaces, so word
+// entries match whole words in any script
+func words(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// maxCachedPatterns bounds the cache of compiled patterns
+const maxCachedPatterns = 1000
+
+// Compiled regular expressions, by pattern
+var (
+	patternsMu sync.Mutex
+	patterns   = make(map[string]*regexp.Regexp)
+)
+
+// compile compiles a case-insensitive pattern, caching the result
+func compile(pattern string) (*regexp.Regexp, error) {
+	patternsMu.Lock()
+	defer patternsMu.Unlock()
+
+	if re, ok := patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, err
+	}
+	// Patterns of deleted entries would pile up otherwise
+	if len(patterns) >= maxCachedPatterns {
+		patterns = make(map[string]*regexp.Regexp)
+	}
+	patterns[pattern] = re
+	return re, nil
+}
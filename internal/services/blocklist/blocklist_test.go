@@ -0,0 +1,98 @@
+package blocklist
+
+import (
+	"testing"
+	"time"
+
+	"zoomment-server/internal/models"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		entryType string
+		value     string
+		expected  string
+		valid     bool
+	}{
+		{entryType: models.BlockEmail, value: " Troll@Example.com ", expected: "troll@example.com", valid: true},
+		{entryType: models.BlockEmail, value: "example.com", valid: false},
+		{entryType: models.BlockDomain, value: "@Spam.Example", expected: "spam.example", valid: true},
+		{entryType: models.BlockDomain, value: "a@b.com", valid: false},
+		{entryType: models.BlockIP, value: "203.0.113.7", expected: "203.0.113.7", valid: true},
+		{entryType: models.BlockIP, value: "203.0.113.7/24", expected: "203.0.113.0/24", valid: true},
+		{entryType: models.BlockIP, value: "::ffff:203.0.113.7", expected: "203.0.113.7", valid: true},
+		{entryType: models.BlockIP, value: "2001:db8::1/32", expected: "2001:db8::/32", valid: true},
+		{entryType: models.BlockIP, value: "203.0.113", valid: false},
+		{entryType: models.BlockFingerprint, value: "abc123", expected: "abc123", valid: true},
+		{entryType: models.BlockWord, value: "  Cheap,  PILLS ", expected: "cheap pills", valid: true},
+		{entryType: models.BlockWord, value: "!!!", valid: false},
+		{entryType: models.BlockRegex, value: `viagr[a@]`, expected: `viagr[a@]`, valid: true},
+		{entryType: models.BlockRegex, value: `(unclosed`, valid: false},
+		{entryType: "unknown", value: "x", valid: false},
+		{entryType: models.BlockEmail, value: "   ", valid: false},
+	}
+
+	for _, tt := range tests {
+		result, err := Normalize(tt.entryType, tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("Normalize(%q, %q) error = %v, want valid = %v", tt.entryType, tt.value, err, tt.valid)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("Normalize(%q, %q) = %q, want %q", tt.entryType, tt.value, result, tt.expected)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	entries := []models.BlocklistEntry{
+		{Type: models.BlockEmail, Value: "troll@example.com"},
+		{Type: models.BlockDomain, Value: "spam.example", Mode: models.BlockModeHold},
+		{Type: models.BlockIP, Value: "203.0.113.0/24", Mode: models.BlockModeShadow},
+		{Type: models.BlockIP, Value: "2001:db8::1", Mode: models.BlockModeReject},
+		{Type: models.BlockFingerprint, Value: "fp-1", Mode: models.BlockModeShadow, ExpiresAt: &future},
+		{Type: models.BlockFingerprint, Value: "fp-2", Mode: models.BlockModeReject, ExpiresAt: &past},
+		{Type: models.BlockWord, Value: "cheap pills", Mode: models.BlockModeHold},
+		{Type: models.BlockWord, Value: "дурак", Mode: models.BlockModeHold},
+		{Type: models.BlockRegex, Value: `viagr[a@]`, Mode: models.BlockModeShadow},
+	}
+
+	tests := []struct {
+		name     string
+		subject  Subject
+		expected string // mode of the matching entry, "" for none
+	}{
+		{name: "nobody", subject: Subject{Email: "reader@example.com", IP: "198.51.100.1", Text: "Nice post"}, expected: ""},
+		{name: "email", subject: Subject{Email: "Troll@Example.com"}, expected: models.BlockModeReject},
+		{name: "email domain", subject: Subject{Email: "a@spam.example"}, expected: models.BlockModeHold},
+		{name: "email subdomain", subject: Subject{Email: "a@mail.spam.example"}, expected: models.BlockModeHold},
+		{name: "similar domain", subject: Subject{Email: "a@notspam.example"}, expected: ""},
+		{name: "ip range", subject: Subject{IP: "203.0.113.99"}, expected: models.BlockModeShadow},
+		{name: "mapped ip", subject: Subject{IP: "::ffff:203.0.113.99"}, expected: models.BlockModeShadow},
+		{name: "ipv6", subject: Subject{IP: "2001:db8::1"}, expected: models.BlockModeReject},
+		{name: "fingerprint", subject: Subject{Fingerprint: "fp-1"}, expected: models.BlockModeShadow},
+		{name: "expired", subject: Subject{Fingerprint: "fp-2"}, expected: ""},
+		{name: "phrase", subject: Subject{Text: "Buy CHEAP   pills now"}, expected: models.BlockModeHold},
+		{name: "part of a word", subject: Subject{Text: "cheap pillsbury"}, expected: ""},
+		{name: "cyrillic word", subject: Subject{Text: "Ты дурак!"}, expected: models.BlockModeHold},
+		{name: "regex", subject: Subject{Text: "VIAGR@ here"}, expected: models.BlockModeShadow},
+		{name: "strictest wins", subject: Subject{Email: "troll@example.com", Text: "cheap pills"}, expected: models.BlockModeReject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := Match(entries, tt.subject, now)
+			result := ""
+			if entry != nil {
+				result = entry.CurrentMode()
+			}
+			if result != tt.expected {
+				t.Errorf("Match() mode = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
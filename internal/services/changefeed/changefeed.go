@@ -0,0 +1,406 @@
+package changefeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/events"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+)
+
+// Event sources (EVENTS_SOURCE)
+const (
+	// SourceLocal publishes the changes made by this instance
+	SourceLocal = "local"
+	// SourceChangeStream publishes every change in the database
+	SourceChangeStream = "changestream"
+)
+
+// Change operations
+const (
+	Insert  = "insert"
+	Update  = "update"
+	Replace = "replace"
+	Delete  = "delete"
+)
+
+const (
+	// streamCursor and pollCursor prefix the names of saved positions; each
+	// instance keeps its own
+	streamCursor = "changestream"
+	pollCursor   = "poll"
+
+	// saveInterval limits how often the position is saved; after a crash at
+	// most this much is read again (consumers deduplicate on Change.Key)
+	saveInterval = time.Second
+
+	// retryDelay is how long to wait before reopening a failed feed
+	retryDelay = 5 * time.Second
+
+	// pollInterval and pollBatch control the fallback for standalone servers
+	pollInterval = 2 * time.Second
+	pollBatch    = 500
+)
+
+// Server error codes
+const (
+	// codeNotReplicaSet: change streams need a replica set or sharded cluster
+	codeNotReplicaSet = 40573
+	// codeHistoryLost: the resume token is older than the oplog
+	codeHistoryLost = 286
+)
+
+// Change is a write to one of the watched collections
+type Change struct {
+	Collection string
+	Operation  string
+	DocumentID primitive.ObjectID
+
+	// Document is the document after the change (nil for deletes)
+	Document bson.Raw
+	// Before is the document before an update or delete, when the server keeps it
+	Before bson.Raw
+	// UpdatedFields lists the fields an update set; nil when unknown
+	UpdatedFields []string
+
+	// ID orders the change cluster-wide (0 when polling)
+	ID uint64
+	// Key identifies the change on every instance
+	Key string
+}
+
+// Changed reports whether an update set one of the fields
+func (c Change) Changed(fields ...string) bool {
+	for _, updated := range c.UpdatedFields {
+		for _, field := range fields {
+			if updated == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Handler turns a change into events
+type Handler func(Change)
+
+// watched returns the collections whose changes become events
+func watched() []string {
+	return []string{
+		mgm.CollName(&models.Comment{}),
+		mgm.CollName(&models.Vote{}),
+		mgm.CollName(&models.Reaction{}),
+	}
+}
+
+// Start feeds database changes to handle in the background
+// With the changestream source, every instance sees every write (not just its
+// own) and handlers no longer publish their events locally.
+func Start(cfg *config.Config, handle Handler) error {
+	switch cfg.EventsSource {
+	case SourceLocal, "":
+		return nil
+	case SourceChangeStream:
+	default:
+		return fmt.Errorf("unknown events source %q", cfg.EventsSource)
+	}
+
+	events.SetLocalPublishing(false)
+
+	go func() {
+		for {
+			err := watch(handle, cfg.InstanceName)
+			if isServerError(err, codeNotReplicaSet) {
+				logger.Warn("MongoDB is not a replica set, polling for changes instead of using change streams")
+				poll(handle, cfg.InstanceName)
+				return
+			}
+			logger.Error(err, "Change stream failed, reopening")
+			time.Sleep(retryDelay)
+		}
+	}()
+
+	return nil
+}
+
+// ========================================
+// Change Streams
+// ========================================
+
+// changeEvent is a change stream document
+type changeEvent struct {
+	ID            bson.Raw            `bson:"_id"`
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	NS            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument             bson.Raw `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.Raw `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        struct {
+		UpdatedFields bson.Raw `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+// watch follows the change stream of the database until it fails
+func watch(handle Handler, instance string) error {
+	ctx := context.Background()
+	_, _, db, err := mgm.DefaultConfigs()
+	if err != nil {
+		return err
+	}
+
+	name := cursorName(streamCursor, instance)
+	saved, err := repository.GetEventCursor(name)
+	if err != nil {
+		return err
+	}
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if enablePreImages(ctx, db) {
+		// Deleted votes and reactions only have their ID without it
+		opts.SetFullDocumentBeforeChange(options.WhenAvailable)
+	}
+	if saved != nil && saved.ResumeToken != nil {
+		opts.SetStartAfter(saved.ResumeToken)
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{
+		"ns.coll":       bson.M{"$in": watched()},
+		"operationType": bson.M{"$in": bson.A{Insert, Update, Replace, Delete}},
+	}}}}
+
+	stream, err := db.Watch(ctx, pipeline, opts)
+	if isServerError(err, codeHistoryLost) {
+		logger.Warn("Change stream position is too old, continuing from now")
+		if err := repository.DeleteEventCursor(name); err != nil {
+			return err
+		}
+		opts.StartAfter = nil
+		stream, err = db.Watch(ctx, pipeline, opts)
+	}
+	if err != nil {
+		return err
+	}
+	defer stream.Close(ctx)
+
+	logger.Info("📡 Watching MongoDB change stream for live events")
+
+	cursor := &models.EventCursor{Name: name}
+	var lastSave time.Time
+	for stream.Next(ctx) {
+		var event changeEvent
+		if err := stream.Decode(&event); err != nil {
+			logger.Error(err, "Failed to decode change event")
+			continue
+		}
+		handle(event.change())
+
+		if time.Since(lastSave) >= saveInterval {
+			cursor.ResumeToken = stream.ResumeToken()
+			if err := repository.SaveEventCursor(cursor); err != nil {
+				logger.Error(err, "Failed to save change stream position")
+			}
+			lastSave = time.Now()
+		}
+	}
+
+	return stream.Err()
+}
+
+// change converts a change stream document
+func (e *changeEvent) change() Change {
+	change := Change{
+		Collection: e.NS.Coll,
+		Operation:  e.OperationType,
+		DocumentID: e.DocumentKey.ID,
+		Document:   e.FullDocument,
+		Before:     e.FullDocumentBeforeChange,
+		// Cluster time is the same on every instance and always grows
+		ID: uint64(e.ClusterTime.T)<<32 | uint64(e.ClusterTime.I),
+	}
+	if token, ok := e.ID.Lookup("_data").StringValueOK(); ok {
+		change.Key = token
+	}
+
+	if e.OperationType == Update {
+		change.UpdatedFields = []string{}
+		if elements, err := e.UpdateDescription.UpdatedFields.Elements(); err == nil {
+			for _, element := range elements {
+				change.UpdatedFields = append(change.UpdatedFields, element.Key())
+			}
+		}
+		change.UpdatedFields = append(change.UpdatedFields, e.UpdateDescription.RemovedFields...)
+	}
+
+	return change
+}
+
+// enablePreImages asks the server to keep documents as they were before a change
+// Needs MongoDB 6.0 or newer; reports whether it worked.
+func enablePreImages(ctx context.Context, db *mongo.Database) bool {
+	for _, coll := range watched() {
+		err := db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: coll},
+			{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": true}},
+		}).Err()
+		if err != nil {
+			logger.Warn("Change stream pre-images unavailable, deleted votes and reactions won't be announced: " + err.Error())
+			return false
+		}
+	}
+	return true
+}
+
+// cursorName names the saved position of a feed of an instance
+func cursorName(parts ...string) string {
+	return strings.Join(parts, ":")
+}
+
+// isServerError reports whether err is a MongoDB error with the given code
+func isServerError(err error, code int) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(code)
+}
+
+// ========================================
+// Polling
+// ========================================
+
+// poller reads new and updated documents of one collection by updatedAt
+// Deletions can't be seen this way, so toggled-off votes and reactions are
+// announced with the next change on the same comment or page.
+type poller struct {
+	coll   *mongo.Collection
+	cursor *models.EventCursor
+	// seen holds the documents already handled at cursor.Position
+	seen map[string]bool
+}
+
+// poll reads the watched collections forever
+func poll(handle Handler, instance string) {
+	_, _, db, err := mgm.DefaultConfigs()
+	if err != nil {
+		logger.Error(err, "Failed to start polling for changes")
+		return
+	}
+
+	var pollers []*poller
+	for _, name := range watched() {
+		p, err := newPoller(db.Collection(name), instance)
+		if err != nil {
+			logger.Error(err, "Failed to load polling position for "+name)
+			return
+		}
+		pollers = append(pollers, p)
+	}
+
+	for {
+		for _, p := range pollers {
+			if err := p.poll(handle); err != nil {
+				logger.Error(err, "Failed to poll "+p.coll.Name()+" for changes")
+			}
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// newPoller continues from the saved position, or from now
+func newPoller(coll *mongo.Collection, instance string) (*poller, error) {
+	name := cursorName(pollCursor, instance, coll.Name())
+	cursor, err := repository.GetEventCursor(name)
+	if err != nil {
+		return nil, err
+	}
+	if cursor == nil {
+		// MongoDB stores milliseconds
+		cursor = &models.EventCursor{Name: name, Position: time.Now().Truncate(time.Millisecond)}
+	}
+
+	p := &poller{coll: coll, cursor: cursor, seen: make(map[string]bool)}
+	for _, id := range cursor.IDs {
+		p.seen[id] = true
+	}
+	return p, nil
+}
+
+// polledDocument holds the fields polling needs from any document
+type polledDocument struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt"`
+}
+
+// poll handles the documents changed since the last poll
+func (p *poller) poll(handle Handler) error {
+	ctx := context.Background()
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updatedAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(pollBatch)
+
+	// $gte: more documents may have been written in the same millisecond
+	results, err := p.coll.Find(ctx, bson.M{"updatedAt": bson.M{"$gte": p.cursor.Position}}, opts)
+	if err != nil {
+		return err
+	}
+	defer results.Close(ctx)
+
+	moved := false
+	for results.Next(ctx) {
+		var doc polledDocument
+		if err := results.Decode(&doc); err != nil {
+			return err
+		}
+		id := doc.ID.Hex()
+		if doc.UpdatedAt.Equal(p.cursor.Position) && p.seen[id] {
+			continue
+		}
+
+		operation := Update
+		if doc.CreatedAt.Equal(doc.UpdatedAt) {
+			operation = Insert
+		}
+		handle(Change{
+			Collection: p.coll.Name(),
+			Operation:  operation,
+			DocumentID: doc.ID,
+			Document:   append(bson.Raw(nil), results.Current...),
+			Key:        fmt.Sprintf("%s:%s:%d", p.coll.Name(), id, doc.UpdatedAt.UnixMilli()),
+		})
+
+		if doc.UpdatedAt.After(p.cursor.Position) {
+			p.cursor.Position = doc.UpdatedAt
+			p.seen = make(map[string]bool)
+		}
+		p.seen[id] = true
+		moved = true
+	}
+	if err := results.Err(); err != nil {
+		return err
+	}
+
+	if !moved {
+		return nil
+	}
+	p.cursor.IDs = p.cursor.IDs[:0]
+	for id := range p.seen {
+		p.cursor.IDs = append(p.cursor.IDs, id)
+	}
+	return repository.SaveEventCursor(p.cursor)
+}
@@ -0,0 +1,59 @@
+package changefeed
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestChangeEventConversion(t *testing.T) {
+	id := primitive.NewObjectID()
+	raw, err := bson.Marshal(bson.M{
+		"_id":           bson.M{"_data": "8265A1"},
+		"operationType": Update,
+		"clusterTime":   primitive.Timestamp{T: 1700000000, I: 3},
+		"ns":            bson.M{"db": "zoomment", "coll": "comments"},
+		"documentKey":   bson.M{"_id": id},
+		"fullDocument":  bson.M{"_id": id, "body": "edited"},
+		"updateDescription": bson.M{
+			"updatedFields": bson.D{{Key: "body", Value: "edited"}, {Key: "updatedAt", Value: 1}},
+			"removedFields": bson.A{"editedAt"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event changeEvent
+	if err := bson.Unmarshal(raw, &event); err != nil {
+		t.Fatal(err)
+	}
+	change := event.change()
+
+	if change.Collection != "comments" || change.Operation != Update || change.DocumentID != id {
+		t.Errorf("got %+v", change)
+	}
+	if change.Key != "8265A1" {
+		t.Errorf("Key = %q, want the resume token", change.Key)
+	}
+	if want := uint64(1700000000)<<32 | 3; change.ID != want {
+		t.Errorf("ID = %d, want %d", change.ID, want)
+	}
+	if want := []string{"body", "updatedAt", "editedAt"}; !reflect.DeepEqual(change.UpdatedFields, want) {
+		t.Errorf("UpdatedFields = %v, want %v", change.UpdatedFields, want)
+	}
+	if !change.Changed("status", "body") || change.Changed("status") {
+		t.Error("Changed() doesn't match UpdatedFields")
+	}
+}
+
+func TestClusterTimeIDsGrow(t *testing.T) {
+	earlier := changeEvent{ClusterTime: primitive.Timestamp{T: 100, I: 9}}
+	later := changeEvent{ClusterTime: primitive.Timestamp{T: 101, I: 1}}
+
+	if earlier.change().ID >= later.change().ID {
+		t.Error("IDs should follow the cluster time")
+	}
+}
@@ -0,0 +1,151 @@
+// Package chat posts new comments to Slack, Discord, Telegram and Matrix
+// Each channel type gets the message in its native format, with links
+// to the comment and quick moderation actions.
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/i18n"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/services/mailer"
+	"zoomment-server/internal/services/webhooks"
+	"zoomment-server/internal/utils"
+)
+
+const (
+	// requestTimeout bounds a single post
+	requestTimeout = 10 * time.Second
+
+	// maxBodyLength is how many characters of a comment are quoted
+	maxBodyLength = 1000
+
+	// maxErrorBody is how much of a failed response ends up in the error
+	maxErrorBody = 256
+)
+
+// Action styles
+const (
+	StylePrimary = "primary"
+	StyleDanger  = "danger"
+)
+
+// Message is a new comment to post
+type Message struct {
+	// Locale is the language of the message
+	Locale  string
+	Domain  string
+	Comment mailer.CommentData
+	// Pending is set while the comment waits for approval
+	Pending bool
+	Actions []Action
+}
+
+// Action is a link button under a message
+// The URL carries everything needed to act (a signed token), so it works
+// from any chat without signing in.
+type Action struct {
+	Label string
+	URL   string
+	Style string
+}
+
+// title is the first line of a message
+func (m Message) title() string {
+	title := i18n.T(m.Locale, "New comment on %s", m.Domain)
+	if m.Pending {
+		title += " (" + i18n.T(m.Locale, "waiting for approval") + ")"
+	}
+	return title
+}
+
+// text is the comment body as plain text, shortened for chats
+func (m Message) text() string {
+	text := utils.HTMLToText(m.Comment.Body)
+	if runes := []rune(text); len(runes) > maxBodyLength {
+		text = strings.TrimSpace(string(runes[:maxBodyLength])) + "…"
+	}
+	return text
+}
+
+// Sender posts messages to notification channels
+type Sender struct {
+	client *http.Client
+
+	// telegramAPI is the Telegram Bot API server
+	telegramAPI string
+}
+
+// NewSender creates a Sender
+// Channels are checked like webhooks: they may only point into private
+// networks when WEBHOOK_ALLOW_PRIVATE_NETWORKS is set.
+func NewSender(cfg *config.Config) *Sender {
+	return &Sender{
+		client:      webhooks.NewClient(cfg.WebhookAllowPrivateNetworks),
+		telegramAPI: strings.TrimSuffix(cfg.TelegramAPIURL, "/"),
+	}
+}
+
+// Send posts a message to a channel
+func (s *Sender) Send(channel *models.NotificationChannel, msg Message) error {
+	switch channel.Type {
+	case models.ChannelSlack:
+		return s.do(http.MethodPost, channel.WebhookURL, "", slackMessage(msg))
+	case models.ChannelDiscord:
+		return s.do(http.MethodPost, channel.WebhookURL, "", discordMessage(msg))
+	case models.ChannelTelegram:
+		endpoint := s.telegramAPI + "/bot" + channel.BotToken + "/sendMessage"
+		return s.do(http.MethodPost, endpoint, "", telegramMessage(channel.ChatID, msg))
+	case models.ChannelMatrix:
+		// The transaction ID makes the homeserver ignore repeated posts of a comment
+		endpoint := strings.TrimSuffix(channel.HomeserverURL, "/") + "/_matrix/client/v3/rooms/" +
+			url.PathEscape(channel.RoomID) + "/send/m.room.message/" + url.PathEscape("zoomment-"+msg.Comment.ID)
+		return s.do(http.MethodPut, endpoint, channel.AccessToken, matrixMessage(msg))
+	}
+	return fmt.Errorf("unknown channel type %q", channel.Type)
+}
+
+// do sends a JSON request; any response other than 2xx is an error
+func (s *Sender) do(method, endpoint, bearer string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Telegram URLs contain the bot token, keep it out of the logs
+		if urlErr, ok := err.(*url.Error); ok {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, responseBody)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/services/mailer"
+)
+
+// recorded is a request received by the stand-in server
+type recorded struct {
+	method string
+	path   string
+	auth   string
+	body   map[string]any
+}
+
+// standIn starts a local server that records one request per call
+func standIn(t *testing.T, status int) (*httptest.Server, *recorded) {
+	t.Helper()
+	got := &recorded{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.method = r.Method
+		got.path = r.URL.EscapedPath()
+		got.auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got.body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, got
+}
+
+func testSender(telegramAPI string) *Sender {
+	return NewSender(&config.Config{WebhookAllowPrivateNetworks: true, TelegramAPIURL: telegramAPI})
+}
+
+func testMessage() Message {
+	return Message{
+		Domain: "example.com",
+		Comment: mailer.CommentData{
+			ID:      "c1",
+			Author:  "Ann <b>",
+			Date:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			PageURL: "https://example.com/post",
+			Body:    "<p>Nice *post* &amp; thanks</p><p>Second</p>",
+		},
+		Pending: true,
+		Actions: []Action{
+			{Label: "Approve", URL: "https://api.example.com/api/moderate/a?b=1&c=2", Style: StylePrimary},
+			{Label: "Delete", URL: "https://api.example.com/api/moderate/d", Style: StyleDanger},
+		},
+	}
+}
+
+// field walks decoded JSON, indexing maps by string and arrays by int
+func field(v any, path ...any) any {
+	for _, key := range path {
+		switch k := key.(type) {
+		case string:
+			m, _ := v.(map[string]any)
+			v = m[k]
+		case int:
+			a, _ := v.([]any)
+			if k >= len(a) {
+				return nil
+			}
+			v = a[k]
+		}
+	}
+	return v
+}
+
+func TestSendSlack(t *testing.T) {
+	server, got := standIn(t, http.StatusOK)
+	channel := &models.NotificationChannel{Type: models.ChannelSlack, WebhookURL: server.URL + "/services/T/B/X"}
+
+	if err := testSender("").Send(channel, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.method != http.MethodPost || got.path != "/services/T/B/X" {
+		t.Errorf("got %s %s", got.method, got.path)
+	}
+	section, _ := field(got.body, "blocks", 0, "text", "text").(string)
+	for _, want := range []string{"<https://example.com/post|New comment on example.com (waiting for approval)>", "*Ann &lt;b&gt;*", "> Nice *post* &amp; thanks\n> \n> Second"} {
+		if !strings.Contains(section, want) {
+			t.Errorf("section %q doesn't contain %q", section, want)
+		}
+	}
+	if got := field(got.body, "blocks", 1, "elements", 0, "url"); got != "https://api.example.com/api/moderate/a?b=1&c=2" {
+		t.Errorf("approve button url = %v", got)
+	}
+	if got := field(got.body, "blocks", 1, "elements", 1, "style"); got != StyleDanger {
+		t.Errorf("delete button style = %v", got)
+	}
+}
+
+func TestSendDiscord(t *testing.T) {
+	server, got := standIn(t, http.StatusNoContent)
+	channel := &models.NotificationChannel{Type: models.ChannelDiscord, WebhookURL: server.URL + "/api/webhooks/1/x"}
+
+	if err := testSender("").Send(channel, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	embed := field(got.body, "embeds", 0)
+	if field(embed, "url") != "https://example.com/post" || field(embed, "author", "name") != "Ann <b>" {
+		t.Errorf("got embed %v", embed)
+	}
+	if field(embed, "color") != float64(discordColorPending) || field(embed, "timestamp") != "2024-05-01T12:00:00Z" {
+		t.Errorf("got embed %v", embed)
+	}
+	description, _ := field(embed, "description").(string)
+	for _, want := range []string{`Nice \*post\* & thanks`, "**[Approve](https://api.example.com/api/moderate/a?b=1&c=2)**"} {
+		if !strings.Contains(description, want) {
+			t.Errorf("description %q doesn't contain %q", description, want)
+		}
+	}
+	if parse, ok := field(got.body, "allowed_mentions", "parse").([]any); !ok || len(parse) != 0 {
+		t.Errorf("mentions must be disabled, got %v", field(got.body, "allowed_mentions"))
+	}
+}
+
+func TestSendTelegram(t *testing.T) {
+	server, got := standIn(t, http.StatusOK)
+	channel := &models.NotificationChannel{Type: models.ChannelTelegram, BotToken: "123:abc", ChatID: "-100"}
+
+	if err := testSender(server.URL+"/").Send(channel, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.path != "/bot123:abc/sendMessage" {
+		t.Errorf("path = %s", got.path)
+	}
+	if field(got.body, "chat_id") != "-100" || field(got.body, "parse_mode") != "HTML" {
+		t.Errorf("got body %v", got.body)
+	}
+	text, _ := field(got.body, "text").(string)
+	for _, want := range []string{`<a href="https://example.com/post">`, "<b>Ann &lt;b&gt;</b>", "<blockquote>Nice *post* &amp; thanks"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text %q doesn't contain %q", text, want)
+		}
+	}
+	if got := field(got.body, "reply_markup", "inline_keyboard", 0, 1, "text"); got != "Delete" {
+		t.Errorf("second button = %v", got)
+	}
+}
+
+func TestSendMatrix(t *testing.T) {
+	server, got := standIn(t, http.StatusOK)
+	channel := &models.NotificationChannel{
+		Type:          models.ChannelMatrix,
+		HomeserverURL: server.URL,
+		AccessToken:   "syt_token",
+		RoomID:        "!room:example.org",
+	}
+
+	if err := testSender("").Send(channel, testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.method != http.MethodPut || got.path != "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/zoomment-c1" {
+		t.Errorf("got %s %s", got.method, got.path)
+	}
+	if got.auth != "Bearer syt_token" {
+		t.Errorf("Authorization = %q", got.auth)
+	}
+	if field(got.body, "msgtype") != "m.notice" || field(got.body, "format") != "org.matrix.custom.html" {
+		t.Errorf("got body %v", got.body)
+	}
+	body, _ := field(got.body, "body").(string)
+	if !strings.Contains(body, "Approve: https://api.example.com/api/moderate/a?b=1&c=2") {
+		t.Errorf("body %q lacks the approve link", body)
+	}
+	formatted, _ := field(got.body, "formatted_body").(string)
+	if !strings.Contains(formatted, `<a href="https://api.example.com/api/moderate/a?b=1&amp;c=2">Approve</a>`) {
+		t.Errorf("formatted_body %q lacks the approve link", formatted)
+	}
+}
+
+func TestSendFails(t *testing.T) {
+	server, _ := standIn(t, http.StatusForbidden)
+	channel := &models.NotificationChannel{Type: models.ChannelSlack, WebhookURL: server.URL}
+
+	err := testSender("").Send(channel, testMessage())
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Send() error = %v, want the status", err)
+	}
+
+	if err := testSender("").Send(&models.NotificationChannel{Type: "irc"}, testMessage()); err == nil {
+		t.Error("unknown channel types must fail")
+	}
+}
+
+func TestMessageText(t *testing.T) {
+	msg := Message{Comment: mailer.CommentData{Body: "<p>" + strings.Repeat("é", maxBodyLength+10) + "</p>"}}
+	text := msg.text()
+	if got := len([]rune(text)); got != maxBodyLength+1 || !strings.HasSuffix(text, "…") {
+		t.Errorf("long bodies must be cut to %d characters, got %d", maxBodyLength, got)
+	}
+}
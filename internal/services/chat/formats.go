@@ -0,0 +1,246 @@
+package chat
+
+import (
+	"html"
+	"strings"
+	"time"
+)
+
+// ========================================
+// Slack (incoming webhooks, Block Kit)
+// ========================================
+
+type slackPayload struct {
+	// Text is shown in notifications and by clients without blocks
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string        `json:"type"`
+	Text     *slackText    `json:"text,omitempty"`
+	Elements []slackButton `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackButton struct {
+	Type  string    `json:"type"`
+	Text  slackText `json:"text"`
+	URL   string    `json:"url"`
+	Style string    `json:"style,omitempty"`
+}
+
+// slackMessage formats a message for a Slack incoming webhook
+func slackMessage(msg Message) slackPayload {
+	text := "*<" + slackEscape(msg.Comment.PageURL) + "|" + slackEscape(msg.title()) + ">*\n" +
+		"*" + slackEscape(msg.Comment.Author) + "*\n" +
+		quote(slackEscape(msg.text()), "> ")
+
+	payload := slackPayload{
+		Text:   msg.title(),
+		Blocks: []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}},
+	}
+
+	if len(msg.Actions) > 0 {
+		buttons := make([]slackButton, 0, len(msg.Actions))
+		for _, action := range msg.Actions {
+			buttons = append(buttons, slackButton{
+				Type:  "button",
+				Text:  slackText{Type: "plain_text", Text: action.Label},
+				URL:   action.URL,
+				Style: action.Style,
+			})
+		}
+		payload.Blocks = append(payload.Blocks, slackBlock{Type: "actions", Elements: buttons})
+	}
+
+	return payload
+}
+
+// slackEscape escapes the characters Slack uses for links and mentions
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// ========================================
+// Discord (webhooks)
+// ========================================
+
+// Embed colors
+const (
+	discordColorApproved = 0x1677ff
+	discordColorPending  = 0xfaad14
+)
+
+type discordPayload struct {
+	Embeds []discordEmbed `json:"embeds"`
+	// AllowedMentions stops comments from pinging anyone
+	AllowedMentions discordMentions `json:"allowed_mentions"`
+}
+
+type discordEmbed struct {
+	Title       string             `json:"title"`
+	URL         string             `json:"url"`
+	Description string             `json:"description"`
+	Color       int                `json:"color"`
+	Timestamp   string             `json:"timestamp,omitempty"`
+	Author      discordEmbedAuthor `json:"author"`
+}
+
+type discordEmbedAuthor struct {
+	Name string `json:"name"`
+}
+
+type discordMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// discordMessage formats a message for a Discord webhook
+// Plain webhooks can't have buttons, so the actions are Markdown links.
+func discordMessage(msg Message) discordPayload {
+	description := discordEscape(msg.text())
+	if len(msg.Actions) > 0 {
+		links := make([]string, 0, len(msg.Actions))
+		for _, action := range msg.Actions {
+			links = append(links, "**["+discordEscape(action.Label)+"]("+action.URL+")**")
+		}
+		description += "\n\n" + strings.Join(links, " · ")
+	}
+
+	color := discordColorApproved
+	if msg.Pending {
+		color = discordColorPending
+	}
+
+	return discordPayload{
+		Embeds: []discordEmbed{{
+			Title:       msg.title(),
+			URL:         msg.Comment.PageURL,
+			Description: description,
+			Color:       color,
+			Timestamp:   timestamp(msg.Comment.Date),
+			Author:      discordEmbedAuthor{Name: msg.Comment.Author},
+		}},
+		AllowedMentions: discordMentions{Parse: []string{}},
+	}
+}
+
+// discordEscape escapes Discord Markdown
+func discordEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\*_~`|>[]()#", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ========================================
+// Telegram (Bot API sendMessage)
+// ========================================
+
+type telegramPayload struct {
+	ChatID             string               `json:"chat_id"`
+	Text               string               `json:"text"`
+	ParseMode          string               `json:"parse_mode"`
+	LinkPreviewOptions telegramLinkPreview  `json:"link_preview_options"`
+	ReplyMarkup        *telegramReplyMarkup `json:"reply_markup,omitempty"`
+}
+
+type telegramLinkPreview struct {
+	IsDisabled bool `json:"is_disabled"`
+}
+
+type telegramReplyMarkup struct {
+	InlineKeyboard [][]telegramButton `json:"inline_keyboard"`
+}
+
+type telegramButton struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// telegramMessage formats a message for the sendMessage method
+// Actions become a row of inline keyboard buttons.
+func telegramMessage(chatID string, msg Message) telegramPayload {
+	text := `<b><a href="` + html.EscapeString(msg.Comment.PageURL) + `">` + html.EscapeString(msg.title()) + "</a></b>\n" +
+		"<b>" + html.EscapeString(msg.Comment.Author) + "</b>\n" +
+		"<blockquote>" + html.EscapeString(msg.text()) + "</blockquote>"
+
+	payload := telegramPayload{
+		ChatID:             chatID,
+		Text:               text,
+		ParseMode:          "HTML",
+		LinkPreviewOptions: telegramLinkPreview{IsDisabled: true},
+	}
+
+	if len(msg.Actions) > 0 {
+		row := make([]telegramButton, 0, len(msg.Actions))
+		for _, action := range msg.Actions {
+			row = append(row, telegramButton{Text: action.Label, URL: action.URL})
+		}
+		payload.ReplyMarkup = &telegramReplyMarkup{InlineKeyboard: [][]telegramButton{row}}
+	}
+
+	return payload
+}
+
+// ========================================
+// Matrix (m.room.message)
+// ========================================
+
+type matrixPayload struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// matrixMessage formats a message as an m.room.message event
+// Sent as a notice, which bots are expected to use, with an HTML version.
+func matrixMessage(msg Message) matrixPayload {
+	body := msg.title() + "\n" + msg.Comment.PageURL + "\n" + msg.Comment.Author + "\n" + quote(msg.text(), "> ")
+	formatted := `<p><strong><a href="` + html.EscapeString(msg.Comment.PageURL) + `">` + html.EscapeString(msg.title()) + "</a></strong><br>" +
+		"<strong>" + html.EscapeString(msg.Comment.Author) + "</strong></p>" +
+		"<blockquote>" + strings.ReplaceAll(html.EscapeString(msg.text()), "\n", "<br>") + "</blockquote>"
+
+	if len(msg.Actions) > 0 {
+		body += "\n"
+		links := make([]string, 0, len(msg.Actions))
+		for _, action := range msg.Actions {
+			body += "\n" + action.Label + ": " + action.URL
+			links = append(links, `<a href="`+html.EscapeString(action.URL)+`">`+html.EscapeString(action.Label)+"</a>")
+		}
+		formatted += "<p>" + strings.Join(links, " · ") + "</p>"
+	}
+
+	return matrixPayload{
+		MsgType:       "m.notice",
+		Body:          body,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted,
+	}
+}
+
+// ========================================
+// Helpers
+// ========================================
+
+// quote prefixes every line of text
+func quote(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+// timestamp formats a time as ISO 8601 ("" for the zero time)
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -1,148 +1,280 @@
 package mailer
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/gomail.v2"
 
 	"zoomment-server/internal/config"
+	"zoomment-server/internal/constants"
+	"zoomment-server/internal/i18n"
 	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
 )
 
 // Mailer handles sending emails
+// The Send* methods only put emails in the outbox; the outbox workers
+// deliver them with Deliver.
 type Mailer struct {
-	dialer    *gomail.Dialer
-	from      string
-	brandName string
+	transport    Transport
+	templates    *templates
+	from         string
+	brandName    string
 	dashboardURL string
 }
 
+// defaultFrom is the sender for non-SMTP transports when no bot email is set
+const defaultFrom = "noreply@localhost"
+
 // New creates a new Mailer instance
+// Emails are disabled when the transport can't be created.
 func New(cfg *config.Config) *Mailer {
-	dialer := gomail.NewDialer(
-		cfg.BotEmail.Host,
-		cfg.BotEmail.Port,
-		cfg.BotEmail.Address,
-		cfg.BotEmail.Password,
-	)
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		logger.Error(err, "Invalid mail transport, emails are disabled")
+	}
+
+	return NewWithTransport(cfg, transport)
+}
+
+// NewWithTransport creates a Mailer that delivers through the given transport
+func NewWithTransport(cfg *config.Config, transport Transport) *Mailer {
+	from := cfg.BotEmail.Address
+	if from == "" {
+		from = defaultFrom
+	}
 
 	return &Mailer{
-		dialer:    dialer,
-		from:      cfg.BotEmail.Address,
-		brandName: cfg.BrandName,
+		transport:    transport,
+		templates:    loadTemplates(cfg.MailTemplateDir),
+		from:         from,
+		brandName:    cfg.BrandName,
 		dashboardURL: cfg.DashboardURL,
 	}
 }
 
 // SendMagicLink sends a magic link email for authentication
-func (m *Mailer) SendMagicLink(email, token string) error {
-	if m.from == "" {
+func (m *Mailer) SendMagicLink(to Recipient, token string) error {
+	if m.transport == nil {
 		logger.Warn("Email not configured, skipping magic link email")
 		return nil
 	}
 
 	link := fmt.Sprintf("%s/dashboard?zoommentToken=%s", m.dashboardURL, token)
 
-	html := generateTemplate(TemplateData{
-		BrandName:    m.brandName,
-		DashboardURL: m.dashboardURL,
-		Introduction: fmt.Sprintf("Click the link below to sign in to your %s dashboard.", m.brandName),
-		ButtonText:   fmt.Sprintf("Sign in to %s", m.brandName),
-		ButtonURL:    link,
-		Epilogue:     "If you did not make this request, you can safely ignore this email.",
+	return m.enqueue(templateMagicLink, to, &models.OutboxMessage{
+		IdempotencyKey: idempotencyKey("magic-link", to.Email, token),
+		Subject:        i18n.T(to.Locale, "Sign in to %s", m.brandName),
+	}, TemplateData{
+		ButtonText: i18n.T(to.Locale, "Sign in to %s", m.brandName),
+		ButtonURL:  link,
 	})
-
-	msg := gomail.NewMessage()
-	msg.SetHeader("From", fmt.Sprintf("%s <%s>", m.brandName, m.from))
-	msg.SetHeader("To", email)
-	msg.SetHeader("Subject", fmt.Sprintf("Sign in to %s", m.brandName))
-	msg.SetBody("text/html", html)
-
-	if err := m.dialer.DialAndSend(msg); err != nil {
-		logger.Error(err, "Failed to send magic link email")
-		return err
-	}
-
-	logger.Info("Magic link email sent to " + email)
-	return nil
 }
 
 // SendEmailVerification sends email verification link for guest comments
-func (m *Mailer) SendEmailVerification(email, token, pageURL string) error {
-	if m.from == "" {
+func (m *Mailer) SendEmailVerification(to Recipient, token, pageURL string) error {
+	if m.transport == nil {
 		logger.Warn("Email not configured, skipping verification email")
 		return nil
 	}
 
 	link := fmt.Sprintf("%s?zoommentToken=%s", pageURL, token)
 
-	html := generateTemplate(TemplateData{
-		BrandName:    m.brandName,
-		DashboardURL: m.dashboardURL,
-		Introduction: "Please confirm your email address to be able to manage your comment.",
-		ButtonText:   "Confirm",
-		ButtonURL:    link,
-		Epilogue:     "If you did not make this request, you can safely ignore this email.",
+	return m.enqueue(templateVerification, to, &models.OutboxMessage{
+		IdempotencyKey: idempotencyKey("verification", to.Email, token),
+		Subject:        i18n.T(to.Locale, "You have added a comment!"),
+	}, TemplateData{
+		ButtonText: i18n.T(to.Locale, "Confirm"),
+		ButtonURL:  link,
 	})
+}
 
-	msg := gomail.NewMessage()
-	msg.SetHeader("From", fmt.Sprintf("%s <%s>", m.brandName, m.from))
-	msg.SetHeader("To", email)
-	msg.SetHeader("Subject", "You have added a comment!")
-	msg.SetBody("text/html", html)
-
-	if err := m.dialer.DialAndSend(msg); err != nil {
-		logger.Error(err, "Failed to send verification email")
-		return err
+// SendCommentNotification notifies site owner about a new comment
+// links become one-click moderation buttons (empty links are left out).
+func (m *Mailer) SendCommentNotification(to Recipient, comment CommentData, links ModerationLinks) error {
+	if m.transport == nil {
+		logger.Warn("Email not configured, skipping notification email")
+		return nil
 	}
 
-	logger.Info("Verification email sent to " + email)
-	return nil
+	return m.enqueue(templateCommentNotification, to, &models.OutboxMessage{
+		IdempotencyKey: idempotencyKey("comment-notification", to.Email, comment.ID),
+		Subject:        i18n.T(to.Locale, "You have a new comment!"),
+	}, TemplateData{
+		ButtonText: i18n.T(to.Locale, "Sign in to manage comments"),
+		ButtonURL:  m.dashboardURL + "/auth",
+		Comment:    comment,
+		Moderation: links,
+	})
 }
 
-// SendCommentNotification notifies site owner about a new comment
-func (m *Mailer) SendCommentNotification(ownerEmail string, comment CommentData) error {
-	if m.from == "" {
-		logger.Warn("Email not configured, skipping notification email")
+// SendReportNotification tells the site owner that readers reported a comment
+// Owners hear about the first report of a comment, and again when enough
+// readers reported it to hide it.
+func (m *Mailer) SendReportNotification(to Recipient, comment CommentData, report ReportData, links ModerationLinks) error {
+	if m.transport == nil {
+		logger.Warn("Email not configured, skipping report notification email")
 		return nil
 	}
 
-	html := generateTemplate(TemplateData{
-		BrandName:    m.brandName,
-		DashboardURL: m.dashboardURL,
-		Introduction: fmt.Sprintf(`
-			<p>You have a new comment!</p>
-			<div style="font-size: 14px; line-height: 27px; margin-top: 10px;"> 
-				<div><b>User:</b> %s</div>
-				<div><b>Date:</b> %s</div>
-				<div><b>Page:</b> %s</div>
-				<div><b>Comment:</b> %s</div>
-			</div>
-		`, comment.Author, comment.Date, comment.PageURL, comment.Body),
-		ButtonText: "Sign in to manage comments",
+	subject := "A reader reported a comment"
+	if report.Hidden {
+		subject = "A reported comment was hidden"
+	}
+	report.Reason = reportReasons[report.Reason]
+
+	return m.enqueue(templateReportNotification, to, &models.OutboxMessage{
+		IdempotencyKey: idempotencyKey("report-notification", to.Email, comment.ID, strconv.FormatBool(report.Hidden)),
+		Subject:        i18n.T(to.Locale, subject),
+	}, TemplateData{
+		ButtonText: i18n.T(to.Locale, "Sign in to manage comments"),
 		ButtonURL:  m.dashboardURL + "/auth",
-		Epilogue:   "",
+		Comment:    comment,
+		Moderation: links,
+		Report:     report,
+	})
+}
+
+// SendReplyNotification tells a comment author that someone replied to them
+// unsubscribeURL is a signed link that stops notifications for the thread;
+// it is also sent as List-Unsubscribe so mail clients can offer one-click unsubscribe.
+func (m *Mailer) SendReplyNotification(to Recipient, reply CommentData, unsubscribeURL string) error {
+	if m.transport == nil {
+		logger.Warn("Email not configured, skipping reply notification email")
+		return nil
+	}
+
+	return m.enqueue(templateReplyNotification, to, &models.OutboxMessage{
+		IdempotencyKey: idempotencyKey("reply-notification", to.Email, reply.ID),
+		Subject:        i18n.T(to.Locale, "Someone replied to your comment"),
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}, TemplateData{
+		ButtonText:     i18n.T(to.Locale, "View reply"),
+		ButtonURL:      reply.PageURL,
+		Comment:        reply,
+		UnsubscribeURL: unsubscribeURL,
 	})
+}
 
+// Deliver sends an email from the outbox through the configured transport
+func (m *Mailer) Deliver(message *models.OutboxMessage) error {
 	msg := gomail.NewMessage()
-	msg.SetHeader("From", fmt.Sprintf("%s <%s>", m.brandName, m.from))
-	msg.SetHeader("To", ownerEmail)
-	msg.SetHeader("Subject", "You have a new comment!")
-	msg.SetBody("text/html", html)
+	msg.SetHeader("From", message.From)
+	msg.SetHeader("To", message.To)
+	msg.SetHeader("Subject", message.Subject)
+	for name, value := range message.Headers {
+		msg.SetHeader(name, value)
+	}
+	// Plain text first: clients show the last alternative they support
+	if message.Text != "" {
+		msg.SetBody("text/plain", message.Text)
+		msg.AddAlternative("text/html", message.HTML)
+	} else {
+		msg.SetBody("text/html", message.HTML)
+	}
+
+	if m.transport == nil {
+		return fmt.Errorf("email is not configured")
+	}
+	return gomail.Send(gomail.SendFunc(m.transport.Send), msg)
+}
+
+// enqueue renders an email template for a recipient and puts the email in the outbox
+func (m *Mailer) enqueue(template string, to Recipient, message *models.OutboxMessage, data TemplateData) error {
+	data.BrandName = m.brandName
+	data.DashboardURL = m.dashboardURL
+	data.Locale = i18n.First(to.Locale)
+	if !data.Comment.Date.IsZero() {
+		data.Date = formatDate(data.Comment.Date, to.Timezone)
+	}
+
+	html, text, err := m.templates.render(template, data)
+	if err != nil {
+		logger.Error(err, "Failed to render email template "+template)
+		return err
+	}
+
+	message.From = fmt.Sprintf("%s <%s>", m.brandName, m.from)
+	message.To = to.Email
+	message.HTML = html
+	message.Text = text
 
-	if err := m.dialer.DialAndSend(msg); err != nil {
-		logger.Error(err, "Failed to send notification email")
+	queued, err := repository.EnqueueMail(message)
+	if err != nil {
+		logger.Error(err, "Failed to queue email: "+message.Subject)
 		return err
 	}
 
-	logger.Info("Notification email sent to " + ownerEmail)
+	if !queued {
+		logger.Debug("Email already queued for " + message.To + ": " + message.Subject)
+		return nil
+	}
+
+	logger.Info("Email queued for " + message.To + ": " + message.Subject)
 	return nil
 }
 
+// formatDate formats a date for an email in the recipient's timezone
+func formatDate(date time.Time, timezone string) string {
+	return date.In(i18n.Timezone(timezone)).Format(constants.DateFormat)
+}
+
+// idempotencyKey identifies an email so it is only queued once
+// Parts may contain tokens, so they are hashed instead of stored as-is.
+func idempotencyKey(kind string, parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return kind + ":" + hex.EncodeToString(sum[:])
+}
+
+// Recipient is who an email is sent to
+// Locale and Timezone are used to localize the email (empty = English, server time).
+type Recipient struct {
+	Email    string
+	Locale   string
+	Timezone string
+}
+
+// ModerationLinks are signed links that moderate a comment without signing in
+type ModerationLinks struct {
+	Approve string
+	Delete  string
+	Spam    string
+	Ban     string
+}
+
+// ReportData describes the reports of a comment
+// Reason is one of the models.Report* reasons; Count is how many readers
+// reported the comment and Hidden is set when that made it wait for moderation.
+type ReportData struct {
+	Reason  string
+	Details string
+	Count   int64
+	Hidden  bool
+}
+
+// reportReasons are the names of report reasons in emails
+var reportReasons = map[string]string{
+	models.ReportSpam:       "Spam",
+	models.ReportAbuse:      "Abusive or hateful",
+	models.ReportHarassment: "Harassment",
+	models.ReportOffTopic:   "Off topic",
+	models.ReportOther:      "Other",
+}
+
 // CommentData holds data for comment notification email
 type CommentData struct {
+	ID      string
 	Author  string
-	Date    string
+	Date    time.Time
 	PageURL string
 	Body    string
 }
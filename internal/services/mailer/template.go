@@ -1,128 +1,174 @@
 package mailer
 
-import "fmt"
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+
+	"zoomment-server/internal/i18n"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/utils"
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// Email template names
+// Each one has a .html and a .txt file that define "content" and
+// optionally "epilogue", rendered inside layout.html / layout.txt.
+const (
+	templateMagicLink           = "magic_link"
+	templateVerification        = "verification"
+	templateCommentNotification = "comment_notification"
+	templateReplyNotification   = "reply_notification"
+	templateReportNotification  = "report_notification"
+)
+
+var templateNames = []string{
+	templateMagicLink,
+	templateVerification,
+	templateCommentNotification,
+	templateReplyNotification,
+	templateReportNotification,
+}
 
 // TemplateData holds data for email templates
+// Date is Comment.Date formatted in the recipient's timezone.
 type TemplateData struct {
-	BrandName    string
-	DashboardURL string
-	Introduction string
-	ButtonText   string
-	ButtonURL    string
-	Epilogue     string
+	Locale         string
+	BrandName      string
+	DashboardURL   string
+	ButtonText     string
+	ButtonURL      string
+	Comment        CommentData
+	Date           string
+	UnsubscribeURL string
+	Moderation     ModerationLinks
+	Report         ReportData
 }
 
-// generateTemplate creates the HTML email template
-// This is the same template as your Node.js version
-func generateTemplate(data TemplateData) string {
-	buttonHTML := ""
-	if data.ButtonURL != "" {
-		buttonHTML = fmt.Sprintf(`
-			<br/>
-			<table role="presentation" border="0" cellpadding="0" cellspacing="0" class="btn btn-primary">
-				<tbody>
-					<tr>
-						<td align="center">
-							<table role="presentation" border="0" cellpadding="0" cellspacing="0">
-								<tbody>
-									<tr>
-										<td>
-											<a href="%s" target="_blank">%s</a> 
-										</td>
-									</tr>
-								</tbody>
-							</table>
-						</td>
-					</tr>
-				</tbody>
-			</table>
-			<br/>
-		`, data.ButtonURL, data.ButtonText)
+// htmlFuncs and textFuncs are available in the email templates
+// Comment bodies are stored as sanitized HTML.
+// {{t .Locale "message" args...}} translates a message.
+var (
+	htmlFuncs = htmltemplate.FuncMap{
+		"t": i18n.T,
+		"commentHTML": func(body string) htmltemplate.HTML {
+			return htmltemplate.HTML(utils.SanitizeComment(body))
+		},
+	}
+	textFuncs = texttemplate.FuncMap{
+		"t":           i18n.T,
+		"commentText": utils.HTMLToText,
 	}
+)
 
-	return fmt.Sprintf(`
-<!doctype html>
-<html lang="en">
-<head>
-	<meta name="viewport" content="width=device-width, initial-scale=1.0">
-	<meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
-	<title>%s</title>
-	<style media="all" type="text/css">
-		body {
-			font-family: Helvetica, sans-serif;
-			-webkit-font-smoothing: antialiased;
-			font-size: 14px;
-			line-height: 1.3;
-			-ms-text-size-adjust: 100%%;
-			-webkit-text-size-adjust: 100%%;
-			background-color: #f5f5f5;
-			margin: 0;
-			padding: 0;
+// blankLinesRegex matches runs of empty lines in rendered plain-text emails
+var blankLinesRegex = regexp.MustCompile(`\n\s*\n(\s*\n)+`)
+
+// templates holds the parsed HTML and plain-text version of every email
+type templates struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+// loadTemplates parses the email templates
+// Files in dir (MAIL_TEMPLATE_DIR) replace the embedded ones with the same name.
+// A broken override is logged and the embedded template is used instead.
+func loadTemplates(dir string) *templates {
+	t := &templates{
+		html: make(map[string]*htmltemplate.Template),
+		text: make(map[string]*texttemplate.Template),
+	}
+
+	for _, name := range templateNames {
+		html, err := parseHTML(dir, name)
+		if err != nil && dir != "" {
+			logger.Error(err, "Invalid email template override, using the default: "+name+".html")
+			html, err = parseHTML("", name)
 		}
-		.container {
-			margin: 0 auto !important;
-			max-width: 400px;
-			padding: 24px 0;
+		if err != nil {
+			panic(err)
 		}
-		.main {
-			background: #ffffff;
-			border: 1px solid #eaebed;
-			border-radius: 14px;
-			padding: 24px 35px;
+		t.html[name] = html
+
+		text, err := parseText(dir, name)
+		if err != nil && dir != "" {
+			logger.Error(err, "Invalid email template override, using the default: "+name+".txt")
+			text, err = parseText("", name)
 		}
-		h2 {
-			font-size: 18px;
-			font-weight: 700;
-			margin: 12px 0 16px;
-			text-align: center;
+		if err != nil {
+			panic(err)
+		}
+		t.text[name] = text
+	}
+
+	return t
+}
+
+// parseHTML parses the HTML layout together with one email template
+func parseHTML(dir, name string) (*htmltemplate.Template, error) {
+	tmpl := htmltemplate.New(name).Funcs(htmlFuncs)
+	for _, file := range []string{"layout.html", name + ".html"} {
+		source, err := readTemplate(dir, file)
+		if err != nil {
+			return nil, err
 		}
-		p {
-			margin: 0 0 16px;
+		if _, err := tmpl.Parse(source); err != nil {
+			return nil, err
 		}
-		.btn-primary a {
-			background-color: #1677ff;
-			border: solid 2px #1677ff;
-			border-radius: 8px;
-			color: #ffffff;
-			display: inline-block;
-			font-size: 16px;
-			font-weight: bold;
-			padding: 10px 20px;
-			text-decoration: none;
+	}
+	return tmpl, nil
+}
+
+// parseText parses the plain-text layout together with one email template
+func parseText(dir, name string) (*texttemplate.Template, error) {
+	tmpl := texttemplate.New(name).Funcs(textFuncs)
+	for _, file := range []string{"layout.txt", name + ".txt"} {
+		source, err := readTemplate(dir, file)
+		if err != nil {
+			return nil, err
 		}
-		.btn-primary a:hover {
-			background-color: #4096ff;
+		if _, err := tmpl.Parse(source); err != nil {
+			return nil, err
 		}
-		.footer {
-			text-align: center;
-			padding: 24px;
-			color: #9a9ea6;
-			font-size: 14px;
+	}
+	return tmpl, nil
+}
+
+// readTemplate reads a template file, preferring the override directory
+func readTemplate(dir, file string) (string, error) {
+	if dir != "" {
+		source, err := os.ReadFile(filepath.Join(dir, file))
+		if err == nil {
+			return string(source), nil
 		}
-		.logo {
-			text-align: center;
-			margin-bottom: 20px;
+		if !os.IsNotExist(err) {
+			return "", err
 		}
-	</style>
-</head>
-<body>
-	<div class="container">
-		<div class="main">
-			<div class="logo">
-				<img width="50" height="auto" src="%s/email-logo.png" alt="%s" />
-				<h2>%s</h2>
-			</div>
-			<p>%s</p>
-			%s
-			<p>%s</p>
-		</div>
-		<div class="footer">
-			%s
-		</div>
-	</div>
-</body>
-</html>
-`, data.BrandName, data.DashboardURL, data.BrandName, data.BrandName, 
-   data.Introduction, buttonHTML, data.Epilogue, data.BrandName)
+	}
+
+	source, err := embeddedTemplates.ReadFile("templates/" + file)
+	return string(source), err
 }
 
+// render renders the HTML and plain-text versions of an email
+func (t *templates) render(name string, data TemplateData) (string, string, error) {
+	var html bytes.Buffer
+	if err := t.html[name].ExecuteTemplate(&html, "layout", data); err != nil {
+		return "", "", err
+	}
+
+	var text bytes.Buffer
+	if err := t.text[name].ExecuteTemplate(&text, "layout", data); err != nil {
+		return "", "", err
+	}
+
+	plain := blankLinesRegex.ReplaceAllString(text.String(), "\n\n")
+	return html.String(), strings.TrimSpace(plain) + "\n", nil
+}
@@ -0,0 +1,170 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"zoomment-server/internal/models"
+)
+
+func TestRenderEscapesCommentData(t *testing.T) {
+	tmpl := loadTemplates("")
+
+	html, text, err := tmpl.render(templateCommentNotification, TemplateData{
+		BrandName: "Zoomment",
+		ButtonURL: "https://dashboard.example.com/auth",
+		Date:      "01 Feb 2025 - 10:00",
+		Comment: CommentData{
+			Author:  `<img src=x onerror=alert(1)>`,
+			PageURL: "https://example.com/post",
+			Body:    `<p>Nice <b>post</b></p><script>alert(1)</script>`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	if strings.Contains(html, "<img src=x") || !strings.Contains(html, "&lt;img src=x") {
+		t.Errorf("author is not escaped in HTML:\n%s", html)
+	}
+	if strings.Contains(html, "<script>") {
+		t.Errorf("comment body is not sanitized in HTML:\n%s", html)
+	}
+	if !strings.Contains(html, "Nice <b>post</b>") {
+		t.Errorf("comment body formatting is lost in HTML:\n%s", html)
+	}
+
+	for _, want := range []string{"User: <img src=x onerror=alert(1)>", "Nice post", "https://dashboard.example.com/auth"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("plain text does not contain %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "<p>") || strings.Contains(text, "\n\n\n") {
+		t.Errorf("plain text is not clean:\n%s", text)
+	}
+}
+
+func TestRenderAllTemplates(t *testing.T) {
+	tmpl := loadTemplates("")
+
+	for _, name := range templateNames {
+		t.Run(name, func(t *testing.T) {
+			html, text, err := tmpl.render(name, TemplateData{BrandName: "Zoomment"})
+			if err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+			if !strings.Contains(html, "<html") || strings.TrimSpace(text) == "" {
+				t.Errorf("render() returned an incomplete email")
+			}
+		})
+	}
+}
+
+func TestRenderTranslates(t *testing.T) {
+	html, text, err := loadTemplates("").render(templateReplyNotification, TemplateData{
+		Locale:         "de",
+		BrandName:      "Zoomment",
+		Comment:        CommentData{Author: "Anna", Body: "Danke!"},
+		UnsubscribeURL: "https://api.example.com/unsubscribe",
+	})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	for _, want := range []string{`<html lang="de">`, "Anna hat auf Ihren Kommentar geantwortet!", ">Abmelden</a>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML does not contain %q", want)
+		}
+	}
+	if !strings.Contains(text, "Abmelden: https://api.example.com/unsubscribe") {
+		t.Errorf("plain text is not translated:\n%s", text)
+	}
+}
+
+func TestRenderModerationLinks(t *testing.T) {
+	tmpl := loadTemplates("")
+	links := ModerationLinks{Approve: "https://api.example.com/api/moderate/a?x=1&y=2", Ban: "https://api.example.com/api/moderate/b"}
+
+	html, text, err := tmpl.render(templateCommentNotification, TemplateData{BrandName: "Zoomment", Moderation: links})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	for _, want := range []string{`href="https://api.example.com/api/moderate/a?x=1&amp;y=2"`, ">Approve</a>", ">Ban author</a>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML does not contain %q", want)
+		}
+	}
+	if strings.Contains(html, "Mark as spam") {
+		t.Error("HTML contains a button for a missing link")
+	}
+	if !strings.Contains(text, "Approve: https://api.example.com/api/moderate/a?x=1&y=2") {
+		t.Errorf("plain text lacks the approve link:\n%s", text)
+	}
+
+	html, _, err = tmpl.render(templateCommentNotification, TemplateData{BrandName: "Zoomment"})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	if strings.Contains(html, "/api/moderate/") || strings.Contains(html, "Ban author") {
+		t.Error("HTML contains moderation buttons without links")
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2025, time.March, 9, 22, 30, 0, 0, time.UTC)
+
+	if got := formatDate(date, "Asia/Tokyo"); got != "10 Mar 2025 - 07:30" {
+		t.Errorf("formatDate() in Tokyo = %q", got)
+	}
+	if got := formatDate(date, "America/New_York"); got != "09 Mar 2025 - 18:30" {
+		t.Errorf("formatDate() in New York = %q", got)
+	}
+}
+
+func TestTemplateOverrides(t *testing.T) {
+	dir := t.TempDir()
+	override := `{{define "content"}}Custom sign-in for {{.BrandName}}{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "magic_link.txt"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A broken override falls back to the built-in template
+	if err := os.WriteFile(filepath.Join(dir, "magic_link.html"), []byte(`{{define "content"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	html, text, err := loadTemplates(dir).render(templateMagicLink, TemplateData{BrandName: "Zoomment"})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	if !strings.Contains(text, "Custom sign-in for Zoomment") {
+		t.Errorf("text override is not used:\n%s", text)
+	}
+	if !strings.Contains(html, "Click the link below") {
+		t.Errorf("broken HTML override did not fall back to the default:\n%s", html)
+	}
+}
+
+func TestRenderReportNotification(t *testing.T) {
+	html, text, err := loadTemplates("").render(templateReportNotification, TemplateData{
+		Locale:    "de",
+		BrandName: "Zoomment",
+		Comment:   CommentData{Author: "Troll", Body: "<p>Go away</p>"},
+		Report:    ReportData{Reason: reportReasons[models.ReportHarassment], Count: 3, Hidden: true},
+	})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	for _, want := range []string{"3 Leser haben einen Kommentar gemeldet", "Belästigung"} {
+		if !strings.Contains(html, want) || !strings.Contains(text, want) {
+			t.Errorf("email does not contain %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Details") {
+		t.Errorf("plain text shows empty details:\n%s", text)
+	}
+}
@@ -0,0 +1,183 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/logger"
+)
+
+// Transport delivers a composed email
+// from and to are the envelope addresses; msg writes the full RFC 5322 message.
+type Transport interface {
+	Send(from string, to []string, msg io.WriterTo) error
+}
+
+// Transport names accepted in MAIL_TRANSPORT
+const (
+	TransportSMTP     = "smtp"
+	TransportSendmail = "sendmail"
+	TransportFile     = "file"
+	TransportLog      = "log"
+	TransportMemory   = "memory"
+)
+
+// NewTransport creates the transport selected in the config
+// Returns nil when SMTP is selected but no bot email is configured.
+func NewTransport(cfg *config.Config) (Transport, error) {
+	switch cfg.MailTransport {
+	case "", TransportSMTP:
+		if cfg.BotEmail.Address == "" {
+			return nil, nil
+		}
+		return &SMTPTransport{dialer: gomail.NewDialer(
+			cfg.BotEmail.Host,
+			cfg.BotEmail.Port,
+			cfg.BotEmail.Address,
+			cfg.BotEmail.Password,
+		)}, nil
+	case TransportSendmail:
+		return &SendmailTransport{Path: cfg.MailSendmailPath}, nil
+	case TransportFile:
+		return &FileTransport{Dir: cfg.MailFileDir}, nil
+	case TransportLog:
+		return &LogTransport{}, nil
+	case TransportMemory:
+		return Captured, nil
+	}
+
+	return nil, fmt.Errorf("unknown mail transport %q", cfg.MailTransport)
+}
+
+// SMTPTransport sends emails through an SMTP server
+type SMTPTransport struct {
+	dialer *gomail.Dialer
+}
+
+// Send implements Transport
+func (t *SMTPTransport) Send(from string, to []string, msg io.WriterTo) error {
+	sender, err := t.dialer.Dial()
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	return sender.Send(from, to, msg)
+}
+
+// SendmailTransport pipes emails into a sendmail-compatible binary
+type SendmailTransport struct {
+	Path string
+}
+
+// Send implements Transport
+func (t *SendmailTransport) Send(from string, to []string, msg io.WriterTo) error {
+	var raw bytes.Buffer
+	if _, err := msg.WriteTo(&raw); err != nil {
+		return err
+	}
+
+	// -i: a line with a single dot doesn't end the message
+	// -f: envelope sender; "--" so recipients are never read as flags
+	args := append([]string{"-i", "-f", from, "--"}, to...)
+	cmd := exec.Command(t.Path, args...)
+	cmd.Stdin = &raw
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sendmail: %w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// FileTransport writes every email as an .eml file into a directory
+// Handy in development: open the files in any mail client.
+type FileTransport struct {
+	Dir string
+}
+
+// Send implements Transport
+func (t *FileTransport) Send(from string, to []string, msg io.WriterTo) error {
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(t.Dir, time.Now().Format("20060102-150405")+"-*.eml")
+	if err != nil {
+		return err
+	}
+
+	if _, err := msg.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LogTransport writes emails to the application log instead of sending them
+type LogTransport struct{}
+
+// Send implements Transport
+func (t *LogTransport) Send(from string, to []string, msg io.WriterTo) error {
+	var raw bytes.Buffer
+	if _, err := msg.WriteTo(&raw); err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("📧 Email from %s to %v:\n%s", from, to, raw.String()))
+	return nil
+}
+
+// CapturedEmail is an email kept by MemoryTransport
+type CapturedEmail struct {
+	From string
+	To   []string
+	Raw  []byte
+}
+
+// MemoryTransport keeps emails in memory so tests can assert on them
+type MemoryTransport struct {
+	mu     sync.Mutex
+	emails []CapturedEmail
+}
+
+// Captured is the MemoryTransport used when MAIL_TRANSPORT=memory
+var Captured = &MemoryTransport{}
+
+// Send implements Transport
+func (t *MemoryTransport) Send(from string, to []string, msg io.WriterTo) error {
+	var raw bytes.Buffer
+	if _, err := msg.WriteTo(&raw); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.emails = append(t.emails, CapturedEmail{
+		From: from,
+		To:   append([]string(nil), to...),
+		Raw:  raw.Bytes(),
+	})
+	return nil
+}
+
+// Emails returns the emails captured so far
+func (t *MemoryTransport) Emails() []CapturedEmail {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]CapturedEmail(nil), t.emails...)
+}
+
+// Reset forgets all captured emails
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.emails = nil
+}
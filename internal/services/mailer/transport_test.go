@@ -0,0 +1,144 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/models"
+)
+
+func testMessage() *models.OutboxMessage {
+	return &models.OutboxMessage{
+		From:    "Zoomment <bot@example.com>",
+		To:      "reader@example.com",
+		Subject: "Someone replied to your comment",
+		HTML:    "<p>Hello</p>",
+		Text:    "Hello",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+	}
+}
+
+func TestMemoryTransport(t *testing.T) {
+	transport := &MemoryTransport{}
+	m := NewWithTransport(&config.Config{BrandName: "Zoomment"}, transport)
+
+	if err := m.Deliver(testMessage()); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	emails := transport.Emails()
+	if len(emails) != 1 {
+		t.Fatalf("captured %d emails, want 1", len(emails))
+	}
+
+	email := emails[0]
+	if email.From != "bot@example.com" {
+		t.Errorf("envelope from = %q, want %q", email.From, "bot@example.com")
+	}
+	if len(email.To) != 1 || email.To[0] != "reader@example.com" {
+		t.Errorf("envelope to = %v, want [reader@example.com]", email.To)
+	}
+
+	raw := string(email.Raw)
+	for _, want := range []string{
+		"Subject: Someone replied to your comment",
+		"List-Unsubscribe: <https://example.com/unsubscribe>",
+		"<p>Hello</p>",
+		"multipart/alternative",
+		"Content-Type: text/plain",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("message does not contain %q", want)
+		}
+	}
+
+	transport.Reset()
+	if len(transport.Emails()) != 0 {
+		t.Error("Reset() kept captured emails")
+	}
+}
+
+func TestFileTransport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := NewWithTransport(&config.Config{}, &FileTransport{Dir: dir})
+
+	if err := m.Deliver(testMessage()); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("found %d .eml files, want 1 (err: %v)", len(files), err)
+	}
+
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), "To: reader@example.com") {
+		t.Errorf("file does not contain the recipient:\n%s", raw)
+	}
+}
+
+func TestSendmailTransport(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+
+	// A fake sendmail that records its arguments and input
+	script := filepath.Join(dir, "sendmail")
+	content := "#!/bin/sh\necho \"$@\" > " + out + ".args\ncat > " + out + "\n"
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewWithTransport(&config.Config{}, &SendmailTransport{Path: script})
+	if err := m.Deliver(testMessage()); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	args, err := os.ReadFile(out + ".args")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(args)); got != "-i -f bot@example.com -- reader@example.com" {
+		t.Errorf("sendmail args = %q", got)
+	}
+
+	raw, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), "<p>Hello</p>") {
+		t.Errorf("sendmail input does not contain the body:\n%s", raw)
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "smtp without bot email", cfg: config.Config{MailTransport: "smtp"}, wantNil: true},
+		{name: "smtp", cfg: config.Config{MailTransport: "smtp", BotEmail: config.EmailConfig{Address: "bot@example.com"}}},
+		{name: "file", cfg: config.Config{MailTransport: "file", MailFileDir: "mail"}},
+		{name: "memory", cfg: config.Config{MailTransport: "memory"}},
+		{name: "unknown", cfg: config.Config{MailTransport: "pigeon"}, wantNil: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewTransport(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewTransport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (transport == nil) != tt.wantNil {
+				t.Errorf("NewTransport() = %v, wantNil %v", transport, tt.wantNil)
+			}
+		})
+	}
+}
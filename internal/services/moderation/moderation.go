@@ -0,0 +1,155 @@
+package moderation
+
+import (
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+)
+
+// DefaultTrustThreshold is the reputation score that makes an author
+// trusted, when a site doesn't set its own
+const DefaultTrustThreshold = 3
+
+// InitialStatus decides the moderation status of a newly submitted comment
+// site is nil when the comment is posted on a domain that isn't registered.
+// rep is the author's reputation on the site (nil if they have none).
+func InitialStatus(site *models.Site, isVerified bool, rep *models.Reputation) string {
+	if site == nil {
+		return models.CommentStatusApproved
+	}
+
+	// The owner's own decisions and the author's reputation come first
+	if rep != nil && rep.Pin == models.PinUntrusted {
+		return models.CommentStatusPending
+	}
+	if isVerified && Trusted(site, rep) {
+		return models.CommentStatusApproved
+	}
+	if site.Trust != nil && site.Trust.Enabled && (rep == nil || rep.Approved == 0) {
+		return models.CommentStatusPending
+	}
+
+	switch site.ModerationMode {
+	case models.ModerationAll:
+		return models.CommentStatusPending
+	case models.ModerationGuests:
+		if !isVerified {
+			return models.CommentStatusPending
+		}
+	}
+
+	return models.CommentStatusApproved
+}
+
+// Trusted reports whether an author's comments skip moderation on a site
+// A pin decides; otherwise trust must be enabled and the author needs an
+// approved comment and a score of at least the site's threshold.
+func Trusted(site *models.Site, rep *models.Reputation) bool {
+	if rep == nil {
+		return false
+	}
+	switch rep.Pin {
+	case models.PinTrusted:
+		return true
+	case models.PinUntrusted:
+		return false
+	}
+	if site == nil || site.Trust == nil || !site.Trust.Enabled || rep.Approved == 0 {
+		return false
+	}
+
+	threshold := site.Trust.Threshold
+	if threshold <= 0 {
+		threshold = DefaultTrustThreshold
+	}
+	return rep.Score() >= threshold
+}
+
+// RecordStatus counts a comment's move from one status to another in the
+// reputation of its author. from is "" for comments that weren't counted
+// yet and to is "" to stop counting one.
+func RecordStatus(site *models.Site, email, from, to string) error {
+	if site == nil || email == "" {
+		return nil
+	}
+	approved, rejected := statusDelta(from, to)
+	if approved == 0 && rejected == 0 {
+		return nil
+	}
+	return repository.UpdateReputation(site.ID, email, approved, rejected, 0)
+}
+
+// statusDelta returns how a move from one status to another changes the
+// approved and rejected counts of a reputation
+func statusDelta(from, to string) (approved, rejected int) {
+	approved = statusCount(to, models.CommentStatusApproved) - statusCount(from, models.CommentStatusApproved)
+	rejected = statusCount(to, models.CommentStatusRejected) - statusCount(from, models.CommentStatusRejected)
+	return approved, rejected
+}
+
+// statusCount returns 1 if a status falls in a reputation bucket (approved
+// or rejected, which spam is part of), else 0
+func statusCount(status, bucket string) int {
+	if status == models.CommentStatusSpam {
+		status = models.CommentStatusRejected
+	}
+	if status == bucket {
+		return 1
+	}
+	return 0
+}
+
+// SetStatus moves comments of a site to a new moderation status
+// Comments that don't belong to the site are ignored.
+// Returns the comments that actually changed, with their previous status.
+func SetStatus(site *models.Site, ids []primitive.ObjectID, status string) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := mgm.Coll(&models.Comment{}).SimpleFind(&comments, bson.M{
+		"_id":    bson.M{"$in": ids},
+		"domain": site.Domain,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make([]models.Comment, 0, len(comments))
+	changedIDs := make([]primitive.ObjectID, 0, len(comments))
+	for _, comment := range comments {
+		if comment.CurrentStatus() == status {
+			continue
+		}
+		changed = append(changed, comment)
+		changedIDs = append(changedIDs, comment.ID)
+	}
+
+	if len(changedIDs) == 0 {
+		return changed, nil
+	}
+
+	_, err = mgm.Coll(&models.Comment{}).UpdateMany(mgm.Ctx(),
+		bson.M{"_id": bson.M{"$in": changedIDs}},
+		bson.M{"$set": bson.M{"status": status, "counted": true, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Moderators decide: the comments now count in their authors' reputation
+	for i := range changed {
+		from := ""
+		if changed[i].Counted {
+			from = changed[i].CurrentStatus()
+		}
+		if err := RecordStatus(site, changed[i].Email, from, status); err != nil {
+			logger.Error(err, "Failed to update reputation")
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,155 @@
+package moderation
+
+import (
+	"testing"
+
+	"zoomment-server/internal/models"
+)
+
+func TestInitialStatus(t *testing.T) {
+	trustSite := &models.Site{
+		ModerationMode: models.ModerationAll,
+		Trust:          &models.TrustSettings{Enabled: true},
+	}
+
+	tests := []struct {
+		name       string
+		site       *models.Site
+		isVerified bool
+		reputation *models.Reputation
+		expected   string
+	}{
+		{
+			name:     "unregistered domain is published",
+			site:     nil,
+			expected: models.CommentStatusApproved,
+		},
+		{
+			name:     "legacy site without mode is published",
+			site:     &models.Site{},
+			expected: models.CommentStatusApproved,
+		},
+		{
+			name:       "mode all holds every comment",
+			site:       &models.Site{ModerationMode: models.ModerationAll},
+			isVerified: true,
+			expected:   models.CommentStatusPending,
+		},
+		{
+			name:     "mode guests holds unverified authors",
+			site:     &models.Site{ModerationMode: models.ModerationGuests},
+			expected: models.CommentStatusPending,
+		},
+		{
+			name:       "mode guests publishes verified authors",
+			site:       &models.Site{ModerationMode: models.ModerationGuests},
+			isVerified: true,
+			expected:   models.CommentStatusApproved,
+		},
+		{
+			name:       "trust holds first-time authors",
+			site:       trustSite,
+			isVerified: true,
+			expected:   models.CommentStatusPending,
+		},
+		{
+			name:       "trust holds authors without approved comments",
+			site:       trustSite,
+			isVerified: true,
+			reputation: &models.Reputation{Rejected: 1},
+			expected:   models.CommentStatusPending,
+		},
+		{
+			name:       "trust publishes trusted authors in mode all",
+			site:       trustSite,
+			isVerified: true,
+			reputation: &models.Reputation{Approved: 3},
+			expected:   models.CommentStatusApproved,
+		},
+		{
+			name:       "trust needs a verified author",
+			site:       trustSite,
+			reputation: &models.Reputation{Approved: 3},
+			expected:   models.CommentStatusPending,
+		},
+		{
+			name:       "trust falls back to the mode below the threshold",
+			site:       trustSite,
+			isVerified: true,
+			reputation: &models.Reputation{Approved: 4, Reports: 2},
+			expected:   models.CommentStatusPending,
+		},
+		{
+			name:       "pinned trusted author skips moderation",
+			site:       &models.Site{ModerationMode: models.ModerationAll},
+			isVerified: true,
+			reputation: &models.Reputation{Pin: models.PinTrusted},
+			expected:   models.CommentStatusApproved,
+		},
+		{
+			name:       "pinned untrusted author is always held",
+			site:       &models.Site{},
+			isVerified: true,
+			reputation: &models.Reputation{Approved: 10, Pin: models.PinUntrusted},
+			expected:   models.CommentStatusPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := InitialStatus(tt.site, tt.isVerified, tt.reputation)
+			if result != tt.expected {
+				t.Errorf("InitialStatus() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTrusted(t *testing.T) {
+	site := &models.Site{Trust: &models.TrustSettings{Enabled: true, Threshold: 5}}
+
+	tests := []struct {
+		name       string
+		site       *models.Site
+		reputation *models.Reputation
+		expected   bool
+	}{
+		{name: "no reputation", site: site, expected: false},
+		{name: "at threshold", site: site, reputation: &models.Reputation{Approved: 6, Reports: 1}, expected: true},
+		{name: "below threshold", site: site, reputation: &models.Reputation{Approved: 6, Rejected: 1}, expected: false},
+		{name: "trust disabled", site: &models.Site{}, reputation: &models.Reputation{Approved: 50}, expected: false},
+		{name: "default threshold", site: &models.Site{Trust: &models.TrustSettings{Enabled: true}}, reputation: &models.Reputation{Approved: 3}, expected: true},
+		{name: "pinned trusted", site: &models.Site{}, reputation: &models.Reputation{Pin: models.PinTrusted}, expected: true},
+		{name: "pinned untrusted", site: site, reputation: &models.Reputation{Approved: 50, Pin: models.PinUntrusted}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := Trusted(tt.site, tt.reputation); result != tt.expected {
+				t.Errorf("Trusted() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatusDelta(t *testing.T) {
+	tests := []struct {
+		from, to           string
+		approved, rejected int
+	}{
+		{from: "", to: models.CommentStatusApproved, approved: 1},
+		{from: "", to: models.CommentStatusPending},
+		{from: "", to: models.CommentStatusSpam, rejected: 1},
+		{from: models.CommentStatusPending, to: models.CommentStatusRejected, rejected: 1},
+		{from: models.CommentStatusApproved, to: models.CommentStatusSpam, approved: -1, rejected: 1},
+		{from: models.CommentStatusSpam, to: models.CommentStatusRejected},
+		{from: models.CommentStatusApproved, to: "", approved: -1},
+	}
+
+	for _, tt := range tests {
+		approved, rejected := statusDelta(tt.from, tt.to)
+		if approved != tt.approved || rejected != tt.rejected {
+			t.Errorf("statusDelta(%q, %q) = %d, %d, want %d, %d", tt.from, tt.to, approved, rejected, tt.approved, tt.rejected)
+		}
+	}
+}
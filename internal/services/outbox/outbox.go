@@ -0,0 +1,85 @@
+package outbox
+
+import (
+	"fmt"
+	"time"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/services/mailer"
+	"zoomment-server/internal/utils"
+)
+
+const (
+	// pollInterval is how long an idle worker waits before looking for mail again
+	pollInterval = 5 * time.Second
+
+	// claimLease is how long a worker may hold a message before others retry it
+	claimLease = 2 * time.Minute
+
+	// Retry delays grow from retryBaseDelay up to retryMaxDelay
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+)
+
+// Start runs the outbox workers in the background
+// Each worker delivers due emails one at a time; failed emails are retried
+// with exponential backoff and dead-lettered after cfg.MailMaxAttempts.
+func Start(cfg *config.Config) {
+	mailService := mailer.New(cfg)
+
+	workers := cfg.MailWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		go work(mailService, cfg.MailMaxAttempts)
+	}
+}
+
+// work delivers messages until the outbox is empty, then waits and polls again
+func work(mailService *mailer.Mailer, maxAttempts int) {
+	for {
+		msg, err := repository.ClaimMail(claimLease)
+		if err != nil {
+			logger.Error(err, "Failed to claim email from outbox")
+			time.Sleep(pollInterval)
+			continue
+		}
+		if msg == nil {
+			time.Sleep(pollInterval)
+			continue
+		}
+
+		deliver(mailService, msg, maxAttempts)
+	}
+}
+
+// deliver sends one message and records the outcome
+func deliver(mailService *mailer.Mailer, msg *models.OutboxMessage, maxAttempts int) {
+	sendErr := mailService.Deliver(msg)
+	if sendErr == nil {
+		if err := repository.MarkMailSent(msg); err != nil {
+			logger.Error(err, "Failed to mark email as sent")
+		}
+		logger.Info("Email sent to " + msg.To + ": " + msg.Subject)
+		return
+	}
+
+	dead := msg.Attempts >= maxAttempts
+	nextAttempt := time.Now().Add(utils.ExponentialBackoff(msg.Attempts, retryBaseDelay, retryMaxDelay))
+
+	if err := repository.MarkMailFailed(msg, sendErr, nextAttempt, dead); err != nil {
+		logger.Error(err, "Failed to record email failure")
+	}
+
+	if dead {
+		logger.Error(sendErr, fmt.Sprintf("Email to %s dead-lettered after %d attempts", msg.To, msg.Attempts))
+		return
+	}
+	logger.Warn(fmt.Sprintf("Email to %s failed (attempt %d), retrying at %s: %v",
+		msg.To, msg.Attempts, nextAttempt.Format(time.RFC3339), sendErr))
+}
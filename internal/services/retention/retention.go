@@ -0,0 +1,40 @@
+package retention
+
+import (
+	"fmt"
+	"time"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/repository"
+)
+
+// purgeInterval is how often the retention job runs
+const purgeInterval = time.Hour
+
+// Start runs the retention job in the background
+// Soft-deleted comments older than cfg.DeletedCommentRetention are purged.
+func Start(cfg *config.Config) {
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			purge(cfg.DeletedCommentRetention)
+			<-ticker.C
+		}
+	}()
+}
+
+// purge removes comments deleted before the retention period
+func purge(retention time.Duration) {
+	removed, err := repository.PurgeDeletedComments(time.Now().Add(-retention))
+	if err != nil {
+		logger.Error(err, "Failed to purge deleted comments")
+		return
+	}
+
+	if removed > 0 {
+		logger.Info(fmt.Sprintf("🧹 Purged %d deleted comments", removed))
+	}
+}
@@ -0,0 +1,179 @@
+package spam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"zoomment-server/internal/models"
+)
+
+// SignalAkismet is the signal name of the Akismet provider
+const SignalAkismet = "akismet"
+
+const (
+	// akismetTimeout bounds a call to Akismet; comments wait for comment-check
+	akismetTimeout = 5 * time.Second
+
+	// Scores for Akismet's verdicts; "discard" is spam so blatant that
+	// Akismet suggests not even keeping it
+	akismetSpamScore    = 0.95
+	akismetDiscardScore = 1.0
+
+	// maxAkismetResponse is how much of a response is read
+	maxAkismetResponse = 1024
+)
+
+// Akismet asks Akismet about comments of sites with an Akismet key
+// Sites without a key are skipped. Moderator corrections are reported back
+// with submit-spam and submit-ham.
+type Akismet struct {
+	// BaseURL is the API root, e.g. https://rest.akismet.com/1.1
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewAkismet creates an Akismet provider for the API at baseURL
+func NewAkismet(baseURL string) *Akismet {
+	return &Akismet{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  &http.Client{Timeout: akismetTimeout},
+	}
+}
+
+// Check implements Checker
+func (a *Akismet) Check(site *models.Site, comment *models.Comment) ([]Signal, error) {
+	key := akismetKey(site)
+	if key == "" {
+		return nil, nil
+	}
+
+	resp, body, err := a.call("comment-check", akismetParams(key, site, comment))
+	if err != nil {
+		return nil, err
+	}
+
+	switch body {
+	case "true":
+		score := akismetSpamScore
+		if resp.Header.Get("X-akismet-pro-tip") == "discard" {
+			score = akismetDiscardScore
+		}
+		return []Signal{{Name: SignalAkismet, Score: score}}, nil
+	case "false":
+		return []Signal{{Name: SignalAkismet, Score: 0}}, nil
+	}
+	return nil, akismetError(resp, body)
+}
+
+// Learn implements Learner
+// Only tells Akismet when moderators disagree with it: spam it let through
+// is submitted as spam, comments it flagged that were approved as ham.
+func (a *Akismet) Learn(site *models.Site, comment *models.Comment, status string) error {
+	key := akismetKey(site)
+	if key == "" {
+		return nil
+	}
+
+	flagged := false
+	for _, reason := range comment.SpamReasons {
+		if reason == SignalAkismet {
+			flagged = true
+		}
+	}
+
+	var method string
+	switch {
+	case status == models.CommentStatusSpam && !flagged:
+		method = "submit-spam"
+	case status == models.CommentStatusApproved && flagged:
+		method = "submit-ham"
+	default:
+		return nil
+	}
+
+	resp, body, err := a.call(method, akismetParams(key, site, comment))
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(body, "Thanks") {
+		return akismetError(resp, body)
+	}
+	return nil
+}
+
+// call posts form parameters to an Akismet method
+// Returns the trimmed response body.
+func (a *Akismet) call(method string, params url.Values) (*http.Response, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), akismetTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.BaseURL+"/"+method, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "Zoomment/1.0")
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAkismetResponse))
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", fmt.Errorf("akismet %s: unexpected status %d", method, resp.StatusCode)
+	}
+	return resp, strings.TrimSpace(string(body)), nil
+}
+
+// akismetParams describes a comment the way Akismet expects it
+func akismetParams(key string, site *models.Site, comment *models.Comment) url.Values {
+	params := url.Values{
+		"api_key":              {key},
+		"blog":                 {"https://" + site.Domain},
+		"blog_charset":         {"UTF-8"},
+		"user_ip":              {comment.IP},
+		"user_agent":           {comment.UserAgent},
+		"permalink":            {comment.PageURL},
+		"comment_type":         {"comment"},
+		"comment_author":       {comment.Author},
+		"comment_author_email": {comment.Email},
+		"comment_content":      {comment.Body},
+	}
+	if comment.ParentID != nil {
+		params.Set("comment_type", "reply")
+	}
+	if site.Locale != "" {
+		params.Set("blog_lang", site.Locale)
+	}
+	if !comment.CreatedAt.IsZero() {
+		params.Set("comment_date_gmt", comment.CreatedAt.UTC().Format(time.RFC3339))
+	}
+	return params
+}
+
+// akismetKey returns the Akismet API key of a site ("" = not using Akismet)
+func akismetKey(site *models.Site) string {
+	if site.Spam == nil {
+		return ""
+	}
+	return site.Spam.AkismetKey
+}
+
+// akismetError explains an unexpected answer, e.g. "invalid" for a wrong key
+func akismetError(resp *http.Response, body string) error {
+	if help := resp.Header.Get("X-akismet-debug-help"); help != "" {
+		return errors.New("akismet: " + help)
+	}
+	return errors.New("akismet: unexpected response " + body)
+}
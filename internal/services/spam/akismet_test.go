@@ -0,0 +1,178 @@
+package spam
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"zoomment-server/internal/models"
+)
+
+// fakeAkismet answers like Akismet and records the calls it gets
+type fakeAkismet struct {
+	server *httptest.Server
+	calls  []string
+	form   map[string]string
+}
+
+func newFakeAkismet(t *testing.T, answer func(w http.ResponseWriter, r *http.Request)) *fakeAkismet {
+	fake := &fakeAkismet{}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm() error = %v", err)
+		}
+		fake.calls = append(fake.calls, r.URL.Path)
+		fake.form = make(map[string]string)
+		for key := range r.PostForm {
+			fake.form[key] = r.PostForm.Get(key)
+		}
+		answer(w, r)
+	}))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func akismetSite() *models.Site {
+	return &models.Site{Domain: "example.com", Spam: &models.SpamSettings{AkismetKey: "key123"}}
+}
+
+func akismetComment() *models.Comment {
+	return &models.Comment{
+		Author:    "Viagra-test-123",
+		Email:     "spam@example.net",
+		Body:      "<p>Buy now</p>",
+		PageURL:   "https://example.com/post",
+		IP:        "203.0.113.7",
+		UserAgent: "Mozilla/5.0",
+	}
+}
+
+func TestAkismetCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		header   string
+		expected float64
+		wantErr  bool
+	}{
+		{name: "ham", body: "false", expected: 0},
+		{name: "spam", body: "true", expected: akismetSpamScore},
+		{name: "blatant spam", body: "true", header: "discard", expected: akismetDiscardScore},
+		{name: "invalid key", body: "invalid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeAkismet(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.header != "" {
+					w.Header().Set("X-akismet-pro-tip", tt.header)
+				}
+				w.Write([]byte(tt.body))
+			})
+
+			signals, err := NewAkismet(fake.server.URL+"/").Check(akismetSite(), akismetComment())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Check() should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if len(signals) != 1 || signals[0].Name != SignalAkismet || signals[0].Score != tt.expected {
+				t.Errorf("Check() = %v, want score %v", signals, tt.expected)
+			}
+
+			if len(fake.calls) != 1 || fake.calls[0] != "/comment-check" {
+				t.Errorf("calls = %v", fake.calls)
+			}
+			expectedForm := map[string]string{
+				"api_key":              "key123",
+				"blog":                 "https://example.com",
+				"user_ip":              "203.0.113.7",
+				"user_agent":           "Mozilla/5.0",
+				"permalink":            "https://example.com/post",
+				"comment_type":         "comment",
+				"comment_author":       "Viagra-test-123",
+				"comment_author_email": "spam@example.net",
+				"comment_content":      "<p>Buy now</p>",
+			}
+			for key, value := range expectedForm {
+				if fake.form[key] != value {
+					t.Errorf("%s = %q, want %q", key, fake.form[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestAkismetSkipsSitesWithoutKey(t *testing.T) {
+	fake := newFakeAkismet(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("true"))
+	})
+	akismet := NewAkismet(fake.server.URL)
+
+	signals, err := akismet.Check(&models.Site{}, akismetComment())
+	if err != nil || signals != nil {
+		t.Errorf("Check() = %v, %v; want nothing", signals, err)
+	}
+	if err := akismet.Learn(&models.Site{}, akismetComment(), models.CommentStatusSpam); err != nil {
+		t.Errorf("Learn() error = %v", err)
+	}
+	if len(fake.calls) != 0 {
+		t.Errorf("Akismet was called: %v", fake.calls)
+	}
+}
+
+func TestAkismetLearn(t *testing.T) {
+	tests := []struct {
+		name     string
+		reasons  []string
+		status   string
+		expected string
+	}{
+		{name: "missed spam", status: models.CommentStatusSpam, expected: "/submit-spam"},
+		{name: "false positive", reasons: []string{SignalLinks, SignalAkismet}, status: models.CommentStatusApproved, expected: "/submit-ham"},
+		{name: "agreed spam", reasons: []string{SignalAkismet}, status: models.CommentStatusSpam},
+		{name: "agreed ham", status: models.CommentStatusApproved},
+		{name: "rejected", status: models.CommentStatusRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeAkismet(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("Thanks for making the web a better place."))
+			})
+
+			comment := akismetComment()
+			comment.SpamReasons = tt.reasons
+			if err := NewAkismet(fake.server.URL).Learn(akismetSite(), comment, tt.status); err != nil {
+				t.Fatalf("Learn() error = %v", err)
+			}
+
+			if tt.expected == "" {
+				if len(fake.calls) != 0 {
+					t.Errorf("calls = %v, want none", fake.calls)
+				}
+				return
+			}
+			if len(fake.calls) != 1 || fake.calls[0] != tt.expected {
+				t.Errorf("calls = %v, want %s", fake.calls, tt.expected)
+			}
+			if fake.form["comment_content"] != comment.Body {
+				t.Errorf("comment_content = %q", fake.form["comment_content"])
+			}
+		})
+	}
+}
+
+func TestAkismetServerError(t *testing.T) {
+	fake := newFakeAkismet(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	if _, err := NewAkismet(fake.server.URL).Check(akismetSite(), akismetComment()); err == nil {
+		t.Error("Check() should fail on a server error")
+	}
+}
@@ -0,0 +1,172 @@
+package spam
+
+import (
+	"math"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/utils"
+)
+
+// SignalBayes is the signal name of the classifier
+const SignalBayes = "bayes"
+
+const (
+	// minTraining is how many ham and spam comments a site needs before the
+	// classifier has an opinion
+	minTraining = 5
+
+	// interestingTokens is how many of the most telling tokens decide
+	interestingTokens = 15
+
+	// maxTokens limits the tokens taken from one comment
+	maxTokens = 500
+
+	// Robinson's smoothing: how much a token seen once is pulled toward 0.5
+	priorStrength = 1.0
+	priorProb     = 0.5
+)
+
+// TokenCount is how many ham and spam comments of a site contained a token
+type TokenCount struct {
+	Ham  int
+	Spam int
+}
+
+// Corpus stores what the classifier learned, per site
+type Corpus interface {
+	// Totals returns how many ham and spam comments the site was trained on
+	Totals(siteID primitive.ObjectID) (ham, spam int, err error)
+	// Counts returns the counts of tokens (missing tokens were never seen)
+	Counts(siteID primitive.ObjectID, tokens []string) (map[string]TokenCount, error)
+	// Add changes the totals and the counts of tokens by ham and spam
+	Add(siteID primitive.ObjectID, tokens []string, ham, spam int) error
+	// MarkTrained remembers what a comment was learned as ("" = nothing)
+	MarkTrained(commentID primitive.ObjectID, as string) error
+}
+
+// Bayes is a naive Bayes classifier trained on each site's moderation decisions
+// Approved comments are ham, comments moved to spam are spam.
+type Bayes struct {
+	Corpus Corpus
+}
+
+// Check implements Checker
+func (b *Bayes) Check(site *models.Site, comment *models.Comment) ([]Signal, error) {
+	ham, spam, err := b.Corpus.Totals(site.ID)
+	if err != nil || ham < minTraining || spam < minTraining {
+		return nil, err
+	}
+
+	tokens := features(comment)
+	counts, err := b.Corpus.Counts(site.ID, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only a leaning toward spam is evidence; a likely ham comment adds nothing
+	probability := classify(tokens, counts, ham, spam)
+	return []Signal{{Name: SignalBayes, Score: math.Max(0, 2*probability-1)}}, nil
+}
+
+// Learn implements Learner
+// A comment is learned once; when moderators change their mind it is unlearned first.
+func (b *Bayes) Learn(site *models.Site, comment *models.Comment, status string) error {
+	var target string
+	switch status {
+	case models.CommentStatusApproved:
+		target = models.CommentStatusApproved
+	case models.CommentStatusSpam:
+		target = models.CommentStatusSpam
+	}
+	if comment.TrainedAs == target {
+		return nil
+	}
+
+	var ham, spam int
+	switch comment.TrainedAs {
+	case models.CommentStatusApproved:
+		ham--
+	case models.CommentStatusSpam:
+		spam--
+	}
+	switch target {
+	case models.CommentStatusApproved:
+		ham++
+	case models.CommentStatusSpam:
+		spam++
+	}
+
+	if err := b.Corpus.Add(site.ID, features(comment), ham, spam); err != nil {
+		return err
+	}
+	if err := b.Corpus.MarkTrained(comment.ID, target); err != nil {
+		return err
+	}
+	comment.TrainedAs = target
+	return nil
+}
+
+// features returns the distinct tokens of a comment
+// Words of the body, plus the hosts it links to ("url:example.com").
+func features(comment *models.Comment) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(token string) {
+		if !seen[token] && len(tokens) < maxTokens {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	for _, word := range words(utils.HTMLToText(comment.Body)) {
+		if length := len([]rune(word)); length >= 2 && length <= 24 {
+			add(word)
+		}
+	}
+	for _, host := range linkHosts(comment.Body) {
+		add("url:" + host)
+	}
+	return tokens
+}
+
+// classify returns the probability that a comment with tokens is spam
+// Uses Robinson's smoothed token probabilities, combined over the tokens
+// that are furthest from neutral.
+func classify(tokens []string, counts map[string]TokenCount, hamTotal, spamTotal int) float64 {
+	probabilities := make([]float64, 0, len(tokens))
+	for _, token := range tokens {
+		count, ok := counts[token]
+		if !ok || count.Ham+count.Spam == 0 {
+			continue
+		}
+		spamFreq := float64(count.Spam) / float64(spamTotal)
+		hamFreq := float64(count.Ham) / float64(hamTotal)
+		p := spamFreq / (spamFreq + hamFreq)
+
+		n := float64(count.Ham + count.Spam)
+		p = (priorStrength*priorProb + n*p) / (priorStrength + n)
+		probabilities = append(probabilities, p)
+	}
+	if len(probabilities) == 0 {
+		return priorProb
+	}
+
+	sort.Slice(probabilities, func(i, j int) bool {
+		return math.Abs(probabilities[i]-0.5) > math.Abs(probabilities[j]-0.5)
+	})
+	if len(probabilities) > interestingTokens {
+		probabilities = probabilities[:interestingTokens]
+	}
+
+	// Sum logs to avoid underflow
+	var logSpam, logHam float64
+	for _, p := range probabilities {
+		p = math.Min(math.Max(p, 0.01), 0.99)
+		logSpam += math.Log(p)
+		logHam += math.Log(1 - p)
+	}
+	return 1 / (1 + math.Exp(logHam-logSpam))
+}
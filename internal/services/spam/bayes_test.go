@@ -0,0 +1,134 @@
+package spam
+
+import (
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/models"
+)
+
+// memoryCorpus keeps the training of one site in memory
+type memoryCorpus struct {
+	ham, spam int
+	counts    map[string]TokenCount
+	trained   map[primitive.ObjectID]string
+}
+
+func newMemoryCorpus() *memoryCorpus {
+	return &memoryCorpus{counts: make(map[string]TokenCount), trained: make(map[primitive.ObjectID]string)}
+}
+
+func (m *memoryCorpus) Totals(siteID primitive.ObjectID) (int, int, error) {
+	return m.ham, m.spam, nil
+}
+
+func (m *memoryCorpus) Counts(siteID primitive.ObjectID, tokens []string) (map[string]TokenCount, error) {
+	counts := make(map[string]TokenCount)
+	for _, token := range tokens {
+		if count, ok := m.counts[token]; ok {
+			counts[token] = count
+		}
+	}
+	return counts, nil
+}
+
+func (m *memoryCorpus) Add(siteID primitive.ObjectID, tokens []string, ham, spam int) error {
+	m.ham += ham
+	m.spam += spam
+	for _, token := range tokens {
+		count := m.counts[token]
+		count.Ham += ham
+		count.Spam += spam
+		m.counts[token] = count
+	}
+	return nil
+}
+
+func (m *memoryCorpus) MarkTrained(commentID primitive.ObjectID, as string) error {
+	m.trained[commentID] = as
+	return nil
+}
+
+func newComment(body string) *models.Comment {
+	comment := &models.Comment{Body: body}
+	comment.ID = primitive.NewObjectID()
+	return comment
+}
+
+func TestBayes(t *testing.T) {
+	site := &models.Site{}
+	site.ID = primitive.NewObjectID()
+	corpus := newMemoryCorpus()
+	bayes := &Bayes{Corpus: corpus}
+
+	check := func(body string) []Signal {
+		signals, err := bayes.Check(site, newComment(body))
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		return signals
+	}
+
+	if signals := check("buy cheap watches"); signals != nil {
+		t.Errorf("untrained classifier should have no opinion, got %v", signals)
+	}
+
+	for i := 0; i < minTraining; i++ {
+		spam := newComment(fmt.Sprintf("Buy cheap watches now https://shop%d.example casino bonus", i))
+		ham := newComment(fmt.Sprintf("Thanks for the article, the part about goroutines %d was helpful", i))
+		if err := bayes.Learn(site, spam, models.CommentStatusSpam); err != nil {
+			t.Fatal(err)
+		}
+		if err := bayes.Learn(site, ham, models.CommentStatusApproved); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spamSignals := check("Cheap casino bonus, buy now")
+	if len(spamSignals) != 1 || spamSignals[0].Score < 0.9 {
+		t.Errorf("spam scored %v, want at least 0.9", spamSignals)
+	}
+	hamSignals := check("Helpful article about goroutines, thanks")
+	if len(hamSignals) != 1 || hamSignals[0].Score != 0 {
+		t.Errorf("ham scored %v, want 0", hamSignals)
+	}
+}
+
+func TestBayesRelearn(t *testing.T) {
+	site := &models.Site{}
+	site.ID = primitive.NewObjectID()
+	corpus := newMemoryCorpus()
+	bayes := &Bayes{Corpus: corpus}
+	comment := newComment("hello world")
+
+	steps := []struct {
+		status    string
+		ham, spam int
+	}{
+		{status: models.CommentStatusSpam, ham: 0, spam: 1},
+		{status: models.CommentStatusSpam, ham: 0, spam: 1},
+		{status: models.CommentStatusApproved, ham: 1, spam: 0},
+		{status: models.CommentStatusRejected, ham: 0, spam: 0},
+	}
+	for _, step := range steps {
+		if err := bayes.Learn(site, comment, step.status); err != nil {
+			t.Fatal(err)
+		}
+		if corpus.ham != step.ham || corpus.spam != step.spam {
+			t.Errorf("after %s: totals = %d/%d, want %d/%d", step.status, corpus.ham, corpus.spam, step.ham, step.spam)
+		}
+		if count := corpus.counts["hello"]; count.Ham != step.ham || count.Spam != step.spam {
+			t.Errorf("after %s: token counts = %+v", step.status, count)
+		}
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	tokens := features(newComment(`<p>Hi a b <a href="https://Spam.example/x">click click</a></p>`))
+	expected := []string{"hi", "click", "https", "spam", "example", "url:spam.example"}
+	if fmt.Sprint(tokens) != fmt.Sprint(expected) {
+		t.Errorf("features() = %v, want %v", tokens, expected)
+	}
+}
@@ -0,0 +1,94 @@
+package spam
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/tokens"
+	"zoomment-server/internal/utils"
+)
+
+// Signal names of the bot trap
+const (
+	SignalHoneypot  = "honeypot"
+	SignalTooFast   = "too_fast"
+	SignalFormToken = "form_token"
+)
+
+const (
+	// DefaultMinSubmitSeconds is how long a form must be open before it's sent,
+	// when a site doesn't set its own
+	DefaultMinSubmitSeconds = 3
+
+	// FormTokenTTL is how long a rendered form can be sent
+	FormTokenTTL = 24 * time.Hour
+)
+
+// FormCheck is what the bot trap found out about a submitted form
+// Reason is empty for forms that look human. TokenID and ExpiresAt identify
+// the form token, which must only be used once.
+type FormCheck struct {
+	Reason    string
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+// IssueFormToken signs the moment a comment form was rendered for a domain
+func IssueFormToken(secret, domain string, now time.Time) (string, error) {
+	return tokens.Sign(secret, tokens.PurposeForm, jwt.MapClaims{
+		"jti":      utils.GenerateSecret(),
+		"domain":   domain,
+		"rendered": now.UnixMilli(),
+	}, FormTokenTTL)
+}
+
+// CheckForm looks for signs of a bot in a submitted comment form
+// A filled-in honeypot, a form sent faster than the site's minimum, and an
+// invalid form token are caught. Forms without a token pass unless the site
+// requires one. site is nil for unregistered domains.
+func CheckForm(secret, domain string, site *models.Site, honeypot, token string, now time.Time) FormCheck {
+	if honeypot != "" {
+		return FormCheck{Reason: SignalHoneypot}
+	}
+
+	if token == "" {
+		if requiresFormToken(site) {
+			return FormCheck{Reason: SignalFormToken}
+		}
+		return FormCheck{}
+	}
+
+	claims, err := tokens.Parse(secret, tokens.PurposeForm, token)
+	if err != nil || tokens.String(claims, "domain") != domain {
+		return FormCheck{Reason: SignalFormToken}
+	}
+	rendered, ok := claims["rendered"].(float64)
+	id := tokens.String(claims, "jti")
+	expiresAt, expErr := claims.GetExpirationTime()
+	if !ok || id == "" || expErr != nil || expiresAt == nil {
+		return FormCheck{Reason: SignalFormToken}
+	}
+
+	check := FormCheck{TokenID: id, ExpiresAt: expiresAt.Time}
+	if now.Sub(time.UnixMilli(int64(rendered))) < minSubmit(site) {
+		check.Reason = SignalTooFast
+	}
+	return check
+}
+
+// requiresFormToken reports whether forms of a site must carry a token
+// Strict mode is opt-in: widgets that don't fetch tokens keep working.
+func requiresFormToken(site *models.Site) bool {
+	return Enabled(site) && site.Spam != nil && site.Spam.RequireFormToken
+}
+
+// minSubmit returns how long a site's forms must be open before they're sent
+func minSubmit(site *models.Site) time.Duration {
+	seconds := DefaultMinSubmitSeconds
+	if site != nil && site.Spam != nil && site.Spam.MinSubmitSeconds > 0 {
+		seconds = site.Spam.MinSubmitSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,64 @@
+package spam
+
+import (
+	"testing"
+	"time"
+
+	"zoomment-server/internal/models"
+)
+
+func TestCheckForm(t *testing.T) {
+	rendered := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	token, err := IssueFormToken("secret", "example.com", rendered)
+	if err != nil {
+		t.Fatalf("IssueFormToken() error = %v", err)
+	}
+	slowSite := &models.Site{Spam: &models.SpamSettings{MinSubmitSeconds: 30}}
+	strictSite := &models.Site{Spam: &models.SpamSettings{RequireFormToken: true}}
+	uncheckedSite := &models.Site{Spam: &models.SpamSettings{Disabled: true, RequireFormToken: true}}
+
+	tests := []struct {
+		name     string
+		domain   string
+		site     *models.Site
+		honeypot string
+		token    string
+		after    time.Duration
+		expected string
+	}{
+		{name: "human", domain: "example.com", token: token, after: 20 * time.Second, expected: ""},
+		{name: "honeypot", domain: "example.com", honeypot: "https://spam.example", token: token, after: time.Minute, expected: SignalHoneypot},
+		{name: "too fast", domain: "example.com", token: token, after: time.Second, expected: SignalTooFast},
+		{name: "site minimum", domain: "example.com", site: slowSite, token: token, after: 20 * time.Second, expected: SignalTooFast},
+		{name: "other domain", domain: "other.com", token: token, after: time.Minute, expected: SignalFormToken},
+		{name: "forged token", domain: "example.com", token: "not-a-token", after: time.Minute, expected: SignalFormToken},
+		{name: "no token on unregistered domain", domain: "example.com", expected: ""},
+		{name: "no token on registered site", domain: "example.com", site: &models.Site{}, expected: ""},
+		{name: "no token on strict site", domain: "example.com", site: strictSite, expected: SignalFormToken},
+		{name: "no token without spam checks", domain: "example.com", site: uncheckedSite, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := CheckForm("secret", tt.domain, tt.site, tt.honeypot, tt.token, rendered.Add(tt.after))
+			if check.Reason != tt.expected {
+				t.Errorf("CheckForm() reason = %q, want %q", check.Reason, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckFormTokenID(t *testing.T) {
+	now := time.Now()
+	token, _ := IssueFormToken("secret", "example.com", now.Add(-time.Minute))
+	other, _ := IssueFormToken("secret", "example.com", now.Add(-time.Minute))
+
+	first := CheckForm("secret", "example.com", nil, "", token, now)
+	second := CheckForm("secret", "example.com", nil, "", other, now)
+	if first.TokenID == "" || first.TokenID == second.TokenID {
+		t.Errorf("tokens should have distinct IDs, got %q and %q", first.TokenID, second.TokenID)
+	}
+	if ttl := first.ExpiresAt.Sub(now); ttl <= 0 || ttl > FormTokenTTL {
+		t.Errorf("token expires in %v", ttl)
+	}
+}
@@ -0,0 +1,165 @@
+package spam
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/utils"
+)
+
+// Signal names of the heuristics
+const (
+	SignalLinks       = "links"
+	SignalBlockedTerm = "blocked_term"
+	SignalSpeed       = "speed"
+	SignalDuplicate   = "duplicate"
+	SignalNewEmail    = "new_email"
+)
+
+const (
+	// speedWindow is how far back posting speed is measured
+	speedWindow = time.Minute
+
+	// duplicateWindow is how far back identical bodies are looked for
+	duplicateWindow = 7 * 24 * time.Hour
+
+	// newEmailScore is the (weak) signal for authors the site hasn't seen before
+	newEmailScore = 0.15
+)
+
+// urlRegex finds links in a comment body (in href attributes and text)
+var urlRegex = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// History answers questions about earlier comments
+type History interface {
+	// RecentComments counts comments on a domain since a time by an email or an IP
+	RecentComments(domain, email, ip string, since time.Time) (int64, error)
+	// DuplicateComments counts comments on a domain since a time with a body hash
+	DuplicateComments(domain, bodyHash string, since time.Time) (int64, error)
+	// ApprovedComments counts the approved comments of an email on a domain
+	ApprovedComments(domain, email string) (int64, error)
+}
+
+// Heuristics scores comments by simple, well-known spam traits
+type Heuristics struct {
+	History History
+}
+
+// Check implements Checker
+func (h *Heuristics) Check(site *models.Site, comment *models.Comment) ([]Signal, error) {
+	signals := []Signal{
+		{Name: SignalLinks, Score: linkScore(len(links(comment.Body)))},
+		{Name: SignalBlockedTerm, Score: blockedTermScore(site, comment)},
+	}
+
+	since := time.Now().Add(-speedWindow)
+	recent, err := h.History.RecentComments(comment.Domain, comment.Email, comment.IP, since)
+	if err != nil {
+		return nil, err
+	}
+	// Edited comments are stored already and don't count against themselves
+	if recent > 0 && comment.CreatedAt.After(since) {
+		recent--
+	}
+	signals = append(signals, Signal{Name: SignalSpeed, Score: speedScore(recent)})
+
+	if comment.BodyHash != "" {
+		duplicates, err := h.History.DuplicateComments(comment.Domain, comment.BodyHash, time.Now().Add(-duplicateWindow))
+		if err != nil {
+			return nil, err
+		}
+		if duplicates > 0 {
+			signals = append(signals, Signal{Name: SignalDuplicate, Score: 0.7})
+		}
+	}
+
+	// Signed-in authors proved they own the address
+	if !comment.IsVerified {
+		approved, err := h.History.ApprovedComments(comment.Domain, comment.Email)
+		if err != nil {
+			return nil, err
+		}
+		if approved == 0 {
+			signals = append(signals, Signal{Name: SignalNewEmail, Score: newEmailScore})
+		}
+	}
+
+	return signals, nil
+}
+
+// linkScore grows with the number of links; one link is normal
+func linkScore(count int) float64 {
+	switch {
+	case count >= 8:
+		return 0.9
+	case count >= 4:
+		return 0.6
+	case count >= 2:
+		return 0.3
+	}
+	return 0
+}
+
+// speedScore grows with the comments the author posted in the last minute
+func speedScore(recent int64) float64 {
+	switch {
+	case recent >= 3:
+		return 0.8
+	case recent >= 1:
+		return 0.3
+	}
+	return 0
+}
+
+// blockedTermScore is high when the comment contains one of the site's blocked terms
+// Terms match whole words, ignoring case, in the body, author name and email.
+func blockedTermScore(site *models.Site, comment *models.Comment) float64 {
+	if site.Spam == nil || len(site.Spam.BlockedTerms) == 0 {
+		return 0
+	}
+
+	text := " " + strings.Join(words(utils.HTMLToText(comment.Body)+" "+comment.Author+" "+comment.Email), " ") + " "
+	for _, term := range site.Spam.BlockedTerms {
+		normalized := strings.Join(words(term), " ")
+		if normalized != "" && strings.Contains(text, " "+normalized+" ") {
+			return 0.9
+		}
+	}
+	return 0
+}
+
+// links returns the distinct URLs in a comment body
+func links(body string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, link := range urlRegex.FindAllString(body, -1) {
+		link = strings.TrimRight(link, ".,;:!?)")
+		if !seen[link] {
+			seen[link] = true
+			result = append(result, link)
+		}
+	}
+	return result
+}
+
+// linkHosts returns the hosts the links of a comment body point to
+func linkHosts(body string) []string {
+	var hosts []string
+	for _, link := range links(body) {
+		if parsed, err := url.Parse(link); err == nil && parsed.Hostname() != "" {
+			hosts = append(hosts, strings.ToLower(parsed.Hostname()))
+		}
+	}
+	return hosts
+}
+
+// words splits text into lowercase words
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
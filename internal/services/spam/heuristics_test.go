@@ -0,0 +1,122 @@
+package spam
+
+import (
+	"testing"
+	"time"
+
+	"zoomment-server/internal/models"
+)
+
+// fakeHistory returns fixed counts
+type fakeHistory struct {
+	recent     int64
+	duplicates int64
+	approved   int64
+}
+
+func (h fakeHistory) RecentComments(domain, email, ip string, since time.Time) (int64, error) {
+	return h.recent, nil
+}
+
+func (h fakeHistory) DuplicateComments(domain, bodyHash string, since time.Time) (int64, error) {
+	return h.duplicates, nil
+}
+
+func (h fakeHistory) ApprovedComments(domain, email string) (int64, error) {
+	return h.approved, nil
+}
+
+func TestHeuristics(t *testing.T) {
+	site := &models.Site{Spam: &models.SpamSettings{BlockedTerms: []string{"Cheap Pills"}}}
+	known := fakeHistory{approved: 3}
+
+	tests := []struct {
+		name     string
+		history  fakeHistory
+		comment  models.Comment
+		expected []string
+	}{
+		{
+			name:     "regular comment",
+			history:  known,
+			comment:  models.Comment{Body: "Nice post, see https://example.com"},
+			expected: nil,
+		},
+		{
+			name:    "many links",
+			history: known,
+			comment: models.Comment{Body: `<a href="https://a.example">a</a> https://b.example
+				https://c.example https://d.example`},
+			expected: []string{SignalLinks},
+		},
+		{
+			name:     "blocked term",
+			history:  known,
+			comment:  models.Comment{Body: "<b>cheap</b>   PILLS here"},
+			expected: []string{SignalBlockedTerm},
+		},
+		{
+			name:     "blocked term must be whole words",
+			history:  known,
+			comment:  models.Comment{Body: "cheap pillsbury"},
+			expected: nil,
+		},
+		{
+			name:     "posting fast",
+			history:  fakeHistory{recent: 1, approved: 3},
+			comment:  models.Comment{Body: "again"},
+			expected: []string{SignalSpeed},
+		},
+		{
+			name:     "edits don't count themselves",
+			history:  fakeHistory{recent: 1, approved: 3},
+			comment:  models.Comment{BaseModel: models.BaseModel{CreatedAt: time.Now()}, Body: "fixed a typo"},
+			expected: nil,
+		},
+		{
+			name:     "duplicate body",
+			history:  fakeHistory{duplicates: 1, approved: 3},
+			comment:  models.Comment{Body: "copy", BodyHash: BodyHash("copy")},
+			expected: []string{SignalDuplicate},
+		},
+		{
+			name:     "new email",
+			history:  fakeHistory{},
+			comment:  models.Comment{Body: "hello"},
+			expected: []string{SignalNewEmail},
+		},
+		{
+			name:     "verified authors are not new",
+			history:  fakeHistory{},
+			comment:  models.Comment{Body: "hello", IsVerified: true},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Heuristics{History: tt.history}
+			signals, err := h.Check(site, &tt.comment)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			reasons := Result{Signals: signals}.Reasons()
+			if len(reasons) != len(tt.expected) {
+				t.Fatalf("reasons = %v, want %v", reasons, tt.expected)
+			}
+			for i := range reasons {
+				if reasons[i] != tt.expected[i] {
+					t.Errorf("reasons = %v, want %v", reasons, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestLinks(t *testing.T) {
+	body := `<a href="https://Example.com/a">https://Example.com/a</a>, see http://other.example/b.`
+	hosts := linkHosts(body)
+	if len(hosts) != 2 || hosts[0] != "example.com" || hosts[1] != "other.example" {
+		t.Errorf("linkHosts() = %v", hosts)
+	}
+}
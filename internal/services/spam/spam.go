@@ -0,0 +1,172 @@
+// Package spam scores new comments and routes likely spam away from readers
+// A Pipeline asks several checkers (heuristics, a per-site naive Bayes
+// classifier...) for signals and combines them into one score.
+package spam
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/utils"
+)
+
+// Default thresholds, used when a site doesn't set its own
+const (
+	DefaultHoldScore = 0.5
+	DefaultSpamScore = 0.9
+)
+
+// Signal is one piece of evidence that a comment is spam
+// Score is how sure the checker is, from 0 (no evidence) to 1.
+type Signal struct {
+	Name  string
+	Score float64
+}
+
+// Result is the verdict of a pipeline
+type Result struct {
+	Score   float64
+	Signals []Signal
+}
+
+// Reasons returns the names of the signals that raised the score
+func (r Result) Reasons() []string {
+	var reasons []string
+	for _, signal := range r.Signals {
+		if signal.Score > 0 {
+			reasons = append(reasons, signal.Name)
+		}
+	}
+	return reasons
+}
+
+// Checker looks at a comment before it is stored
+// site is never nil: comments on unregistered domains aren't checked.
+type Checker interface {
+	Check(site *models.Site, comment *models.Comment) ([]Signal, error)
+}
+
+// Learner is a checker that learns from moderators' decisions
+type Learner interface {
+	Learn(site *models.Site, comment *models.Comment, status string) error
+}
+
+// Pipeline runs checkers and combines their signals
+type Pipeline struct {
+	checkers []Checker
+}
+
+// NewPipeline creates a pipeline of checkers
+func NewPipeline(checkers ...Checker) *Pipeline {
+	return &Pipeline{checkers: checkers}
+}
+
+// Default creates the built-in pipeline: heuristics, the per-site classifier
+// and Akismet (for sites with a key)
+func Default(cfg *config.Config) *Pipeline {
+	return NewPipeline(
+		&Heuristics{History: repositoryHistory{}},
+		&Bayes{Corpus: repositoryCorpus{}},
+		NewAkismet(cfg.AkismetBaseURL),
+	)
+}
+
+// Check scores a comment
+// A failing checker is logged and left out rather than blocking the comment.
+func (p *Pipeline) Check(site *models.Site, comment *models.Comment) Result {
+	var result Result
+	for _, checker := range p.checkers {
+		signals, err := checker.Check(site, comment)
+		if err != nil {
+			logger.Error(err, "Spam check failed")
+			continue
+		}
+		result.Signals = append(result.Signals, signals...)
+	}
+
+	scores := make([]float64, 0, len(result.Signals))
+	for _, signal := range result.Signals {
+		scores = append(scores, signal.Score)
+	}
+	result.Score = Combine(scores...)
+	return result
+}
+
+// Learn teaches the learners that moderators moved comments to status
+func (p *Pipeline) Learn(site *models.Site, comments []models.Comment, status string) {
+	for _, checker := range p.checkers {
+		learner, ok := checker.(Learner)
+		if !ok {
+			continue
+		}
+		for i := range comments {
+			if err := learner.Learn(site, &comments[i], status); err != nil {
+				logger.Error(err, "Failed to train spam classifier")
+			}
+		}
+	}
+}
+
+// Combine merges independent scores with a noisy-OR: the comment is spam
+// unless every signal is wrong, so weak signals add up.
+func Combine(scores ...float64) float64 {
+	notSpam := 1.0
+	for _, score := range scores {
+		notSpam *= 1 - clamp(score)
+	}
+	return 1 - notSpam
+}
+
+// Route returns the status of a comment that scored score
+// status is what the site's moderation mode gave it; spam checks only ever
+// make it stricter.
+func Route(site *models.Site, score float64, status string) string {
+	hold, spam := thresholds(site)
+	switch {
+	case score >= spam:
+		return models.CommentStatusSpam
+	case score >= hold && status == models.CommentStatusApproved:
+		return models.CommentStatusPending
+	}
+	return status
+}
+
+// Enabled reports whether comments of a site are checked
+func Enabled(site *models.Site) bool {
+	return site != nil && (site.Spam == nil || !site.Spam.Disabled)
+}
+
+// thresholds returns the hold and spam scores of a site
+func thresholds(site *models.Site) (float64, float64) {
+	hold, spam := DefaultHoldScore, DefaultSpamScore
+	if site != nil && site.Spam != nil {
+		if site.Spam.HoldScore > 0 {
+			hold = site.Spam.HoldScore
+		}
+		if site.Spam.SpamScore > 0 {
+			spam = site.Spam.SpamScore
+		}
+	}
+	return hold, spam
+}
+
+// BodyHash identifies a comment body regardless of markup, case and spacing
+func BodyHash(body string) string {
+	normalized := strings.Join(words(utils.HTMLToText(body)), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func clamp(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
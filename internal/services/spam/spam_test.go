@@ -0,0 +1,118 @@
+package spam
+
+import (
+	"math"
+	"testing"
+
+	"zoomment-server/internal/models"
+)
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		name     string
+		scores   []float64
+		expected float64
+	}{
+		{name: "no signals", scores: nil, expected: 0},
+		{name: "one signal", scores: []float64{0.6}, expected: 0.6},
+		{name: "weak signals add up", scores: []float64{0.5, 0.5}, expected: 0.75},
+		{name: "certain signal wins", scores: []float64{0.2, 1}, expected: 1},
+		{name: "scores are clamped", scores: []float64{-1, 2}, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Combine(tt.scores...)
+			if math.Abs(result-tt.expected) > 1e-9 {
+				t.Errorf("Combine(%v) = %v, want %v", tt.scores, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRoute(t *testing.T) {
+	custom := &models.Site{Spam: &models.SpamSettings{HoldScore: 0.3, SpamScore: 0.6}}
+
+	tests := []struct {
+		name     string
+		site     *models.Site
+		score    float64
+		status   string
+		expected string
+	}{
+		{
+			name:     "low score keeps the status",
+			site:     &models.Site{},
+			score:    0.2,
+			status:   models.CommentStatusApproved,
+			expected: models.CommentStatusApproved,
+		},
+		{
+			name:     "medium score holds the comment",
+			site:     &models.Site{},
+			score:    DefaultHoldScore,
+			status:   models.CommentStatusApproved,
+			expected: models.CommentStatusPending,
+		},
+		{
+			name:     "high score is spam",
+			site:     &models.Site{},
+			score:    DefaultSpamScore,
+			status:   models.CommentStatusApproved,
+			expected: models.CommentStatusSpam,
+		},
+		{
+			name:     "pending stays pending",
+			site:     &models.Site{},
+			score:    0.7,
+			status:   models.CommentStatusPending,
+			expected: models.CommentStatusPending,
+		},
+		{
+			name:     "site thresholds hold",
+			site:     custom,
+			score:    0.4,
+			status:   models.CommentStatusApproved,
+			expected: models.CommentStatusPending,
+		},
+		{
+			name:     "site thresholds mark spam",
+			site:     custom,
+			score:    0.6,
+			status:   models.CommentStatusPending,
+			expected: models.CommentStatusSpam,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Route(tt.site, tt.score, tt.status)
+			if result != tt.expected {
+				t.Errorf("Route() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	if Enabled(nil) {
+		t.Error("comments on unregistered domains should not be checked")
+	}
+	if !Enabled(&models.Site{}) {
+		t.Error("checks should be on by default")
+	}
+	if Enabled(&models.Site{Spam: &models.SpamSettings{Disabled: true}}) {
+		t.Error("checks should be off when disabled")
+	}
+}
+
+func TestBodyHash(t *testing.T) {
+	a := BodyHash("<p>Buy   CHEAP watches!</p>")
+	b := BodyHash("buy cheap watches")
+	if a != b {
+		t.Errorf("BodyHash should ignore markup, case and spacing")
+	}
+	if a == BodyHash("buy cheap clocks") {
+		t.Errorf("BodyHash should differ for different text")
+	}
+}
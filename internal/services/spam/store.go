@@ -0,0 +1,51 @@
+package spam
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/repository"
+)
+
+// repositoryHistory is the History of the comments in the database
+type repositoryHistory struct{}
+
+func (repositoryHistory) RecentComments(domain, email, ip string, since time.Time) (int64, error) {
+	return repository.CountRecentComments(domain, email, ip, since)
+}
+
+func (repositoryHistory) DuplicateComments(domain, bodyHash string, since time.Time) (int64, error) {
+	return repository.CountDuplicateComments(domain, bodyHash, since)
+}
+
+func (repositoryHistory) ApprovedComments(domain, email string) (int64, error) {
+	return repository.CountApprovedComments(domain, email)
+}
+
+// repositoryCorpus is the Corpus stored in the database
+type repositoryCorpus struct{}
+
+func (repositoryCorpus) Totals(siteID primitive.ObjectID) (int, int, error) {
+	return repository.GetSpamCorpus(siteID)
+}
+
+func (repositoryCorpus) Counts(siteID primitive.ObjectID, tokens []string) (map[string]TokenCount, error) {
+	stored, err := repository.GetSpamTokens(siteID, tokens)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]TokenCount, len(stored))
+	for _, token := range stored {
+		counts[token.Token] = TokenCount{Ham: token.Ham, Spam: token.Spam}
+	}
+	return counts, nil
+}
+
+func (repositoryCorpus) Add(siteID primitive.ObjectID, tokens []string, ham, spam int) error {
+	return repository.AddSpamTraining(siteID, tokens, ham, spam)
+}
+
+func (repositoryCorpus) MarkTrained(commentID primitive.ObjectID, as string) error {
+	return repository.SetCommentTrainedAs(commentID, as)
+}
@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the timestamp and HMAC of a payload:
+//
+//	X-Zoomment-Signature: t=1700000000,v1=5257a869...
+//
+// v1 is the hex HMAC-SHA256 of "<t>.<body>" keyed with the webhook secret.
+// Receivers should recompute it and reject old timestamps to stop replays.
+const SignatureHeader = "X-Zoomment-Signature"
+
+// Sign returns the signature header value for a payload
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", t, computeMAC(secret, t, body))
+}
+
+// Verify checks a signature header against a payload
+// Signatures older (or newer) than tolerance are rejected.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) bool {
+	var t string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	timestamp, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > tolerance || age < -tolerance {
+		return false
+	}
+
+	expected := computeMAC(secret, t, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return true
+		}
+	}
+	return false
+}
+
+// computeMAC returns the hex HMAC-SHA256 of "<t>.<body>"
+func computeMAC(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,47 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"type":"comment.created"}' | openssl dgst -sha256 -hmac secret
+	got := Sign("secret", time.Unix(1700000000, 0), []byte(`{"type":"comment.created"}`))
+	want := "t=1700000000,v1=0b1457b852a7ca1afb87f2aecc18aa895867dfa2eebacb1f50104d2911ff0428"
+	if got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"vote.cast"}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := Sign("secret", signedAt, body)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   bool
+	}{
+		{"valid", "secret", header, body, signedAt.Add(time.Minute), true},
+		{"wrong secret", "other", header, body, signedAt, false},
+		{"tampered body", "secret", header, []byte(`{"type":"comment.deleted"}`), signedAt, false},
+		{"too old", "secret", header, body, signedAt.Add(10 * time.Minute), false},
+		{"from the future", "secret", header, body, signedAt.Add(-10 * time.Minute), false},
+		{"missing timestamp", "secret", "v1=abc", body, signedAt, false},
+		{"garbage", "secret", "nonsense", body, signedAt, false},
+		{"rotated secret", "secret", header + ",v1=deadbeef", body, signedAt, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.header, tt.body, tt.now, 5*time.Minute); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/kamva/mgm/v3"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"zoomment-server/internal/config"
+	"zoomment-server/internal/events"
+	"zoomment-server/internal/logger"
+	"zoomment-server/internal/models"
+	"zoomment-server/internal/repository"
+	"zoomment-server/internal/utils"
+)
+
+// Headers sent with every delivery (besides SignatureHeader)
+const (
+	EventHeader    = "X-Zoomment-Event"
+	DeliveryHeader = "X-Zoomment-Delivery"
+)
+
+const (
+	// workers is how many deliveries are sent at the same time
+	workers = 2
+
+	// queueSize is how many events may wait to be turned into deliveries
+	queueSize = 256
+
+	// pollInterval is how long an idle worker waits before looking for deliveries again
+	pollInterval = 5 * time.Second
+
+	// claimLease is how long a worker may hold a delivery before others retry it
+	claimLease = 2 * time.Minute
+
+	// requestTimeout bounds a single delivery
+	requestTimeout = 10 * time.Second
+
+	// maxAttempts is how many times a delivery is tried before it is given up
+	maxAttempts = 10
+
+	// Retry delays grow from retryBaseDelay up to retryMaxDelay
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 6 * time.Hour
+
+	// maxResponseBody is how much of the receiver's response is logged
+	maxResponseBody = 1024
+)
+
+// errPrivateAddress is returned for webhooks pointing into our own network
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// Payload is the JSON body of a delivery
+// ID is the same for every delivery (and redelivery) of an event.
+type Payload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Site      PayloadSite `json:"site"`
+	PageID    string      `json:"pageId"`
+	Data      any         `json:"data"`
+}
+
+// PayloadSite identifies the site an event happened on
+type PayloadSite struct {
+	ID     string `json:"id"`
+	Domain string `json:"domain"`
+}
+
+// VoteCast is the data of a vote.cast event
+type VoteCast struct {
+	CommentID string `json:"commentId"`
+	Upvotes   int    `json:"upvotes"`
+	Downvotes int    `json:"downvotes"`
+	Score     int    `json:"score"`
+}
+
+// ReactionAdded is the data of a reaction.added event
+type ReactionAdded struct {
+	Reaction    string                       `json:"reaction"`
+	Aggregation []models.ReactionAggregation `json:"aggregation"`
+}
+
+// queue holds events between the hub listener and the dispatcher
+var queue = make(chan events.Event, queueSize)
+
+// Start sends webhooks for the events of the default hub in the background
+func Start(cfg *config.Config) {
+	client := NewClient(cfg.WebhookAllowPrivateNetworks)
+
+	events.Listen(listen)
+	go dispatch()
+	for i := 0; i < workers; i++ {
+		go work(client)
+	}
+}
+
+// NewClient creates the HTTP client used for deliveries
+// Unless allowPrivate is set, it refuses to connect to loopback and private
+// addresses, so webhooks can't be used to reach internal services.
+func NewClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+				ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Through a proxy the dialer would only check the proxy's address, not
+	// the webhook's, so HTTP_PROXY and friends are ignored
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+		// A redirect counts as a failed delivery
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// listen hands events to the dispatcher without blocking the publisher
+func listen(event events.Event) {
+	select {
+	case queue <- event:
+	default:
+		logger.Warn("Webhook queue is full, dropping " + event.Type + " event")
+	}
+}
+
+// dispatch queues a delivery for every webhook that wants an event
+func dispatch() {
+	for event := range queue {
+		if err := enqueue(event); err != nil {
+			logger.Error(err, "Failed to queue webhook deliveries")
+		}
+	}
+}
+
+// enqueue creates the deliveries for one event
+func enqueue(event events.Event) error {
+	eventType, data := webhookEvent(event)
+	if eventType == "" || event.Domain == "" {
+		return nil
+	}
+
+	site := &models.Site{}
+	if err := mgm.Coll(site).First(bson.M{"domain": event.Domain}, site); err != nil {
+		// Not a registered site
+		return nil
+	}
+
+	webhooks, err := repository.GetActiveWebhooks(site.ID, eventType)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	body, err := json.Marshal(Payload{
+		ID:        event.Key,
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Site:      PayloadSite{ID: site.ID.Hex(), Domain: site.Domain},
+		PageID:    event.PageID,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		_, err := repository.EnqueueWebhookDelivery(&models.WebhookDelivery{
+			IdempotencyKey: webhook.ID.Hex() + ":" + event.Key,
+			WebhookID:      webhook.ID,
+			SiteID:         site.ID,
+			EventID:        event.Key,
+			EventType:      eventType,
+			Payload:        string(body),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhookEvent maps a hub event to a webhook event type and data
+// Returns an empty type for events webhooks don't carry.
+func webhookEvent(event events.Event) (string, any) {
+	switch data := event.Data.(type) {
+	case events.VoteChangedEvent:
+		return models.WebhookEventVoteCast, VoteCast{
+			CommentID: data.CommentID,
+			Upvotes:   data.Upvotes,
+			Downvotes: data.Downvotes,
+			Score:     data.Score,
+		}
+	case events.ReactionChangedEvent:
+		if data.Added == "" {
+			return "", nil
+		}
+		return models.WebhookEventReactionAdded, ReactionAdded{Reaction: data.Added, Aggregation: data.Aggregation}
+	}
+
+	switch event.Type {
+	case events.CommentCreated:
+		return models.WebhookEventCommentCreated, event.Data
+	case events.CommentDeleted:
+		return models.WebhookEventCommentDeleted, event.Data
+	}
+	return "", nil
+}
+
+// work sends deliveries until none are due, then waits and polls again
+func work(client *http.Client) {
+	for {
+		delivery, err := repository.ClaimWebhookDelivery(claimLease)
+		if err != nil {
+			logger.Error(err, "Failed to claim webhook delivery")
+			time.Sleep(pollInterval)
+			continue
+		}
+		if delivery == nil {
+			time.Sleep(pollInterval)
+			continue
+		}
+
+		deliver(client, delivery)
+	}
+}
+
+// deliver sends one delivery and records the attempt
+func deliver(client *http.Client, delivery *models.WebhookDelivery) {
+	webhook, err := repository.GetWebhook(delivery.SiteID, delivery.WebhookID)
+	if err != nil {
+		logger.Error(err, "Failed to load webhook")
+		return // The lease expires and the delivery is retried
+	}
+
+	var attempt models.WebhookAttempt
+	if webhook == nil || !webhook.Active {
+		attempt = models.WebhookAttempt{At: time.Now(), Error: "webhook was removed or disabled"}
+		if err := repository.RecordWebhookAttempt(delivery, attempt, models.WebhookDeliveryDead, time.Time{}); err != nil {
+			logger.Error(err, "Failed to record webhook attempt")
+		}
+		return
+	}
+
+	attempt = Send(client, webhook, delivery)
+
+	status := models.WebhookDeliveryDelivered
+	var nextAttempt time.Time
+	if attempt.Error != "" {
+		status = models.WebhookDeliveryPending
+		if delivery.Attempts >= maxAttempts {
+			status = models.WebhookDeliveryDead
+			logger.Warn(fmt.Sprintf("Webhook delivery to %s given up after %d attempts", webhook.URL, delivery.Attempts))
+		}
+		nextAttempt = time.Now().Add(utils.ExponentialBackoff(delivery.Attempts, retryBaseDelay, retryMaxDelay))
+	}
+
+	if err := repository.RecordWebhookAttempt(delivery, attempt, status, nextAttempt); err != nil {
+		logger.Error(err, "Failed to record webhook attempt")
+	}
+}
+
+// Send posts a delivery to a webhook
+// Any response other than 2xx is an error.
+func Send(client *http.Client, webhook *models.Webhook, delivery *models.WebhookDelivery) models.WebhookAttempt {
+	start := time.Now()
+	attempt := models.WebhookAttempt{At: start}
+
+	body := []byte(delivery.Payload)
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Zoomment-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID.Hex())
+	// Signed when sent, so a rotated secret applies to retries too
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, start, body))
+
+	resp, err := client.Do(req)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	attempt.ResponseCode = resp.StatusCode
+	attempt.ResponseBody = string(responseBody)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = "unexpected status " + strconv.Itoa(resp.StatusCode)
+	}
+	return attempt
+}
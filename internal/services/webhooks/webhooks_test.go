@@ -0,0 +1,97 @@
+package webhooks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zoomment-server/internal/events"
+	"zoomment-server/internal/models"
+)
+
+func TestWebhookEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    events.Event
+		wantType string
+	}{
+		{"comment created", events.Event{Type: events.CommentCreated, Data: "comment"}, models.WebhookEventCommentCreated},
+		{"comment deleted", events.Event{Type: events.CommentDeleted, Data: events.CommentDeletedEvent{}}, models.WebhookEventCommentDeleted},
+		{"comment edited", events.Event{Type: events.CommentEdited, Data: "comment"}, ""},
+		{"vote", events.Event{Type: events.VoteChanged, Data: events.VoteChangedEvent{Score: 1}}, models.WebhookEventVoteCast},
+		{"reaction added", events.Event{Type: events.ReactionChanged, Data: events.ReactionChangedEvent{Added: "👍"}}, models.WebhookEventReactionAdded},
+		{"reaction removed", events.Event{Type: events.ReactionChanged, Data: events.ReactionChangedEvent{}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := webhookEvent(tt.event); got != tt.wantType {
+				t.Errorf("webhookEvent() type = %q, want %q", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	var received *http.Request
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		if strings.Contains(string(receivedBody), "fail") {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		w.Write([]byte("thanks"))
+	}))
+	defer server.Close()
+
+	webhook := &models.Webhook{URL: server.URL, Secret: "secret"}
+	delivery := &models.WebhookDelivery{EventType: models.WebhookEventCommentCreated, Payload: `{"id":"1"}`}
+	delivery.ID = primitive.NewObjectID()
+
+	attempt := Send(NewClient(true), webhook, delivery)
+	if attempt.Error != "" || attempt.ResponseCode != http.StatusOK || attempt.ResponseBody != "thanks" {
+		t.Fatalf("got attempt %+v", attempt)
+	}
+	if got := received.Header.Get(EventHeader); got != models.WebhookEventCommentCreated {
+		t.Errorf("%s = %q", EventHeader, got)
+	}
+	if got := received.Header.Get(DeliveryHeader); got != delivery.ID.Hex() {
+		t.Errorf("%s = %q", DeliveryHeader, got)
+	}
+	if !Verify("secret", received.Header.Get(SignatureHeader), receivedBody, time.Now(), time.Minute) {
+		t.Error("signature doesn't verify")
+	}
+
+	delivery.Payload = `{"id":"fail"}`
+	attempt = Send(NewClient(true), webhook, delivery)
+	if attempt.Error == "" || attempt.ResponseCode != http.StatusBadGateway {
+		t.Errorf("non-2xx response should fail, got %+v", attempt)
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	webhook := &models.Webhook{URL: server.URL, Secret: "secret"}
+	attempt := Send(NewClient(false), webhook, &models.WebhookDelivery{Payload: "{}"})
+	if !strings.Contains(attempt.Error, errPrivateAddress.Error()) {
+		t.Errorf("got attempt %+v, want a refused connection", attempt)
+	}
+}
+
+func TestClientIgnoresProxies(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://proxy.example:3128")
+	t.Setenv("HTTPS_PROXY", "http://proxy.example:3128")
+
+	transport := NewClient(false).Transport.(*http.Transport)
+	if transport.Proxy != nil {
+		t.Error("client uses a proxy, which would skip the private address check")
+	}
+}
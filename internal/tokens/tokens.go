@@ -0,0 +1,62 @@
+// Package tokens creates and verifies signed, purpose-bound tokens
+// (unsubscribe links, moderation links, challenges...).
+// They are JWTs signed with the app secret, like login tokens, but carry a
+// "purpose" claim so a token issued for one feature can't be used for another.
+package tokens
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token purposes
+const (
+	PurposeUnsubscribe = "unsubscribe"
+	PurposeModerate    = "moderate"
+	PurposeChallenge   = "challenge"
+	PurposeForm        = "form"
+)
+
+// ErrInvalidToken is returned for malformed, expired or foreign tokens
+var ErrInvalidToken = errors.New("invalid token")
+
+// Sign creates a token for a purpose
+// ttl = 0 creates a token that never expires
+func Sign(secret, purpose string, claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	payload := jwt.MapClaims{}
+	for key, value := range claims {
+		payload[key] = value
+	}
+	payload["purpose"] = purpose
+	payload["iat"] = time.Now().Unix()
+	if ttl != 0 {
+		payload["exp"] = time.Now().Add(ttl).Unix()
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, payload).SignedString([]byte(secret))
+}
+
+// Parse verifies a token and checks that it was issued for purpose
+func Parse(secret, purpose, tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// String returns a string claim, or "" if it's missing
+func String(claims jwt.MapClaims, key string) string {
+	value, _ := claims[key].(string)
+	return value
+}
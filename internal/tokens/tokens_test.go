@@ -0,0 +1,51 @@
+package tokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestSignAndParse(t *testing.T) {
+	token, err := Sign("secret", PurposeUnsubscribe, jwt.MapClaims{"email": "test@example.com"}, 0)
+	if err != nil {
+		t.Fatalf("Sign returned error: %v", err)
+	}
+
+	claims, err := Parse("secret", PurposeUnsubscribe, token)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if email := String(claims, "email"); email != "test@example.com" {
+		t.Errorf("email claim = %q, want %q", email, "test@example.com")
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	valid, _ := Sign("secret", PurposeUnsubscribe, nil, 0)
+	expired, _ := Sign("secret", PurposeUnsubscribe, nil, -time.Minute)
+	loginToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": "123"}).SignedString([]byte("secret"))
+
+	tests := []struct {
+		name    string
+		secret  string
+		purpose string
+		token   string
+	}{
+		{name: "wrong secret", secret: "other", purpose: PurposeUnsubscribe, token: valid},
+		{name: "wrong purpose", secret: "secret", purpose: "other", token: valid},
+		{name: "expired", secret: "secret", purpose: PurposeUnsubscribe, token: expired},
+		{name: "login token", secret: "secret", purpose: PurposeUnsubscribe, token: loginToken},
+		{name: "garbage", secret: "secret", purpose: PurposeUnsubscribe, token: "not-a-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.secret, tt.purpose, tt.token); err != ErrInvalidToken {
+				t.Errorf("Parse() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
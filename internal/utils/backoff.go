@@ -0,0 +1,24 @@
+package utils
+
+import "time"
+
+// ExponentialBackoff returns how long to wait before retry number attempt
+// The delay starts at base, doubles on every attempt and is capped at max.
+func ExponentialBackoff(attempt int, base, max time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+
+	if delay > max {
+		return max
+	}
+	return delay
+}
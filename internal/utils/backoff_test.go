@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempt  int
+		base     time.Duration
+		max      time.Duration
+		expected time.Duration
+	}{
+		{
+			name:     "first attempt waits base",
+			attempt:  1,
+			base:     time.Second,
+			max:      time.Hour,
+			expected: time.Second,
+		},
+		{
+			name:     "doubles every attempt",
+			attempt:  4,
+			base:     time.Second,
+			max:      time.Hour,
+			expected: 8 * time.Second,
+		},
+		{
+			name:     "capped at max",
+			attempt:  30,
+			base:     time.Minute,
+			max:      time.Hour,
+			expected: time.Hour,
+		},
+		{
+			name:     "base larger than max",
+			attempt:  1,
+			base:     2 * time.Hour,
+			max:      time.Hour,
+			expected: time.Hour,
+		},
+		{
+			name:     "zero attempt treated as first",
+			attempt:  0,
+			base:     time.Second,
+			max:      time.Hour,
+			expected: time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ExponentialBackoff(tt.attempt, tt.base, tt.max)
+			if result != tt.expected {
+				t.Errorf("ExponentialBackoff(%d, %v, %v) = %v, want %v", tt.attempt, tt.base, tt.max, result, tt.expected)
+			}
+		})
+	}
+}
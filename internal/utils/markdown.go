@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown renders CommonMark with GitHub-style strikethrough and autolinks
+// Fenced code blocks are part of CommonMark itself.
+// Raw HTML inside the source is dropped (goldmark's default, no WithUnsafe).
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.Strikethrough,
+		extension.Linkify,
+	),
+)
+
+// RenderMarkdown converts Markdown source to HTML
+// The output is NOT sanitized yet - always run it through a sanitizer
+func RenderMarkdown(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		contains    string
+		notContains string
+	}{
+		{
+			name:     "renders emphasis",
+			input:    "**bold** and _italic_",
+			contains: "<strong>bold</strong>",
+		},
+		{
+			name:     "renders fenced code",
+			input:    "```go\nfmt.Println(1)\n```",
+			contains: `<code class="language-go">`,
+		},
+		{
+			name:     "renders strikethrough",
+			input:    "~~gone~~",
+			contains: "<del>gone</del>",
+		},
+		{
+			name:     "autolinks bare URLs",
+			input:    "see https://example.com",
+			contains: `<a href="https://example.com">`,
+		},
+		{
+			name:        "drops raw HTML",
+			input:       "<script>evil()</script>",
+			notContains: "<script>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RenderMarkdown(tt.input)
+			if err != nil {
+				t.Fatalf("RenderMarkdown(%q) returned error: %v", tt.input, err)
+			}
+
+			if tt.contains != "" && !strings.Contains(result, tt.contains) {
+				t.Errorf("RenderMarkdown(%q) = %q, should contain %q", tt.input, result, tt.contains)
+			}
+
+			if tt.notContains != "" && strings.Contains(result, tt.notContains) {
+				t.Errorf("RenderMarkdown(%q) = %q, should NOT contain %q", tt.input, result, tt.notContains)
+			}
+		})
+	}
+}
@@ -1,7 +1,13 @@
 package utils
 
 import (
+	"bytes"
+	"net/url"
+	"strings"
+
 	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
 // Global sanitizer instance (thread-safe)
@@ -36,3 +42,173 @@ func SanitizeComment(input string) string {
 // Strict: "Click"
 // UGC:    "Click" (dangerous href removed)
 
+// ========================================
+// Per-site policies
+// ========================================
+
+// Tag sets a site can choose for comment bodies
+const (
+	TagSetPlain = "plain" // No HTML at all
+	TagSetBasic = "basic" // Basic formatting, links and lists
+	TagSetRich  = "rich"  // Everything the UGC policy allows (default)
+)
+
+// basicPolicy allows simple formatting only
+var basicPolicy = newBasicPolicy()
+
+func newBasicPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowStandardURLs()
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowElements("p", "br", "b", "strong", "i", "em", "u", "s", "del",
+		"code", "pre", "blockquote", "ul", "ol", "li")
+	p.AllowImages()
+	return p
+}
+
+// SanitizeOptions describes a site's comment sanitizer settings
+type SanitizeOptions struct {
+	TagSet             string
+	RewriteLinks       bool     // Force rel="nofollow ugc noopener" and target="_blank"
+	AllowedLinkDomains []string // If set, links may only point to these domains
+	DeniedLinkDomains  []string // Links to these domains are removed
+	AllowImages        bool
+}
+
+// SanitizeCommentWith sanitizes a comment body using a site's own settings
+// Links that aren't allowed are unwrapped (their text is kept).
+func SanitizeCommentWith(input string, opts SanitizeOptions) string {
+	switch opts.TagSet {
+	case TagSetPlain:
+		return strictPolicy.Sanitize(input)
+	case TagSetBasic:
+		return rewriteComment(basicPolicy.Sanitize(input), opts)
+	default:
+		return rewriteComment(ugcPolicy.Sanitize(input), opts)
+	}
+}
+
+// rewriteComment applies the link and image rules to already sanitized HTML
+func rewriteComment(sanitized string, opts SanitizeOptions) string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(sanitized), body)
+	if err != nil {
+		// Input is already safe, just skip the rewriting
+		return sanitized
+	}
+	for _, node := range nodes {
+		body.AppendChild(node)
+	}
+
+	rewriteNode(body, opts)
+
+	var buf bytes.Buffer
+	for node := body.FirstChild; node != nil; node = node.NextSibling {
+		if err := html.Render(&buf, node); err != nil {
+			return sanitized
+		}
+	}
+	return buf.String()
+}
+
+// rewriteNode walks the tree and applies the options to links and images
+func rewriteNode(n *html.Node, opts SanitizeOptions) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+
+		if child.Type == html.ElementNode {
+			switch child.DataAtom {
+			case atom.Img:
+				if !opts.AllowImages {
+					n.RemoveChild(child)
+					child = next
+					continue
+				}
+			case atom.A:
+				if !linkAllowed(getAttr(child, "href"), opts) {
+					// Keep the text, drop the link
+					rewriteNode(child, opts)
+					unwrapNode(child)
+					child = next
+					continue
+				}
+				if opts.RewriteLinks {
+					setAttr(child, "rel", "nofollow ugc noopener")
+					setAttr(child, "target", "_blank")
+				}
+			}
+		}
+
+		rewriteNode(child, opts)
+		child = next
+	}
+}
+
+// linkAllowed checks a link target against the domain allow and deny lists
+// Relative links stay on the site itself and are always allowed.
+func linkAllowed(href string, opts SanitizeOptions) bool {
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if host == "" {
+		return true
+	}
+
+	for _, domain := range opts.DeniedLinkDomains {
+		if matchesDomain(host, domain) {
+			return false
+		}
+	}
+
+	if len(opts.AllowedLinkDomains) == 0 {
+		return true
+	}
+	for _, domain := range opts.AllowedLinkDomains {
+		if matchesDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesDomain reports whether host is domain or one of its subdomains
+func matchesDomain(host, domain string) bool {
+	domain = strings.ToLower(domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// unwrapNode replaces a node with its children
+func unwrapNode(n *html.Node) {
+	parent := n.Parent
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		n.RemoveChild(child)
+		parent.InsertBefore(child, n)
+		child = next
+	}
+	parent.RemoveChild(n)
+}
+
+// getAttr returns the value of an attribute, or "" if it's missing
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// setAttr sets an attribute, replacing any existing value
+func setAttr(n *html.Node, key, value string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
@@ -111,3 +111,86 @@ func findSubstring(s, substr string) bool {
 	return false
 }
 
+func TestSanitizeCommentWith(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		opts        SanitizeOptions
+		contains    string
+		notContains string
+	}{
+		{
+			name:        "plain removes all tags",
+			input:       "<b>Bold</b>",
+			opts:        SanitizeOptions{TagSet: TagSetPlain},
+			contains:    "Bold",
+			notContains: "<b>",
+		},
+		{
+			name:        "basic removes tables",
+			input:       "<table><tr><td>Cell</td></tr></table>",
+			opts:        SanitizeOptions{TagSet: TagSetBasic},
+			contains:    "Cell",
+			notContains: "<table>",
+		},
+		{
+			name:     "rich keeps tables",
+			input:    "<table><tr><td>Cell</td></tr></table>",
+			opts:     SanitizeOptions{TagSet: TagSetRich},
+			contains: "<table>",
+		},
+		{
+			name:     "rewrites link attributes",
+			input:    `<a href="https://example.com">Link</a>`,
+			opts:     SanitizeOptions{TagSet: TagSetRich, RewriteLinks: true},
+			contains: `rel="nofollow ugc noopener" target="_blank"`,
+		},
+		{
+			name:        "unwraps denied domains",
+			input:       `<a href="https://spam.example.com/buy">Buy</a>`,
+			opts:        SanitizeOptions{TagSet: TagSetRich, DeniedLinkDomains: []string{"example.com"}},
+			contains:    "Buy",
+			notContains: "href",
+		},
+		{
+			name:        "unwraps domains outside the allow list",
+			input:       `<a href="https://other.org">Other</a>`,
+			opts:        SanitizeOptions{TagSet: TagSetBasic, AllowedLinkDomains: []string{"example.com"}},
+			contains:    "Other",
+			notContains: "href",
+		},
+		{
+			name:     "keeps domains in the allow list",
+			input:    `<a href="https://docs.example.com">Docs</a>`,
+			opts:     SanitizeOptions{TagSet: TagSetBasic, AllowedLinkDomains: []string{"example.com"}},
+			contains: `href="https://docs.example.com"`,
+		},
+		{
+			name:        "removes images when disabled",
+			input:       `<p>Look <img src="https://example.com/a.png"></p>`,
+			opts:        SanitizeOptions{TagSet: TagSetRich},
+			contains:    "Look",
+			notContains: "<img",
+		},
+		{
+			name:     "keeps images when enabled",
+			input:    `<img src="https://example.com/a.png">`,
+			opts:     SanitizeOptions{TagSet: TagSetBasic, AllowImages: true},
+			contains: "<img",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SanitizeCommentWith(tt.input, tt.opts)
+
+			if tt.contains != "" && !contains(result, tt.contains) {
+				t.Errorf("SanitizeCommentWith(%q) = %q, should contain %q", tt.input, result, tt.contains)
+			}
+
+			if tt.notContains != "" && contains(result, tt.notContains) {
+				t.Errorf("SanitizeCommentWith(%q) = %q, should NOT contain %q", tt.input, result, tt.notContains)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// SearchTerms returns the words a text search query looks for, lowercased
+// Quoted phrases count word by word and negated words ("-word") are left out.
+func SearchTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Fields(strings.ReplaceAll(query, `"`, " ")) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(strings.ToLower(field), isDelimiter) {
+			if !seen[word] {
+				seen[word] = true
+				terms = append(terms, word)
+			}
+		}
+	}
+	return terms
+}
+
+// Highlight escapes text as HTML and wraps the words matching terms in <mark>
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	return highlight(runes, wordSpans(runes), terms)
+}
+
+// Snippet cuts about length characters out of text around the first word
+// matching terms, and highlights it like Highlight
+// Whitespace is collapsed and cuts are marked with "…". Text without a
+// matching word is cut from the start.
+func Snippet(text string, terms []string, length int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	spans := wordSpans(runes)
+
+	// Center the first match a little left of the middle
+	start, matchStart, matchEnd := 0, -1, 0
+	for _, span := range spans {
+		if isTerm(runes[span[0]:span[1]], terms) {
+			start = max(0, span[0]-length/3)
+			matchStart, matchEnd = span[0], span[1]
+			break
+		}
+	}
+	end := min(len(runes), start+length)
+	if end == len(runes) {
+		start = max(0, end-length)
+	}
+
+	// Don't cut words in half when there's a space to cut at, without
+	// cutting off the match. Text without spaces (like Chinese or Japanese)
+	// is cut anywhere.
+	if start > 0 {
+		limit := end
+		if matchStart >= 0 {
+			limit = min(limit, matchStart)
+		}
+		for i := start; i <= limit; i++ {
+			if runes[i-1] == ' ' {
+				start = i
+				break
+			}
+		}
+	}
+	if end < len(runes) {
+		for i := end; i > max(start, matchEnd); i-- {
+			if runes[i] == ' ' {
+				end = i
+				break
+			}
+		}
+	}
+
+	window := runes[start:end]
+	snippet := highlight(window, wordSpans(window), terms)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// highlight escapes runes as HTML and marks the spans that match terms
+func highlight(runes []rune, spans [][2]int, terms []string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		word := runes[span[0]:span[1]]
+		if !isTerm(word, terms) {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[last:span[0]])))
+		b.WriteString("<mark>" + html.EscapeString(string(word)) + "</mark>")
+		last = span[1]
+	}
+	b.WriteString(html.EscapeString(string(runes[last:])))
+	return b.String()
+}
+
+// wordSpans returns where the words of runes start and end
+func wordSpans(runes []rune) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range runes {
+		switch {
+		case !isDelimiter(r) && start < 0:
+			start = i
+		case isDelimiter(r) && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(runes)})
+	}
+	return spans
+}
+
+// isTerm reports whether a word is one of the (lowercased) terms
+func isTerm(word []rune, terms []string) bool {
+	lower := strings.ToLower(string(word))
+	for _, term := range terms {
+		if lower == term {
+			return true
+		}
+	}
+	return false
+}
+
+// isDelimiter reports whether r separates words
+func isDelimiter(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
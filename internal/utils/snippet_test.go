@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "Spam", expected: []string{"spam"}},
+		{query: `"cheap pills" -viagra`, expected: []string{"cheap", "pills"}},
+		{query: "word, Word!", expected: []string{"word"}},
+		{query: "привет мир", expected: []string{"привет", "мир"}},
+		{query: "  ", expected: nil},
+	}
+
+	for _, tt := range tests {
+		result := SearchTerms(tt.query)
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("SearchTerms(%q) = %q, want %q", tt.query, result, tt.expected)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := "The first part of this comment talks about nothing at all, then it finally gets to the spam question and keeps going for a long while after that"
+
+	tests := []struct {
+		name     string
+		text     string
+		terms    []string
+		length   int
+		expected string
+	}{
+		{
+			name:     "short text is kept whole",
+			text:     "Great Post, thanks",
+			terms:    []string{"post"},
+			length:   100,
+			expected: "Great <mark>Post</mark>, thanks",
+		},
+		{
+			name:     "every match is marked",
+			text:     "spam, spam and more spam",
+			terms:    []string{"spam"},
+			length:   100,
+			expected: "<mark>spam</mark>, <mark>spam</mark> and more <mark>spam</mark>",
+		},
+		{
+			name:     "whole words only",
+			text:     "spammer spam",
+			terms:    []string{"spam"},
+			length:   100,
+			expected: "spammer <mark>spam</mark>",
+		},
+		{
+			name:     "cut around the first match",
+			text:     long,
+			terms:    []string{"spam"},
+			length:   40,
+			expected: "…gets to the <mark>spam</mark> question and keeps…",
+		},
+		{
+			name:     "no match starts at the beginning",
+			text:     long,
+			terms:    []string{"missing"},
+			length:   30,
+			expected: "The first part of this comment…",
+		},
+		{
+			name:     "match near the end",
+			text:     long,
+			terms:    []string{"that"},
+			length:   30,
+			expected: "…for a long while after <mark>that</mark>",
+		},
+		{
+			name:     "escapes html",
+			text:     "<b>bold</b> & spam",
+			terms:    []string{"spam"},
+			length:   100,
+			expected: "&lt;b&gt;bold&lt;/b&gt; &amp; <mark>spam</mark>",
+		},
+		{
+			name:     "collapses whitespace",
+			text:     "line one\n\n  line  two",
+			terms:    []string{"two"},
+			length:   100,
+			expected: "line one line <mark>two</mark>",
+		},
+		{
+			name:     "long text without spaces",
+			text:     strings.Repeat("lorem,", 10) + "target",
+			terms:    []string{"target"},
+			length:   20,
+			expected: "…m,lorem,lorem,<mark>target</mark>",
+		},
+		{
+			name:     "text without spaces is cut anywhere",
+			text:     "target," + strings.Repeat("lorem,", 10),
+			terms:    []string{"target"},
+			length:   20,
+			expected: "<mark>target</mark>,lorem,lorem,l…",
+		},
+		{
+			name:     "chinese",
+			text:     strings.Repeat("很长的评论，", 5) + "广告，" + strings.Repeat("很多文字，", 5),
+			terms:    []string{"广告"},
+			length:   12,
+			expected: "…的评论，<mark>广告</mark>，很多文字，…",
+		},
+		{
+			name:     "unicode words",
+			text:     "Это спам, точно",
+			terms:    []string{"спам"},
+			length:   100,
+			expected: "Это <mark>спам</mark>, точно",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Snippet(tt.text, tt.terms, tt.length)
+			if result != tt.expected {
+				t.Errorf("Snippet() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSnippetNoSpaces(t *testing.T) {
+	// Must not panic however the match and spaces fall
+	text := strings.Repeat("lorem,", 100) + "target"
+	for _, length := range []int{10, 160, 1000} {
+		if result := Snippet(text, []string{"target"}, length); !strings.Contains(result, "<mark>target</mark>") {
+			t.Errorf("Snippet(length %d) = %q, want the match", length, result)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	result := Highlight("Jane <Spam> Doe", []string{"spam", "doe"})
+	expected := "Jane &lt;<mark>Spam</mark>&gt; <mark>Doe</mark>"
+	if result != expected {
+		t.Errorf("Highlight() = %q, want %q", result, expected)
+	}
+}
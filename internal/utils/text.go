@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	spacesRegex     = regexp.MustCompile(`\s+`)
+	blankLinesRegex = regexp.MustCompile(`\n{3,}`)
+)
+
+// blockElements start on a new paragraph in plain text
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Ul: true, atom.Ol: true, atom.Table: true, atom.Tr: true, atom.Hr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+// HTMLToText converts an HTML fragment (like a comment body) to plain text
+// Paragraphs are separated by blank lines, list items start with "- "
+// and links keep their target in parentheses.
+func HTMLToText(input string) string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(input), body)
+	if err != nil {
+		return input
+	}
+
+	var b strings.Builder
+	for _, node := range nodes {
+		writeText(&b, node, false)
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text := blankLinesRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// writeText appends the text of a node and its children
+// pre is true inside <pre>, where whitespace is kept as-is.
+func writeText(b *strings.Builder, n *html.Node, pre bool) {
+	switch n.Type {
+	case html.TextNode:
+		if pre {
+			b.WriteString(n.Data)
+			return
+		}
+		text := spacesRegex.ReplaceAllString(n.Data, " ")
+		if b.Len() == 0 || strings.HasSuffix(b.String(), " ") || strings.HasSuffix(b.String(), "\n") {
+			text = strings.TrimLeft(text, " ")
+		}
+		b.WriteString(text)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Img:
+		return
+	case atom.Br:
+		b.WriteString("\n")
+		return
+	case atom.Li:
+		b.WriteString("\n- ")
+	}
+
+	block := blockElements[n.DataAtom]
+	if block {
+		b.WriteString("\n\n")
+	}
+
+	start := b.Len()
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		writeText(b, child, pre || n.DataAtom == atom.Pre)
+	}
+
+	if n.DataAtom == atom.A {
+		href := getAttr(n, "href")
+		if href != "" && strings.TrimSpace(b.String()[start:]) != href {
+			b.WriteString(" (" + href + ")")
+		}
+	}
+
+	if block {
+		b.WriteString("\n\n")
+	}
+}
@@ -0,0 +1,71 @@
+package utils
+
+import "testing"
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "plain text",
+			input:    "Hello world",
+			expected: "Hello world",
+		},
+		{
+			name:     "decodes entities",
+			input:    "Tom &amp; Jerry &lt;3",
+			expected: "Tom & Jerry <3",
+		},
+		{
+			name:     "paragraphs are separated by a blank line",
+			input:    "<p>First</p><p>Second</p>",
+			expected: "First\n\nSecond",
+		},
+		{
+			name:     "line breaks",
+			input:    "one<br>two",
+			expected: "one\ntwo",
+		},
+		{
+			name:     "collapses whitespace",
+			input:    "<p>  lots   of\n  space </p>",
+			expected: "lots of space",
+		},
+		{
+			name:     "links keep their target",
+			input:    `see <a href="https://example.com/docs">the docs</a>`,
+			expected: "see the docs (https://example.com/docs)",
+		},
+		{
+			name:     "autolinks are not repeated",
+			input:    `<a href="https://example.com">https://example.com</a>`,
+			expected: "https://example.com",
+		},
+		{
+			name:     "lists",
+			input:    "<ul><li>one</li><li>two</li></ul>",
+			expected: "- one\n- two",
+		},
+		{
+			name:     "code blocks keep indentation",
+			input:    "<pre><code>if x {\n    y()\n}</code></pre>",
+			expected: "if x {\n    y()\n}",
+		},
+		{
+			name:     "drops scripts and images",
+			input:    `hi<script>alert(1)</script><img src="x.png">`,
+			expected: "hi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := HTMLToText(tt.input)
+			if result != tt.expected {
+				t.Errorf("HTMLToText(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
@@ -2,14 +2,41 @@
 // All request types are defined here to ensure consistent validation across handlers.
 package validators
 
+import "time"
+
 // AddCommentRequest validates POST /api/comments
 type AddCommentRequest struct {
-	PageURL  string  `json:"pageUrl" binding:"required,url,max=2000"`
-	PageID   string  `json:"pageId" binding:"required,max=500"`
-	Body     string  `json:"body" binding:"required,min=1,max=10000"`
-	Author   string  `json:"author" binding:"required,min=1,max=100"`
-	Email    string  `json:"email" binding:"required,email,max=254"`
-	ParentID *string `json:"parentId" binding:"omitempty,len=24"`
+	PageURL  string             `json:"pageUrl" binding:"required,url,max=2000"`
+	PageID   string             `json:"pageId" binding:"required,max=500"`
+	Body     string             `json:"body" binding:"required,min=1,max=10000"`
+	Format   string             `json:"format" binding:"omitempty,oneof=html markdown"`
+	Author   string             `json:"author" binding:"required,min=1,max=100"`
+	Email    string             `json:"email" binding:"required,email,max=254"`
+	ParentID *string            `json:"parentId" binding:"omitempty,len=24"`
+	PoW      *ChallengeSolution `json:"pow"`
+
+	// Bot trap: Honeypot is a hidden field people leave empty, FormToken
+	// comes from GET /api/comments/form-token when the form is shown
+	Honeypot  string `json:"honeypot"`
+	FormToken string `json:"formToken" binding:"max=2000"`
+}
+
+// ChallengeSolution is a solved proof-of-work challenge from GET /api/challenge
+type ChallengeSolution struct {
+	Challenge string `json:"challenge" binding:"required,max=2000"`
+	Nonce     string `json:"nonce" binding:"required,max=100"`
+}
+
+// EditCommentRequest validates PATCH /api/comments/:id
+type EditCommentRequest struct {
+	Body   string `json:"body" binding:"required,min=1,max=10000"`
+	Format string `json:"format" binding:"omitempty,oneof=html markdown"`
+}
+
+// ModerateCommentsRequest validates POST /api/comments/sites/:siteId/moderate
+type ModerateCommentsRequest struct {
+	CommentIDs []string `json:"commentIds" binding:"required,min=1,max=100,dive,len=24"`
+	Status     string   `json:"status" binding:"required,oneof=pending approved rejected spam"`
 }
 
 // AuthRequest validates POST /api/users/auth
@@ -17,14 +44,151 @@ type AuthRequest struct {
 	Email string `json:"email" binding:"required,email,max=254"`
 }
 
+// UpdateProfileRequest validates PATCH /api/users/profile
+// Pointer fields are optional: nil means "leave unchanged", "" resets
+type UpdateProfileRequest struct {
+	Locale   *string `json:"locale" binding:"omitempty,max=10"`
+	Timezone *string `json:"timezone" binding:"omitempty,max=64"`
+}
+
 // AddSiteRequest validates POST /api/sites
 type AddSiteRequest struct {
 	URL string `json:"url" binding:"required,url,max=2000"`
 }
 
+// SpamSettingsRequest validates the per-site spam checks
+// Scores of 0 use the defaults.
+type SpamSettingsRequest struct {
+	Disabled     bool     `json:"disabled"`
+	HoldScore    float64  `json:"holdScore" binding:"gte=0,lte=1"`
+	SpamScore    float64  `json:"spamScore" binding:"gte=0,lte=1"`
+	BlockedTerms []string `json:"blockedTerms" binding:"max=500,dive,min=1,max=100"`
+	AkismetKey   string   `json:"akismetKey" binding:"max=100"`
+
+	MinSubmitSeconds int  `json:"minSubmitSeconds" binding:"gte=0,lte=600"`
+	RequireFormToken bool `json:"requireFormToken"`
+}
+
+// ChallengeSettingsRequest validates the proof-of-work settings of a site
+type ChallengeSettingsRequest struct {
+	Enabled    bool `json:"enabled"`
+	Difficulty int  `json:"difficulty" binding:"omitempty,min=8,max=24"`
+}
+
+// ReportSettingsRequest validates the reader report settings of a site
+type ReportSettingsRequest struct {
+	HideThreshold int `json:"hideThreshold" binding:"gte=0,lte=1000"`
+}
+
+// TrustSettingsRequest validates the commenter reputation settings of a site
+type TrustSettingsRequest struct {
+	Enabled   bool `json:"enabled"`
+	Threshold int  `json:"threshold" binding:"omitempty,min=1,max=1000"`
+}
+
 // AddReactionRequest validates POST /api/reactions
 type AddReactionRequest struct {
-	PageID   string `json:"pageId" binding:"required,max=500"`
-	Reaction string `json:"reaction" binding:"required,min=1,max=20"`
+	PageID   string             `json:"pageId" binding:"required,max=500"`
+	Reaction string             `json:"reaction" binding:"required,min=1,max=20"`
+	PoW      *ChallengeSolution `json:"pow"`
+}
+
+// UpdateSiteRequest validates PATCH /api/sites/:id
+// Pointer fields are optional: nil means "leave unchanged"
+type UpdateSiteRequest struct {
+	ModerationMode *string                   `json:"moderationMode" binding:"omitempty,oneof=none guests all"`
+	Sanitizer      *SanitizerSettingsRequest `json:"sanitizer"`
+	Locale         *string                   `json:"locale" binding:"omitempty,max=10"`
+	Spam           *SpamSettingsRequest      `json:"spam"`
+	Challenge      *ChallengeSettingsRequest `json:"challenge"`
+	Reports        *ReportSettingsRequest    `json:"reports"`
+	Trust          *TrustSettingsRequest     `json:"trust"`
 }
 
+// SanitizerSettingsRequest validates the per-site HTML policy
+type SanitizerSettingsRequest struct {
+	TagSet             string   `json:"tagSet" binding:"required,oneof=plain basic rich"`
+	RewriteLinks       bool     `json:"rewriteLinks"`
+	AllowedLinkDomains []string `json:"allowedLinkDomains" binding:"max=100,dive,fqdn"`
+	DeniedLinkDomains  []string `json:"deniedLinkDomains" binding:"max=100,dive,fqdn"`
+	AllowImages        bool     `json:"allowImages"`
+}
+
+// AddWebhookRequest validates POST /api/sites/:id/webhooks
+type AddWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url,startswith=http,max=2000"`
+	Events []string `json:"events" binding:"required,min=1,max=10,dive,oneof=comment.created comment.deleted reaction.added vote.cast"`
+}
+
+// UpdateWebhookRequest validates PATCH /api/sites/:id/webhooks/:webhookId
+// Pointer fields are optional: nil means "leave unchanged"
+type UpdateWebhookRequest struct {
+	URL          *string  `json:"url" binding:"omitempty,url,startswith=http,max=2000"`
+	Events       []string `json:"events" binding:"omitempty,min=1,max=10,dive,oneof=comment.created comment.deleted reaction.added vote.cast"`
+	Active       *bool    `json:"active"`
+	RotateSecret bool     `json:"rotateSecret"`
+}
+
+// AddChannelRequest validates POST /api/sites/:id/channels
+// The connection fields a type needs are checked by the handler.
+type AddChannelRequest struct {
+	Type          string `json:"type" binding:"required,oneof=slack discord telegram matrix"`
+	WebhookURL    string `json:"webhookUrl" binding:"omitempty,url,startswith=http,max=2000"`
+	BotToken      string `json:"botToken" binding:"omitempty,max=200,excludesall=/?#"`
+	ChatID        string `json:"chatId" binding:"omitempty,max=100"`
+	HomeserverURL string `json:"homeserverUrl" binding:"omitempty,url,startswith=http,max=2000"`
+	AccessToken   string `json:"accessToken" binding:"omitempty,max=1000"`
+	RoomID        string `json:"roomId" binding:"omitempty,max=255"`
+}
+
+// UpdateChannelRequest validates PATCH /api/sites/:id/channels/:channelId
+// Pointer fields are optional: nil means "leave unchanged"
+type UpdateChannelRequest struct {
+	WebhookURL    *string `json:"webhookUrl" binding:"omitempty,url,startswith=http,max=2000"`
+	BotToken      *string `json:"botToken" binding:"omitempty,max=200,excludesall=/?#"`
+	ChatID        *string `json:"chatId" binding:"omitempty,max=100"`
+	HomeserverURL *string `json:"homeserverUrl" binding:"omitempty,url,startswith=http,max=2000"`
+	AccessToken   *string `json:"accessToken" binding:"omitempty,max=1000"`
+	RoomID        *string `json:"roomId" binding:"omitempty,max=255"`
+	Active        *bool   `json:"active"`
+}
+
+// AddBlocklistEntryRequest validates POST /api/sites/:id/blocklist
+// The value is checked against its type by the handler.
+type AddBlocklistEntryRequest struct {
+	Type      string     `json:"type" binding:"required,oneof=email domain ip fingerprint word regex"`
+	Value     string     `json:"value" binding:"required,max=500"`
+	Mode      string     `json:"mode" binding:"omitempty,oneof=reject hold shadow"`
+	Note      string     `json:"note" binding:"max=500"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// UpdateBlocklistEntryRequest validates PATCH /api/sites/:id/blocklist/:entryId
+// Pointer fields are optional: nil means "leave unchanged". Set permanent to
+// remove the expiry.
+type UpdateBlocklistEntryRequest struct {
+	Mode      *string    `json:"mode" binding:"omitempty,oneof=reject hold shadow"`
+	Note      *string    `json:"note" binding:"omitempty,max=500"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Permanent bool       `json:"permanent"`
+}
+
+// ReportCommentRequest validates POST /api/comments/:id/report
+type ReportCommentRequest struct {
+	Reason  string `json:"reason" binding:"required,oneof=spam abuse harassment off_topic other"`
+	Details string `json:"details" binding:"max=500"`
+}
+
+// ResolveReportsRequest validates POST /api/sites/:id/reports/resolve
+// Action is what to do with the comment: dismiss keeps it as it is.
+type ResolveReportsRequest struct {
+	CommentID string `json:"commentId" binding:"required,len=24"`
+	Action    string `json:"action" binding:"required,oneof=dismiss approve delete spam ban"`
+}
+
+// PinCommenterRequest validates POST /api/sites/:id/commenters/pin
+// An empty pin removes the owner's decision.
+type PinCommenterRequest struct {
+	Email string `json:"email" binding:"required,email,max=254"`
+	Pin   string `json:"pin" binding:"omitempty,oneof=trusted untrusted"`
+}